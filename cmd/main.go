@@ -1,160 +1,277 @@
-package main
-
-import (
-	"log"
-	"log/slog"
-	"net/http"
-	"os"
-	"strings"
-	"time"
-
-	"deu/internal/config"
-	"deu/internal/places"
-	"deu/internal/repository"
-	"deu/internal/users"
-	"deu/pkg/db"
-	"deu/pkg/router"
-)
-
-func main() {
-	cfg, err := config.Load("config.json")
-	if err != nil {
-		log.Fatalf("Failed to load config: %v", err)
-	}
-
-	var logLevel slog.Level
-	switch strings.ToLower(cfg.LogLevel) {
-	case "debug":
-		logLevel = slog.LevelDebug
-	case "info":
-		logLevel = slog.LevelInfo
-	case "warn":
-		logLevel = slog.LevelWarn
-	case "error":
-		logLevel = slog.LevelError
-	default:
-		logLevel = slog.LevelInfo
-	}
-
-	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{
-		Level: logLevel,
-	}))
-	slog.SetDefault(logger)
-
-	if cfg.DatabaseURL == "" {
-		log.Fatal("database_url is required in config")
-	}
-	gormDB := db.InitDB(cfg.DatabaseURL)
-
-	var userRepo repository.UserRepository = repository.NewPostgresUserRepository(gormDB)
-	var placeRepo repository.PlaceRepository = repository.NewPostgresPlaceRepository(gormDB)
-	var userPlaceRepo repository.UserPlaceRepository = repository.NewPostgresUserPlaceRepository(gormDB)
-
-	if cfg.EnableRequestLogging {
-		userRepo = repository.NewLoggingUserRepository(userRepo, logger)
-		placeRepo = repository.NewLoggingPlaceRepository(placeRepo, logger)
-		userPlaceRepo = repository.NewLoggingUserPlaceRepository(userPlaceRepo, logger)
-	}
-
-	userService := users.NewUserService(userRepo, userPlaceRepo, placeRepo)
-	placeService := places.NewPlaceService(placeRepo, cfg.EnableCache)
-
-	userHandler := &users.Handler{Service: userService}
-	placeHandler := &places.Handler{
-		Service:       placeService,
-		AllowDeletion: cfg.AllowPlaceDeletion,
-	}
-
-	r := router.NewRouter(router.Config{
-		UserHandler:  userHandler,
-		PlaceHandler: placeHandler,
-	})
-
-	serverPort := cfg.ServerPort
-	if serverPort == "" {
-		serverPort = "8080"
-	}
-
-	var handler http.Handler = r
-	if cfg.EnableRequestLogging {
-		handler = requestLoggingMiddleware(handler)
-	}
-
-	if cfg.MaxConnections > 0 {
-		handler = maxConnectionsMiddleware(handler, cfg.MaxConnections)
-	}
-
-	next := handler
-	handler = http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
-		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
-		if req.Method == http.MethodOptions {
-			w.WriteHeader(http.StatusOK)
-			return
-		}
-		next.ServeHTTP(w, req)
-	})
-
-	srv := &http.Server{
-		Addr:    ":" + serverPort,
-		Handler: handler,
-	}
-	if cfg.RequestTimeoutSeconds > 0 {
-		srv.ReadTimeout = time.Duration(cfg.RequestTimeoutSeconds) * time.Second
-		srv.WriteTimeout = time.Duration(cfg.RequestTimeoutSeconds) * time.Second
-		srv.IdleTimeout = time.Duration(cfg.RequestTimeoutSeconds) * 2 * time.Second
-		slog.Info("Server timeouts configured", "timeout_seconds", cfg.RequestTimeoutSeconds)
-	}
-
-	slog.Info("Server starting",
-		"port", serverPort,
-		"cache_enabled", cfg.EnableCache,
-		"max_connections", cfg.MaxConnections,
-		"request_logging", cfg.EnableRequestLogging)
-
-	log.Fatal(srv.ListenAndServe())
-}
-
-func requestLoggingMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		start := time.Now()
-
-		wrapped := &responseWriter{ResponseWriter: w, statusCode: http.StatusOK}
-
-		next.ServeHTTP(wrapped, r)
-
-		duration := time.Since(start)
-		slog.Info("HTTP Request",
-			"method", r.Method,
-			"path", r.URL.Path,
-			"status", wrapped.statusCode,
-			"duration_ms", duration.Milliseconds(),
-			"remote_addr", r.RemoteAddr,
-		)
-	})
-}
-
-type responseWriter struct {
-	http.ResponseWriter
-	statusCode int
-}
-
-func (rw *responseWriter) WriteHeader(code int) {
-	rw.statusCode = code
-	rw.ResponseWriter.WriteHeader(code)
-}
-func maxConnectionsMiddleware(next http.Handler, maxConns int) http.Handler {
-	semaphore := make(chan struct{}, maxConns)
-
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		select {
-		case semaphore <- struct{}{}:
-			defer func() { <-semaphore }()
-			next.ServeHTTP(w, r)
-		default:
-			http.Error(w, "Server too busy - max connections reached", http.StatusServiceUnavailable)
-			slog.Warn("Connection rejected - max connections reached", "max", maxConns)
-		}
-	})
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"log"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"deu/internal/audit"
+	"deu/internal/auth"
+	"deu/internal/config"
+	er "deu/internal/errors"
+	"deu/internal/models"
+	"deu/internal/places"
+	"deu/internal/repository"
+	"deu/internal/users"
+	"deu/pkg/blob"
+	"deu/pkg/cache"
+	"deu/pkg/db"
+	"deu/pkg/router"
+)
+
+func main() {
+	cfg, err := config.Load("config.json")
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+
+	var logLevel slog.Level
+	switch strings.ToLower(cfg.LogLevel) {
+	case "debug":
+		logLevel = slog.LevelDebug
+	case "info":
+		logLevel = slog.LevelInfo
+	case "warn":
+		logLevel = slog.LevelWarn
+	case "error":
+		logLevel = slog.LevelError
+	default:
+		logLevel = slog.LevelInfo
+	}
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{
+		Level: logLevel,
+	}))
+	slog.SetDefault(logger)
+
+	// ctx is cancelled on SIGINT or SIGTERM, which shuts the server down.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	st, err := openStorage(ctx, cfg)
+	if err != nil {
+		log.Fatalf("Failed to open storage: %v", err)
+	}
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if st.migrator == nil {
+			log.Fatalf("migrate: the %s storage backend has no schema to migrate", cfg.StorageBackend)
+		}
+		if err := runMigrate(ctx, st.migrator, os.Args[2:]); err != nil {
+			log.Fatalf("migrate: %v", err)
+		}
+		return
+	}
+	if st.migrator != nil {
+		if err := prepareSchema(ctx, st.migrator, cfg.AutoMigrate); err != nil {
+			if errors.Is(err, db.ErrSchemaBehind) {
+				log.Fatalf("%v; run \"server migrate up\" or enable auto_migrate", err)
+			}
+			log.Fatalf("Failed to migrate database: %v", err)
+		}
+	}
+
+	userRepo := st.users
+	placeRepo := st.places
+	userPlaceRepo := st.userPlaces
+	sessionRepo := st.sessions
+	refreshTokenRepo := st.refreshTokens
+	apiKeyRepo := st.apiKeys
+	reviewRepo := st.reviews
+	tagRepo := st.tags
+	photoRepo := st.photos
+	auditRepo := st.audit
+	uow := st.uow
+
+	if cfg.EnableRequestLogging {
+		userRepo = repository.NewLoggingUserRepository(userRepo, logger)
+		placeRepo = repository.NewLoggingPlaceRepository(placeRepo, logger)
+		userPlaceRepo = repository.NewLoggingUserPlaceRepository(userPlaceRepo, logger)
+		tagRepo = repository.NewLoggingTagRepository(tagRepo, logger)
+		uow = repository.NewLoggingUnitOfWork(uow, logger)
+	}
+
+	photoDir := cfg.PhotoStorageDir
+	if photoDir == "" {
+		photoDir = "./data/photos"
+	}
+	photoStore, err := blob.NewLocalStore(photoDir)
+	if err != nil {
+		log.Fatalf("Failed to open photo storage: %v", err)
+	}
+
+	trashRetention := time.Duration(cfg.TrashRetentionDays) * 24 * time.Hour
+	if trashRetention <= 0 {
+		trashRetention = 30 * 24 * time.Hour
+	}
+
+	trail := audit.NewTrail(auditRepo)
+
+	userService := users.NewUserService(userRepo, userPlaceRepo, placeRepo, uow, trail)
+	var placeCache cache.Cache[*models.Place] = cache.Nop[*models.Place]{}
+	if cfg.EnableCache {
+		placeCache = cache.NewLRU(cache.Options[*models.Place]{
+			MaxEntries: cfg.CacheMaxEntries,
+			TTL:        time.Duration(cfg.CacheTTLSeconds) * time.Second,
+			Clone:      (*models.Place).Clone,
+		})
+	}
+	placeService := places.NewPlaceService(placeRepo, userPlaceRepo, reviewRepo, tagRepo, photoRepo, uow, photoStore, trail, placeCache, st.invalidations)
+
+	authSettings := auth.Settings{
+		SessionTTL:      time.Duration(cfg.SessionTTLHours) * time.Hour,
+		JWTSecret:       []byte(cfg.JWTSecret),
+		AccessTokenTTL:  time.Duration(cfg.AccessTokenTTLMinutes) * time.Minute,
+		RefreshTokenTTL: time.Duration(cfg.RefreshTokenTTLHours) * time.Hour,
+		AdminEmails:     cfg.AdminEmails,
+	}
+	if authSettings.SessionTTL <= 0 {
+		authSettings.SessionTTL = 7 * 24 * time.Hour
+	}
+	if authSettings.AccessTokenTTL <= 0 {
+		authSettings.AccessTokenTTL = 15 * time.Minute
+	}
+	if authSettings.RefreshTokenTTL <= 0 {
+		authSettings.RefreshTokenTTL = 30 * 24 * time.Hour
+	}
+	if len(authSettings.JWTSecret) == 0 {
+		slog.Warn("jwt_secret is not set, using a random key; access tokens will not survive a restart")
+		authSettings.JWTSecret = make([]byte, 32)
+		rand.Read(authSettings.JWTSecret)
+	}
+	authService := auth.NewAuthService(userRepo, sessionRepo, refreshTokenRepo, apiKeyRepo, authSettings)
+
+	authHandler := &auth.Handler{Service: authService}
+	userHandler := &users.Handler{Service: userService, TrashRetention: trashRetention}
+	placeHandler := &places.Handler{
+		Service:        placeService,
+		AllowDeletion:  cfg.AllowPlaceDeletion,
+		MaxPhotoBytes:  int64(cfg.MaxPhotoUploadMB) << 20,
+		TrashRetention: trashRetention,
+	}
+
+	r := router.NewRouter(router.Config{
+		AuthHandler:  authHandler,
+		UserHandler:  userHandler,
+		PlaceHandler: placeHandler,
+	})
+
+	serverPort := cfg.ServerPort
+	if serverPort == "" {
+		serverPort = "8080"
+	}
+
+	var handler http.Handler = router.AuthMiddleware(r, authService)
+	if cfg.EnableRequestLogging {
+		handler = requestLoggingMiddleware(handler)
+	}
+
+	if cfg.MaxConnections > 0 {
+		handler = maxConnectionsMiddleware(handler, cfg.MaxConnections)
+	}
+
+	// Outermost but for CORS, so that every log line and error body of a
+	// request carries its id.
+	handler = router.RequestID(handler)
+
+	next := handler
+	handler = http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, If-Match, If-None-Match, "+router.RequestIDHeader)
+		w.Header().Set("Access-Control-Expose-Headers", "ETag, "+router.RequestIDHeader)
+		if req.Method == http.MethodOptions {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		next.ServeHTTP(w, req)
+	})
+
+	srv := &http.Server{
+		Addr:    ":" + serverPort,
+		Handler: handler,
+	}
+	if cfg.RequestTimeoutSeconds > 0 {
+		srv.ReadTimeout = time.Duration(cfg.RequestTimeoutSeconds) * time.Second
+		srv.WriteTimeout = time.Duration(cfg.RequestTimeoutSeconds) * time.Second
+		srv.IdleTimeout = time.Duration(cfg.RequestTimeoutSeconds) * 2 * time.Second
+		slog.Info("Server timeouts configured", "timeout_seconds", cfg.RequestTimeoutSeconds)
+	}
+
+	slog.Info("Server starting",
+		"port", serverPort,
+		"storage_backend", cfg.StorageBackend,
+		"cache_enabled", cfg.EnableCache,
+		"cache_max_entries", placeCache.Stats().MaxEntries,
+		"max_connections", cfg.MaxConnections,
+		"request_logging", cfg.EnableRequestLogging,
+		"auto_migrate", cfg.AutoMigrate)
+
+	go func() {
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatalf("Server failed: %v", err)
+		}
+	}()
+
+	<-ctx.Done()
+	slog.Info("Shutting down")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		slog.Error("Server shutdown failed", "error", err)
+	}
+	if err := st.close(); err != nil {
+		slog.Error("Failed to close storage", "error", err)
+	}
+}
+
+func requestLoggingMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+
+		wrapped := &responseWriter{ResponseWriter: w, statusCode: http.StatusOK}
+
+		next.ServeHTTP(wrapped, r)
+
+		duration := time.Since(start)
+		slog.Info("HTTP Request",
+			"method", r.Method,
+			"path", r.URL.Path,
+			"status", wrapped.statusCode,
+			"duration_ms", duration.Milliseconds(),
+			"remote_addr", r.RemoteAddr,
+			"request_id", er.RequestID(r.Context()),
+		)
+	})
+}
+
+type responseWriter struct {
+	http.ResponseWriter
+	statusCode int
+}
+
+func (rw *responseWriter) WriteHeader(code int) {
+	rw.statusCode = code
+	rw.ResponseWriter.WriteHeader(code)
+}
+func maxConnectionsMiddleware(next http.Handler, maxConns int) http.Handler {
+	semaphore := make(chan struct{}, maxConns)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case semaphore <- struct{}{}:
+			defer func() { <-semaphore }()
+			next.ServeHTTP(w, r)
+		default:
+			er.Write(w, r, er.ErrServerBusy)
+			slog.Warn("Connection rejected - max connections reached", "max", maxConns)
+		}
+	})
 }
\ No newline at end of file
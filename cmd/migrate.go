@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strconv"
+
+	"deu/pkg/db"
+)
+
+const migrateUsage = "usage: server migrate [up | down [steps] | status]"
+
+// runMigrate implements the migrate subcommand.
+func runMigrate(ctx context.Context, m *db.Migrator, args []string) error {
+	command := "up"
+	if len(args) > 0 {
+		command, args = args[0], args[1:]
+	}
+
+	switch command {
+	case "up":
+		applied, err := m.Up(ctx)
+		if err != nil {
+			return err
+		}
+		if len(applied) == 0 {
+			fmt.Println("Schema is up to date.")
+		}
+		for _, mig := range applied {
+			fmt.Printf("Applied %04d_%s\n", mig.Version, mig.Name)
+		}
+
+	case "down":
+		steps := 1
+		if len(args) > 0 {
+			n, err := strconv.Atoi(args[0])
+			if err != nil || n < 1 {
+				return fmt.Errorf("steps must be a positive integer\n%s", migrateUsage)
+			}
+			steps = n
+		}
+		reverted, err := m.Down(ctx, steps)
+		if err != nil {
+			return err
+		}
+		if len(reverted) == 0 {
+			fmt.Println("No migrations to roll back.")
+		}
+		for _, mig := range reverted {
+			fmt.Printf("Rolled back %04d_%s\n", mig.Version, mig.Name)
+		}
+
+	case "status":
+		status, err := m.Status(ctx)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Current version: %d\nLatest version:  %d\n", status.Current, status.Latest)
+		for _, mig := range status.Pending {
+			fmt.Printf("Pending %04d_%s\n", mig.Version, mig.Name)
+		}
+		for _, v := range status.Unknown {
+			fmt.Printf("Applied but unknown to this build: %04d\n", v)
+		}
+
+	default:
+		return fmt.Errorf("unknown command %q\n%s", command, migrateUsage)
+	}
+	return nil
+}
+
+// prepareSchema brings the schema up to date when autoMigrate is set, and
+// otherwise fails with db.ErrSchemaBehind if it is outdated.
+func prepareSchema(ctx context.Context, m *db.Migrator, autoMigrate bool) error {
+	if autoMigrate {
+		applied, err := m.Up(ctx)
+		if err != nil {
+			return err
+		}
+		for _, mig := range applied {
+			slog.Info("Applied migration", "version", mig.Version, "name", mig.Name)
+		}
+	}
+
+	status, err := m.Status(ctx)
+	if err != nil {
+		return err
+	}
+	if len(status.Unknown) > 0 {
+		slog.Warn("Database has migrations unknown to this build; it was migrated by a newer release", "versions", status.Unknown)
+	}
+	return m.Check(ctx)
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+
+	"deu/internal/config"
+	"deu/internal/repository"
+	"deu/pkg/cache"
+	"deu/pkg/db"
+)
+
+// storage is the set of repositories the server runs on, as chosen by the
+// storage_backend setting.
+type storage struct {
+	users         repository.UserRepository
+	places        repository.PlaceRepository
+	userPlaces    repository.UserPlaceRepository
+	sessions      repository.SessionRepository
+	refreshTokens repository.RefreshTokenRepository
+	apiKeys       repository.APIKeyRepository
+	reviews       repository.ReviewRepository
+	tags          repository.TagRepository
+	photos        repository.PhotoRepository
+	audit         repository.AuditRepository
+	uow           repository.UnitOfWork
+
+	// invalidations reaches every instance sharing the storage.
+	invalidations cache.Bus
+	// migrator manages the schema; nil for backends without one.
+	migrator *db.Migrator
+	// close is called once the server has stopped.
+	close func() error
+}
+
+// openStorage opens the configured backend. ctx bounds background work such
+// as listening for cache invalidations.
+func openStorage(ctx context.Context, cfg *config.Config) (*storage, error) {
+	switch cfg.StorageBackend {
+	case "", "postgres":
+		return openPostgres(ctx, cfg)
+	case "sqlite":
+		return openSQLite(cfg)
+	case "memory":
+		return openMemory(cfg)
+	default:
+		return nil, fmt.Errorf("unknown storage_backend %q; use postgres, sqlite or memory", cfg.StorageBackend)
+	}
+}
+
+func openPostgres(ctx context.Context, cfg *config.Config) (*storage, error) {
+	if cfg.DatabaseURL == "" {
+		return nil, errors.New("DATABASE_URL is required by the postgres storage backend")
+	}
+	gormDB := db.InitDB(cfg.DatabaseURL)
+
+	migrator, err := db.NewMigrator(gormDB, db.PostgresMigrations())
+	if err != nil {
+		return nil, fmt.Errorf("loading migrations: %w", err)
+	}
+
+	var invalidations cache.Bus = cache.NewLocalBus()
+	if cfg.EnableCache {
+		// Other instances behind the load balancer hear about writes
+		// through the database.
+		pgBus := cache.NewPostgresBus(gormDB, cfg.DatabaseURL, "place_cache_invalidation")
+		go pgBus.Listen(ctx)
+		invalidations = pgBus
+	}
+
+	return &storage{
+		users:         repository.NewPostgresUserRepository(gormDB),
+		places:        repository.NewPostgresPlaceRepository(gormDB),
+		userPlaces:    repository.NewPostgresUserPlaceRepository(gormDB),
+		sessions:      repository.NewPostgresSessionRepository(gormDB),
+		refreshTokens: repository.NewPostgresRefreshTokenRepository(gormDB),
+		apiKeys:       repository.NewPostgresAPIKeyRepository(gormDB),
+		reviews:       repository.NewPostgresReviewRepository(gormDB),
+		tags:          repository.NewPostgresTagRepository(gormDB),
+		photos:        repository.NewPostgresPhotoRepository(gormDB),
+		audit:         repository.NewPostgresAuditRepository(gormDB),
+		uow:           repository.NewPostgresUnitOfWork(gormDB),
+		invalidations: invalidations,
+		migrator:      migrator,
+		close: func() error {
+			sqlDB, err := gormDB.DB()
+			if err != nil {
+				return err
+			}
+			return sqlDB.Close()
+		},
+	}, nil
+}
+
+// openSQLite keeps everything in a single database file, for deployments
+// with one instance. Only users, places and visits need their own SQLite
+// repositories; the SQL of the others is portable.
+func openSQLite(cfg *config.Config) (*storage, error) {
+	path := cfg.SQLitePath
+	if path == "" {
+		return nil, errors.New("sqlite_path is required by the sqlite storage backend")
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+	gormDB := db.InitSQLite(path)
+
+	migrator, err := db.NewMigrator(gormDB, db.SQLiteMigrations())
+	if err != nil {
+		return nil, fmt.Errorf("loading migrations: %w", err)
+	}
+
+	return &storage{
+		users:         repository.NewSQLiteUserRepository(gormDB),
+		places:        repository.NewSQLitePlaceRepository(gormDB),
+		userPlaces:    repository.NewSQLiteUserPlaceRepository(gormDB),
+		sessions:      repository.NewPostgresSessionRepository(gormDB),
+		refreshTokens: repository.NewPostgresRefreshTokenRepository(gormDB),
+		apiKeys:       repository.NewPostgresAPIKeyRepository(gormDB),
+		reviews:       repository.NewPostgresReviewRepository(gormDB),
+		tags:          repository.NewPostgresTagRepository(gormDB),
+		photos:        repository.NewPostgresPhotoRepository(gormDB),
+		audit:         repository.NewPostgresAuditRepository(gormDB),
+		uow:           repository.NewSQLiteUnitOfWork(gormDB),
+		invalidations: cache.NewLocalBus(),
+		migrator:      migrator,
+		close: func() error {
+			sqlDB, err := gormDB.DB()
+			if err != nil {
+				return err
+			}
+			return sqlDB.Close()
+		},
+	}, nil
+}
+
+// openMemory keeps everything in memory. With memory_snapshot_path set, the
+// data is loaded from that file at startup and saved back on shutdown.
+func openMemory(cfg *config.Config) (*storage, error) {
+	backend := repository.NewMemoryBackend()
+	closeFn := func() error { return nil }
+
+	if path := cfg.MemorySnapshotPath; path != "" {
+		if err := backend.Load(path); err != nil {
+			return nil, fmt.Errorf("loading memory snapshot: %w", err)
+		}
+		slog.Info("Loaded memory snapshot", "path", path)
+		closeFn = func() error {
+			if err := backend.Save(path); err != nil {
+				return fmt.Errorf("saving memory snapshot: %w", err)
+			}
+			slog.Info("Saved memory snapshot", "path", path)
+			return nil
+		}
+	} else {
+		slog.Warn("Using the memory storage backend without memory_snapshot_path; all data is lost on shutdown")
+	}
+
+	return &storage{
+		users:         backend.Users,
+		places:        backend.Places,
+		userPlaces:    backend.UserPlaces,
+		sessions:      backend.Sessions,
+		refreshTokens: backend.RefreshTokens,
+		apiKeys:       backend.APIKeys,
+		reviews:       backend.Reviews,
+		tags:          backend.Tags,
+		photos:        backend.Photos,
+		audit:         backend.Audit,
+		uow:           backend.UnitOfWork,
+		invalidations: cache.NewLocalBus(),
+		close:         closeFn,
+	}, nil
+}
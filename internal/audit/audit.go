@@ -0,0 +1,135 @@
+// Package audit keeps a trail of changes to places and users: who made each
+// change, when, and how every audited field changed.
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+
+	"deu/internal/auth"
+	er "deu/internal/errors"
+	"deu/internal/models"
+	repo "deu/internal/repository"
+)
+
+// Change describes a change to record. Before and After hold the audited
+// fields of the entity; Before is nil for creations, and After is the last
+// known state for deletions.
+type Change struct {
+	EntityType string
+	EntityID   string
+	Action     string
+	Before     map[string]any
+	After      map[string]any
+	RevertOf   *string
+}
+
+type Trail struct {
+	repo repo.AuditRepository
+}
+
+func NewTrail(repo repo.AuditRepository) *Trail {
+	return &Trail{repo: repo}
+}
+
+// Record stores a change made by the caller in ctx. Updates that leave
+// every audited field as it was are skipped. The change has already been
+// applied, so failures are logged rather than returned.
+func (t *Trail) Record(ctx context.Context, c Change) {
+	before, after := normalize(c.Before), normalize(c.After)
+
+	changes := []models.FieldChange{}
+	switch c.Action {
+	case models.AuditCreate, models.AuditUpdate, models.AuditRevert:
+		changes = Diff(before, after)
+	}
+	if c.Action == models.AuditUpdate && len(changes) == 0 {
+		return
+	}
+
+	entry := models.AuditEntry{
+		Id:         uuid.New().String(),
+		EntityType: c.EntityType,
+		EntityID:   c.EntityID,
+		Action:     c.Action,
+		Changes:    changes,
+		Snapshot:   after,
+		RevertOf:   c.RevertOf,
+		CreatedAt:  time.Now().UTC(),
+	}
+	if actorID, ok := auth.UserIDFromContext(ctx); ok {
+		entry.ActorID = &actorID
+	}
+
+	if err := t.repo.Create(ctx, &entry); err != nil {
+		slog.Warn("Failed to record audit entry", "entity_type", c.EntityType, "entity_id", c.EntityID, "action", c.Action, "error", err)
+	}
+}
+
+// History returns a page of an entity's changes, newest first.
+func (t *Trail) History(ctx context.Context, entityType, entityID string, limit, offset int) (*models.Page[models.AuditEntry], error) {
+	entries, total, err := t.repo.ListByEntity(ctx, entityType, entityID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.Page[models.AuditEntry]{Items: entries, Total: &total, Limit: limit, Offset: offset}, nil
+}
+
+// Entry loads one entry and checks that it belongs to the entity.
+func (t *Trail) Entry(ctx context.Context, entityType, entityID, id string) (*models.AuditEntry, error) {
+	entry, err := t.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if entry.EntityType != entityType || entry.EntityID != entityID {
+		return nil, er.ErrAuditEntryNotFound
+	}
+	return entry, nil
+}
+
+// Diff lists the fields whose values differ between two states, by name.
+// A field missing from one side counts as null there.
+func Diff(before, after map[string]any) []models.FieldChange {
+	fields := make([]string, 0, len(after))
+	for field := range after {
+		fields = append(fields, field)
+	}
+	for field := range before {
+		if _, ok := after[field]; !ok {
+			fields = append(fields, field)
+		}
+	}
+	sort.Strings(fields)
+
+	changes := []models.FieldChange{}
+	for _, field := range fields {
+		if !reflect.DeepEqual(before[field], after[field]) {
+			changes = append(changes, models.FieldChange{Field: field, Before: before[field], After: after[field]})
+		}
+	}
+	return changes
+}
+
+// normalize round-trips a state through JSON so that values compare the
+// same way whether they came from a model or from a stored snapshot.
+func normalize(state map[string]any) map[string]any {
+	if state == nil {
+		return nil
+	}
+	raw, err := json.Marshal(state)
+	if err != nil {
+		return state
+	}
+	var result map[string]any
+	if err := json.Unmarshal(raw, &result); err != nil {
+		return state
+	}
+	return result
+}
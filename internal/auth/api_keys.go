@@ -0,0 +1,126 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+
+	er "deu/internal/errors"
+	"deu/internal/models"
+)
+
+const (
+	apiKeyPrefix = "tt_"
+	// lastUsedResolution limits how often a busy key writes its
+	// last-used timestamp.
+	lastUsedResolution = time.Minute
+)
+
+// authorizeKeyOwner allows users to manage their own keys and admins to
+// manage anyone's. Keys cannot be used to mint or revoke other keys.
+func authorizeKeyOwner(ctx context.Context, userID string) error {
+	caller, ok := PrincipalFromContext(ctx)
+	if !ok {
+		return er.ErrUnauthorized
+	}
+	if caller.APIKeyID != "" {
+		return er.ErrForbidden
+	}
+	if caller.UserID != userID && !caller.IsAdmin() {
+		return er.ErrForbidden
+	}
+	return nil
+}
+
+func (s *AuthService) CreateAPIKey(ctx context.Context, userID string, r *models.APIKeyCreateRequest) (*models.APIKeyCreated, error) {
+	if err := authorizeKeyOwner(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	if _, err := s.users.GetByID(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	if r.ExpiresAt != nil && !r.ExpiresAt.After(time.Now()) {
+		return nil, er.ErrInvalidAPIKeyData
+	}
+
+	scope := r.Scope
+	if scope == "" {
+		scope = models.APIKeyScopeReadOnly
+	}
+
+	secret, err := newToken()
+	if err != nil {
+		return nil, err
+	}
+	key := apiKeyPrefix + secret
+
+	apiKey := models.APIKey{
+		Id:        uuid.New().String(),
+		UserID:    userID,
+		Name:      r.Name,
+		Prefix:    key[:len(apiKeyPrefix)+6],
+		KeyHash:   hashToken(key),
+		Scope:     scope,
+		ExpiresAt: r.ExpiresAt,
+		CreatedAt: time.Now(),
+	}
+	if err := s.apiKeys.Create(ctx, &apiKey); err != nil {
+		return nil, err
+	}
+
+	return &models.APIKeyCreated{APIKey: apiKey, Key: key}, nil
+}
+
+func (s *AuthService) ListAPIKeys(ctx context.Context, userID string) ([]models.APIKey, error) {
+	if err := authorizeKeyOwner(ctx, userID); err != nil {
+		return nil, err
+	}
+	return s.apiKeys.ListByUser(ctx, userID)
+}
+
+func (s *AuthService) RevokeAPIKey(ctx context.Context, userID, keyID string) error {
+	if err := authorizeKeyOwner(ctx, userID); err != nil {
+		return err
+	}
+	return s.apiKeys.Delete(ctx, userID, keyID)
+}
+
+// AuthenticateAPIKey resolves a personal API key to the caller it belongs to
+// and records when it was last used.
+func (s *AuthService) AuthenticateAPIKey(ctx context.Context, key string) (Principal, error) {
+	if key == "" {
+		return Principal{}, er.ErrInvalidToken
+	}
+
+	apiKey, err := s.apiKeys.GetByHash(ctx, hashToken(key))
+	if err != nil {
+		if errors.Is(err, er.ErrAPIKeyNotFound) {
+			return Principal{}, er.ErrInvalidToken
+		}
+		return Principal{}, err
+	}
+
+	now := time.Now()
+	if apiKey.ExpiresAt != nil && now.After(*apiKey.ExpiresAt) {
+		return Principal{}, er.ErrInvalidToken
+	}
+
+	if apiKey.LastUsedAt == nil || now.Sub(*apiKey.LastUsedAt) >= lastUsedResolution {
+		if err := s.apiKeys.TouchLastUsed(ctx, apiKey.Id, now); err != nil {
+			slog.Warn("Failed to record API key usage", "key_id", apiKey.Id, "error", err)
+		}
+	}
+
+	principal, err := s.principalFor(ctx, apiKey.UserID)
+	if err != nil {
+		return Principal{}, err
+	}
+	principal.APIKeyID = apiKey.Id
+	principal.Scope = apiKey.Scope
+	return principal, nil
+}
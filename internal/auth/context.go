@@ -0,0 +1,55 @@
+package auth
+
+import (
+	"context"
+
+	"deu/internal/models"
+)
+
+// Principal is the authenticated caller of a request.
+type Principal struct {
+	UserID string
+	Role   string
+	// APIKeyID and Scope are set when the caller authenticated with a
+	// personal API key rather than a session or bearer token.
+	APIKeyID string
+	Scope    string
+}
+
+// ReadOnly reports whether the caller may only use safe (read) methods.
+func (p Principal) ReadOnly() bool {
+	return p.Scope == models.APIKeyScopeReadOnly
+}
+
+// HasRole reports whether the principal holds one of the given roles.
+func (p Principal) HasRole(roles ...string) bool {
+	for _, role := range roles {
+		if p.Role == role {
+			return true
+		}
+	}
+	return false
+}
+
+func (p Principal) IsAdmin() bool {
+	return p.Role == models.RoleAdmin
+}
+
+type contextKey struct{}
+
+// WithPrincipal returns a copy of ctx carrying the authenticated caller.
+func WithPrincipal(ctx context.Context, p Principal) context.Context {
+	return context.WithValue(ctx, contextKey{}, p)
+}
+
+// PrincipalFromContext returns the authenticated caller, if the request has one.
+func PrincipalFromContext(ctx context.Context) (Principal, bool) {
+	p, ok := ctx.Value(contextKey{}).(Principal)
+	return p, ok && p.UserID != ""
+}
+
+// UserIDFromContext returns the authenticated user id, if the request has one.
+func UserIDFromContext(ctx context.Context) (string, bool) {
+	p, ok := PrincipalFromContext(ctx)
+	return p.UserID, ok
+}
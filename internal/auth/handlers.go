@@ -0,0 +1,214 @@
+package auth
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	er "deu/internal/errors"
+	"deu/internal/models"
+	"deu/pkg/httpx"
+)
+
+type Handler struct {
+	Service *AuthService
+}
+
+func setSessionCookie(w http.ResponseWriter, r *http.Request, session *SessionResult) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     SessionCookieName,
+		Value:    session.Token,
+		Path:     "/",
+		Expires:  session.ExpiresAt,
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+func clearSessionCookie(w http.ResponseWriter, r *http.Request) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     SessionCookieName,
+		Value:    "",
+		Path:     "/",
+		Expires:  time.Unix(0, 0),
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// POST /auth/register
+func (h *Handler) Register(w http.ResponseWriter, r *http.Request) {
+	var req models.RegisterRequest
+	if !httpx.Decode(w, r, &req) {
+		return
+	}
+
+	user, err := h.Service.Register(r.Context(), &req)
+	if err != nil {
+		er.Write(w, r, err)
+		return
+	}
+
+	session, err := h.Service.StartSession(r.Context(), user)
+	if err != nil {
+		er.Write(w, r, err)
+		return
+	}
+
+	setSessionCookie(w, r, session)
+	httpx.JSON(w, http.StatusCreated, *user)
+}
+
+// POST /auth/login
+func (h *Handler) Login(w http.ResponseWriter, r *http.Request) {
+	var req models.LoginRequest
+	if !httpx.Decode(w, r, &req) {
+		return
+	}
+
+	session, err := h.Service.Login(r.Context(), &req)
+	if err != nil {
+		er.Write(w, r, err)
+		return
+	}
+
+	setSessionCookie(w, r, session)
+	httpx.JSON(w, http.StatusOK, *session.User)
+}
+
+// POST /auth/logout
+func (h *Handler) Logout(w http.ResponseWriter, r *http.Request) {
+	if cookie, err := r.Cookie(SessionCookieName); err == nil {
+		if err := h.Service.Logout(r.Context(), cookie.Value); err != nil {
+			er.Write(w, r, err)
+			return
+		}
+	}
+
+	clearSessionCookie(w, r)
+	httpx.Status(w, http.StatusOK, "logged out")
+}
+
+// GET /auth/me
+func (h *Handler) Me(w http.ResponseWriter, r *http.Request) {
+	user, err := h.Service.CurrentUser(r.Context())
+	if err != nil {
+		// A session whose user has since been deleted is no login at all.
+		if errors.Is(err, er.ErrUserNotFound) {
+			err = er.ErrUnauthorized
+		}
+		er.Write(w, r, err)
+		return
+	}
+
+	httpx.JSON(w, http.StatusOK, *user)
+}
+
+// POST /auth/token
+func (h *Handler) Token(w http.ResponseWriter, r *http.Request) {
+	var req models.LoginRequest
+	if !httpx.Decode(w, r, &req) {
+		return
+	}
+
+	tokens, err := h.Service.IssueTokens(r.Context(), &req)
+	if err != nil {
+		er.Write(w, r, err)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	httpx.JSON(w, http.StatusOK, tokens)
+}
+
+// POST /auth/refresh
+func (h *Handler) Refresh(w http.ResponseWriter, r *http.Request) {
+	var req models.RefreshRequest
+	if !httpx.Decode(w, r, &req) {
+		return
+	}
+
+	tokens, err := h.Service.Refresh(r.Context(), req.RefreshToken)
+	if err != nil {
+		er.Write(w, r, err)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	httpx.JSON(w, http.StatusOK, tokens)
+}
+
+// POST /auth/revoke
+func (h *Handler) Revoke(w http.ResponseWriter, r *http.Request) {
+	var req models.RefreshRequest
+	if !httpx.Decode(w, r, &req) {
+		return
+	}
+
+	if err := h.Service.RevokeRefreshToken(r.Context(), req.RefreshToken); err != nil {
+		er.Write(w, r, err)
+		return
+	}
+
+	httpx.Status(w, http.StatusOK, "revoked")
+}
+
+// POST /users/{id}/api-keys
+func (h *Handler) CreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	userID, ok := httpx.PathUUID(w, r, "id")
+	if !ok {
+		return
+	}
+
+	var req models.APIKeyCreateRequest
+	if !httpx.Decode(w, r, &req) {
+		return
+	}
+
+	key, err := h.Service.CreateAPIKey(r.Context(), userID, &req)
+	if err != nil {
+		er.Write(w, r, err)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	httpx.JSON(w, http.StatusCreated, key)
+}
+
+// GET /users/{id}/api-keys
+func (h *Handler) ListAPIKeys(w http.ResponseWriter, r *http.Request) {
+	userID, ok := httpx.PathUUID(w, r, "id")
+	if !ok {
+		return
+	}
+
+	keys, err := h.Service.ListAPIKeys(r.Context(), userID)
+	if err != nil {
+		er.Write(w, r, err)
+		return
+	}
+
+	httpx.JSON(w, http.StatusOK, keys)
+}
+
+// DELETE /users/{id}/api-keys/{key_id}
+func (h *Handler) RevokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	userID, ok := httpx.PathUUID(w, r, "id")
+	if !ok {
+		return
+	}
+	keyID, ok := httpx.PathUUID(w, r, "key_id")
+	if !ok {
+		return
+	}
+
+	if err := h.Service.RevokeAPIKey(r.Context(), userID, keyID); err != nil {
+		er.Write(w, r, err)
+		return
+	}
+
+	httpx.Status(w, http.StatusOK, "revoked")
+}
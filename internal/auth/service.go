@@ -0,0 +1,232 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+
+	er "deu/internal/errors"
+	"deu/internal/models"
+	repo "deu/internal/repository"
+)
+
+const SessionCookieName = "session_token"
+
+// dummyHash is compared against when the email is unknown or the account has
+// no password, so that a failed login takes the same time either way.
+var dummyHash, _ = bcrypt.GenerateFromPassword([]byte("not-a-real-password"), bcrypt.DefaultCost)
+
+type AuthService struct {
+	users         repo.UserRepository
+	sessions      repo.SessionRepository
+	refreshTokens repo.RefreshTokenRepository
+	apiKeys       repo.APIKeyRepository
+	settings      Settings
+}
+
+// Settings holds the lifetimes and signing key used by AuthService.
+type Settings struct {
+	SessionTTL      time.Duration
+	JWTSecret       []byte
+	AccessTokenTTL  time.Duration
+	RefreshTokenTTL time.Duration
+	// AdminEmails are granted the admin role when they register, so a fresh
+	// deployment has someone who can assign roles to everybody else.
+	AdminEmails []string
+}
+
+type SessionResult struct {
+	User      *models.User
+	Token     string
+	ExpiresAt time.Time
+}
+
+func NewAuthService(userRepo repo.UserRepository, sessionRepo repo.SessionRepository, refreshRepo repo.RefreshTokenRepository, apiKeyRepo repo.APIKeyRepository, settings Settings) *AuthService {
+	return &AuthService{
+		users:         userRepo,
+		sessions:      sessionRepo,
+		refreshTokens: refreshRepo,
+		apiKeys:       apiKeyRepo,
+		settings:      settings,
+	}
+}
+
+func (s *AuthService) Register(ctx context.Context, r *models.RegisterRequest) (*models.User, error) {
+	if r.Name == "" || r.Email == "" || r.Password == "" {
+		return nil, er.ErrInvalidUserData
+	}
+
+	email := models.NormalizeEmail(r.Email)
+	if _, err := s.users.GetByEmail(ctx, email); err == nil {
+		return nil, er.ErrConflict
+	} else if !errors.Is(err, er.ErrUserNotFound) {
+		return nil, err
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(r.Password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, err
+	}
+
+	role := models.RoleMember
+	for _, admin := range s.settings.AdminEmails {
+		if strings.EqualFold(admin, email) {
+			role = models.RoleAdmin
+			break
+		}
+	}
+
+	user := models.User{
+		Id:           uuid.New().String(),
+		Name:         r.Name,
+		Email:        email,
+		PasswordHash: string(hash),
+		Role:         role,
+		Version:      1,
+		CreatedAt:    time.Now(),
+	}
+
+	if err := s.users.Create(ctx, &user); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (s *AuthService) Login(ctx context.Context, r *models.LoginRequest) (*SessionResult, error) {
+	user, err := s.checkCredentials(ctx, r)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.StartSession(ctx, user)
+}
+
+func (s *AuthService) checkCredentials(ctx context.Context, r *models.LoginRequest) (*models.User, error) {
+	user, err := s.users.GetByEmail(ctx, models.NormalizeEmail(r.Email))
+	if err != nil {
+		if errors.Is(err, er.ErrUserNotFound) {
+			bcrypt.CompareHashAndPassword(dummyHash, []byte(r.Password))
+			return nil, er.ErrInvalidCredentials
+		}
+		return nil, err
+	}
+
+	if user.PasswordHash == "" {
+		bcrypt.CompareHashAndPassword(dummyHash, []byte(r.Password))
+		return nil, er.ErrInvalidCredentials
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(r.Password)); err != nil {
+		return nil, er.ErrInvalidCredentials
+	}
+
+	return user, nil
+}
+
+// StartSession issues a new session token for user. Only the token hash is
+// persisted; the plain token is returned once, to be set as a cookie.
+func (s *AuthService) StartSession(ctx context.Context, user *models.User) (*SessionResult, error) {
+	token, err := newToken()
+	if err != nil {
+		return nil, err
+	}
+
+	session := models.Session{
+		Id:        hashToken(token),
+		UserID:    user.Id,
+		ExpiresAt: time.Now().Add(s.settings.SessionTTL),
+		CreatedAt: time.Now(),
+	}
+	if err := s.sessions.Create(ctx, &session); err != nil {
+		return nil, err
+	}
+
+	return &SessionResult{User: user, Token: token, ExpiresAt: session.ExpiresAt}, nil
+}
+
+func (s *AuthService) Logout(ctx context.Context, token string) error {
+	if token == "" {
+		return nil
+	}
+	return s.sessions.Delete(ctx, hashToken(token))
+}
+
+// AuthenticateSession resolves a session token to the caller it belongs to.
+func (s *AuthService) AuthenticateSession(ctx context.Context, token string) (Principal, error) {
+	if token == "" {
+		return Principal{}, er.ErrUnauthorized
+	}
+
+	id := hashToken(token)
+	session, err := s.sessions.GetByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, er.ErrSessionNotFound) {
+			return Principal{}, er.ErrUnauthorized
+		}
+		return Principal{}, err
+	}
+
+	if time.Now().After(session.ExpiresAt) {
+		s.sessions.Delete(ctx, id)
+		return Principal{}, er.ErrUnauthorized
+	}
+
+	return s.principalFor(ctx, session.UserID)
+}
+
+// AuthenticateBearer resolves a signed access token to the caller it was
+// issued to.
+func (s *AuthService) AuthenticateBearer(ctx context.Context, token string) (Principal, error) {
+	userID, err := s.VerifyAccessToken(token)
+	if err != nil {
+		return Principal{}, err
+	}
+
+	return s.principalFor(ctx, userID)
+}
+
+// principalFor loads the user behind a credential, so that role changes and
+// deleted accounts take effect immediately instead of when tokens expire.
+func (s *AuthService) principalFor(ctx context.Context, userID string) (Principal, error) {
+	user, err := s.users.GetByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, er.ErrUserNotFound) {
+			return Principal{}, er.ErrUnauthorized
+		}
+		return Principal{}, err
+	}
+
+	role := user.Role
+	if role == "" {
+		role = models.RoleMember
+	}
+	return Principal{UserID: user.Id, Role: role}, nil
+}
+
+func (s *AuthService) CurrentUser(ctx context.Context) (*models.User, error) {
+	userID, ok := UserIDFromContext(ctx)
+	if !ok {
+		return nil, er.ErrUnauthorized
+	}
+	return s.users.GetByID(ctx, userID)
+}
+
+func newToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,269 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	er "deu/internal/errors"
+	"deu/internal/models"
+	"deu/internal/repository"
+)
+
+var testSecret = []byte("test-secret")
+
+func newTestService(t *testing.T) (*AuthService, *repository.MemoryBackend) {
+	t.Helper()
+	b := repository.NewMemoryBackend()
+	s := NewAuthService(b.Users, b.Sessions, b.RefreshTokens, b.APIKeys, Settings{
+		SessionTTL:      time.Hour,
+		JWTSecret:       testSecret,
+		AccessTokenTTL:  time.Minute,
+		RefreshTokenTTL: time.Hour,
+	})
+	return s, b
+}
+
+func register(t *testing.T, s *AuthService, email, password string) *models.User {
+	t.Helper()
+	user, err := s.Register(context.Background(), &models.RegisterRequest{Name: "tester", Email: email, Password: password})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return user
+}
+
+func TestLoginChecksCredentials(t *testing.T) {
+	ctx := context.Background()
+	s, b := newTestService(t)
+	register(t, s, "ada@example.com", "correct horse")
+
+	// A user created without a password, e.g. by an admin, cannot log in.
+	noPassword := models.User{Id: "00000000-0000-0000-0000-0000000000aa", Name: "nopass", Email: "nopass@example.com", Role: models.RoleMember}
+	if err := b.Users.Create(ctx, &noPassword); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		email    string
+		password string
+		wantErr  error
+	}{
+		{"right password", "ada@example.com", "correct horse", nil},
+		{"email in another case", " ADA@example.com", "correct horse", nil},
+		{"wrong password", "ada@example.com", "wrong horse", er.ErrInvalidCredentials},
+		{"unknown email", "bob@example.com", "correct horse", er.ErrInvalidCredentials},
+		{"no password set", "nopass@example.com", "", er.ErrInvalidCredentials},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := &models.LoginRequest{Email: tt.email, Password: tt.password}
+
+			session, err := s.Login(ctx, req)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Login returned %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && session.Token == "" {
+				t.Error("Login returned no session token")
+			}
+
+			if _, err := s.IssueTokens(ctx, req); !errors.Is(err, tt.wantErr) {
+				t.Errorf("IssueTokens returned %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSessionLastsUntilLogout(t *testing.T) {
+	ctx := context.Background()
+	s, _ := newTestService(t)
+	user := register(t, s, "ada@example.com", "correct horse")
+
+	session, err := s.Login(ctx, &models.LoginRequest{Email: "ada@example.com", Password: "correct horse"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if principal, err := s.AuthenticateSession(ctx, session.Token); err != nil || principal.UserID != user.Id {
+		t.Fatalf("AuthenticateSession = %+v, %v, want user %s", principal, err, user.Id)
+	}
+
+	if err := s.Logout(ctx, session.Token); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.AuthenticateSession(ctx, session.Token); !errors.Is(err, er.ErrUnauthorized) {
+		t.Errorf("AuthenticateSession after logout returned %v, want ErrUnauthorized", err)
+	}
+	if _, err := s.AuthenticateSession(ctx, "not-a-token"); !errors.Is(err, er.ErrUnauthorized) {
+		t.Errorf("AuthenticateSession of an unknown token returned %v, want ErrUnauthorized", err)
+	}
+}
+
+func TestRefreshRotatesTokens(t *testing.T) {
+	ctx := context.Background()
+	s, _ := newTestService(t)
+	user := register(t, s, "ada@example.com", "correct horse")
+
+	first, err := s.IssueTokens(ctx, &models.LoginRequest{Email: "ada@example.com", Password: "correct horse"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := s.Refresh(ctx, first.RefreshToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second.RefreshToken == first.RefreshToken {
+		t.Fatal("Refresh returned the same refresh token")
+	}
+	if id, err := s.VerifyAccessToken(second.AccessToken); err != nil || id != user.Id {
+		t.Errorf("VerifyAccessToken = %q, %v, want %q", id, err, user.Id)
+	}
+	if _, err := s.Refresh(ctx, "not-a-token"); !errors.Is(err, er.ErrInvalidToken) {
+		t.Errorf("Refresh of an unknown token returned %v, want ErrInvalidToken", err)
+	}
+}
+
+func TestRefreshTokenReuseRevokesFamily(t *testing.T) {
+	ctx := context.Background()
+	s, _ := newTestService(t)
+	register(t, s, "ada@example.com", "correct horse")
+	login := &models.LoginRequest{Email: "ada@example.com", Password: "correct horse"}
+
+	first, err := s.IssueTokens(ctx, login)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := s.IssueTokens(ctx, login)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := s.Refresh(ctx, first.RefreshToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The rotated token is presented again, as an attacker holding a copy
+	// of it would.
+	if _, err := s.Refresh(ctx, first.RefreshToken); !errors.Is(err, er.ErrTokenReused) {
+		t.Fatalf("reusing a rotated token returned %v, want ErrTokenReused", err)
+	}
+	if _, err := s.Refresh(ctx, second.RefreshToken); err == nil {
+		t.Error("the token issued by the rotation still works after the reuse")
+	}
+
+	// Other logins have their own families and are not affected.
+	if _, err := s.Refresh(ctx, other.RefreshToken); err != nil {
+		t.Errorf("a token of another family stopped working: %v", err)
+	}
+}
+
+func signed(t *testing.T, method jwt.SigningMethod, key any, claims jwt.Claims) string {
+	t.Helper()
+	token, err := jwt.NewWithClaims(method, claims).SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func TestVerifyAccessTokenRejectsForgedTokens(t *testing.T) {
+	s, _ := newTestService(t)
+	now := time.Now()
+	valid := jwt.RegisteredClaims{
+		Issuer:    tokenIssuer,
+		Subject:   "00000000-0000-0000-0000-000000000001",
+		IssuedAt:  jwt.NewNumericDate(now),
+		ExpiresAt: jwt.NewNumericDate(now.Add(time.Minute)),
+	}
+	with := func(change func(c *jwt.RegisteredClaims)) jwt.RegisteredClaims {
+		c := valid
+		change(&c)
+		return c
+	}
+
+	tests := []struct {
+		name  string
+		token string
+		ok    bool
+	}{
+		{"valid", signed(t, jwt.SigningMethodHS256, testSecret, valid), true},
+		{"alg none", signed(t, jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType, valid), false},
+		{"other HMAC algorithm", signed(t, jwt.SigningMethodHS512, testSecret, valid), false},
+		{"wrong secret", signed(t, jwt.SigningMethodHS256, []byte("other-secret"), valid), false},
+		{"wrong issuer", signed(t, jwt.SigningMethodHS256, testSecret, with(func(c *jwt.RegisteredClaims) { c.Issuer = "someone-else" })), false},
+		{"no issuer", signed(t, jwt.SigningMethodHS256, testSecret, with(func(c *jwt.RegisteredClaims) { c.Issuer = "" })), false},
+		{"expired", signed(t, jwt.SigningMethodHS256, testSecret, with(func(c *jwt.RegisteredClaims) { c.ExpiresAt = jwt.NewNumericDate(now.Add(-time.Minute)) })), false},
+		{"no expiry", signed(t, jwt.SigningMethodHS256, testSecret, with(func(c *jwt.RegisteredClaims) { c.ExpiresAt = nil })), false},
+		{"no subject", signed(t, jwt.SigningMethodHS256, testSecret, with(func(c *jwt.RegisteredClaims) { c.Subject = "" })), false},
+		{"garbage", "not.a.jwt", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := s.VerifyAccessToken(tt.token)
+			if tt.ok && err != nil {
+				t.Errorf("VerifyAccessToken rejected a valid token: %v", err)
+			}
+			if !tt.ok && !errors.Is(err, er.ErrInvalidToken) {
+				t.Errorf("VerifyAccessToken returned %v, want ErrInvalidToken", err)
+			}
+		})
+	}
+}
+
+func TestAPIKeyCallersCannotManageKeys(t *testing.T) {
+	s, _ := newTestService(t)
+	user := register(t, s, "ada@example.com", "correct horse")
+
+	asUser := WithPrincipal(context.Background(), Principal{UserID: user.Id, Role: user.Role})
+	created, err := s.CreateAPIKey(asUser, user.Id, &models.APIKeyCreateRequest{Name: "ci", Scope: models.APIKeyScopeReadWrite})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	principal, err := s.AuthenticateAPIKey(context.Background(), created.Key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	asKey := WithPrincipal(context.Background(), principal)
+
+	if _, err := s.CreateAPIKey(asKey, user.Id, &models.APIKeyCreateRequest{Name: "minted"}); !errors.Is(err, er.ErrForbidden) {
+		t.Errorf("CreateAPIKey with an API key returned %v, want ErrForbidden", err)
+	}
+	if _, err := s.ListAPIKeys(asKey, user.Id); !errors.Is(err, er.ErrForbidden) {
+		t.Errorf("ListAPIKeys with an API key returned %v, want ErrForbidden", err)
+	}
+	if err := s.RevokeAPIKey(asKey, user.Id, created.Id); !errors.Is(err, er.ErrForbidden) {
+		t.Errorf("RevokeAPIKey with an API key returned %v, want ErrForbidden", err)
+	}
+}
+
+func TestAuthenticateAPIKey(t *testing.T) {
+	s, _ := newTestService(t)
+	user := register(t, s, "ada@example.com", "correct horse")
+	asUser := WithPrincipal(context.Background(), Principal{UserID: user.Id, Role: user.Role})
+
+	readOnly, err := s.CreateAPIKey(asUser, user.Id, &models.APIKeyCreateRequest{Name: "default scope"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	principal, err := s.AuthenticateAPIKey(context.Background(), readOnly.Key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !principal.ReadOnly() || principal.UserID != user.Id || principal.APIKeyID != readOnly.Id {
+		t.Errorf("principal = %+v, want a read-only principal of the key's owner", principal)
+	}
+
+	if err := s.RevokeAPIKey(asUser, user.Id, readOnly.Id); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.AuthenticateAPIKey(context.Background(), readOnly.Key); !errors.Is(err, er.ErrInvalidToken) {
+		t.Errorf("a revoked key authenticated: %v", err)
+	}
+	if _, err := s.AuthenticateAPIKey(context.Background(), "tt_unknown"); !errors.Is(err, er.ErrInvalidToken) {
+		t.Errorf("an unknown key authenticated: %v", err)
+	}
+}
@@ -0,0 +1,135 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+
+	er "deu/internal/errors"
+	"deu/internal/models"
+)
+
+const tokenIssuer = "traveler-track"
+
+// IssueTokens exchanges email and password for a short-lived access token
+// and a refresh token that starts a new rotation family.
+func (s *AuthService) IssueTokens(ctx context.Context, r *models.LoginRequest) (*models.TokenPair, error) {
+	user, err := s.checkCredentials(ctx, r)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.issueTokenPair(ctx, user.Id, uuid.New().String())
+}
+
+// Refresh rotates a refresh token: the presented token is revoked and a new
+// pair is issued in the same family. Presenting a token that was already
+// rotated means it has leaked, so the whole family is revoked.
+func (s *AuthService) Refresh(ctx context.Context, refreshToken string) (*models.TokenPair, error) {
+	id := hashToken(refreshToken)
+
+	token, err := s.refreshTokens.GetByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, er.ErrTokenNotFound) {
+			return nil, er.ErrInvalidToken
+		}
+		return nil, err
+	}
+
+	if token.RevokedAt != nil {
+		return nil, s.revokeReusedFamily(ctx, token)
+	}
+	if time.Now().After(token.ExpiresAt) {
+		return nil, er.ErrInvalidToken
+	}
+
+	if err := s.refreshTokens.Revoke(ctx, id); err != nil {
+		if errors.Is(err, er.ErrTokenRevoked) {
+			return nil, s.revokeReusedFamily(ctx, token)
+		}
+		return nil, err
+	}
+
+	return s.issueTokenPair(ctx, token.UserID, token.FamilyID)
+}
+
+// RevokeRefreshToken ends the token family the given refresh token belongs to.
+func (s *AuthService) RevokeRefreshToken(ctx context.Context, refreshToken string) error {
+	token, err := s.refreshTokens.GetByID(ctx, hashToken(refreshToken))
+	if err != nil {
+		if errors.Is(err, er.ErrTokenNotFound) {
+			return nil
+		}
+		return err
+	}
+	return s.refreshTokens.RevokeFamily(ctx, token.FamilyID)
+}
+
+// VerifyAccessToken checks the signature and expiry of a bearer token and
+// returns the user id it was issued to.
+func (s *AuthService) VerifyAccessToken(tokenString string) (string, error) {
+	claims := &jwt.RegisteredClaims{}
+	_, err := jwt.ParseWithClaims(tokenString, claims, func(t *jwt.Token) (interface{}, error) {
+		return s.settings.JWTSecret, nil
+	},
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
+		jwt.WithIssuer(tokenIssuer),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil || claims.Subject == "" {
+		return "", er.ErrInvalidToken
+	}
+
+	return claims.Subject, nil
+}
+
+func (s *AuthService) revokeReusedFamily(ctx context.Context, token *models.RefreshToken) error {
+	slog.Warn("Refresh token reuse detected, revoking family", "user_id", token.UserID, "family_id", token.FamilyID)
+	if err := s.refreshTokens.RevokeFamily(ctx, token.FamilyID); err != nil {
+		return err
+	}
+	return er.ErrTokenReused
+}
+
+func (s *AuthService) issueTokenPair(ctx context.Context, userID, familyID string) (*models.TokenPair, error) {
+	now := time.Now()
+
+	claims := jwt.RegisteredClaims{
+		Issuer:    tokenIssuer,
+		Subject:   userID,
+		IssuedAt:  jwt.NewNumericDate(now),
+		ExpiresAt: jwt.NewNumericDate(now.Add(s.settings.AccessTokenTTL)),
+		ID:        uuid.New().String(),
+	}
+	accessToken, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(s.settings.JWTSecret)
+	if err != nil {
+		return nil, err
+	}
+
+	refreshToken, err := newToken()
+	if err != nil {
+		return nil, err
+	}
+
+	stored := models.RefreshToken{
+		Id:        hashToken(refreshToken),
+		UserID:    userID,
+		FamilyID:  familyID,
+		ExpiresAt: now.Add(s.settings.RefreshTokenTTL),
+		CreatedAt: now,
+	}
+	if err := s.refreshTokens.Create(ctx, &stored); err != nil {
+		return nil, err
+	}
+
+	return &models.TokenPair{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int(s.settings.AccessTokenTTL.Seconds()),
+	}, nil
+}
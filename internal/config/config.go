@@ -1,41 +1,97 @@
-package config
-
-import (
-	"encoding/json"
-	"os"
-)
-
-type Config struct {
-	ServerPort             string `json:"server_port"`
-	DatabaseURL            string `json:"database_url"`
-	LogLevel               string `json:"log_level"`
-	EnableCache            bool   `json:"enable_cache"`
-	RequestTimeoutSeconds  int    `json:"request_timeout_seconds"`
-	MaxConnections         int    `json:"max_connections"`
-	EnableRequestLogging   bool   `json:"enable_request_logging"`
-	AllowPlaceDeletion     bool   `json:"allow_place_deletion"`
-}
-
-func Load(path string) (*Config, error) {
-	file, err := os.Open(path)
-	if err != nil {
-		return nil, err
-	}
-	defer file.Close()
-
-	var cfg Config
-	if err := json.NewDecoder(file).Decode(&cfg); err != nil {
-		return nil, err
-	}
-
-	cfg.DatabaseURL = os.Getenv("DATABASE_URL")
-
-	if envPort := os.Getenv("SERVER_PORT"); envPort != "" {
-		cfg.ServerPort = envPort
-	}
-	if envLog := os.Getenv("LOG_LEVEL"); envLog != "" {
-		cfg.LogLevel = envLog
-	}
-
-	return &cfg, nil
-}
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+type Config struct {
+	ServerPort             string   `json:"server_port"`
+	DatabaseURL            string   `json:"database_url"`
+	LogLevel               string   `json:"log_level"`
+	EnableCache            bool     `json:"enable_cache"`
+	// EnableCache caches places by id for GET /places/{id} and the lookups
+	// that go through it; listings, search and nearby queries always read
+	// the database. CacheMaxEntries bounds the cache; CacheTTLSeconds is how
+	// long a cached place is served before it is read again.
+	CacheMaxEntries        int      `json:"cache_max_entries"`
+	CacheTTLSeconds        int      `json:"cache_ttl_seconds"`
+	RequestTimeoutSeconds  int      `json:"request_timeout_seconds"`
+	MaxConnections         int      `json:"max_connections"`
+	EnableRequestLogging   bool     `json:"enable_request_logging"`
+	AllowPlaceDeletion     bool     `json:"allow_place_deletion"`
+	SessionTTLHours        int      `json:"session_ttl_hours"`
+	JWTSecret              string   `json:"jwt_secret"`
+	AccessTokenTTLMinutes  int      `json:"access_token_ttl_minutes"`
+	RefreshTokenTTLHours   int      `json:"refresh_token_ttl_hours"`
+	AdminEmails            []string `json:"admin_emails"`
+	PhotoStorageDir        string   `json:"photo_storage_dir"`
+	MaxPhotoUploadMB       int      `json:"max_photo_upload_mb"`
+	TrashRetentionDays     int      `json:"trash_retention_days"`
+	// AutoMigrate applies pending migrations at startup. Without it the
+	// server refuses to start on an outdated schema.
+	AutoMigrate            bool     `json:"auto_migrate"`
+	// StorageBackend is "postgres" (the default), "sqlite" or "memory".
+	StorageBackend         string   `json:"storage_backend"`
+	// MemorySnapshotPath is where the memory backend keeps its data between
+	// runs. When empty, the data is lost on shutdown.
+	MemorySnapshotPath     string   `json:"memory_snapshot_path"`
+	// SQLitePath is the database file of the sqlite backend.
+	SQLitePath             string   `json:"sqlite_path"`
+}
+
+func Load(path string) (*Config, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var cfg Config
+	if err := json.NewDecoder(file).Decode(&cfg); err != nil {
+		return nil, err
+	}
+
+	cfg.DatabaseURL = os.Getenv("DATABASE_URL")
+
+	if envPort := os.Getenv("SERVER_PORT"); envPort != "" {
+		cfg.ServerPort = envPort
+	}
+	if envLog := os.Getenv("LOG_LEVEL"); envLog != "" {
+		cfg.LogLevel = envLog
+	}
+	if envSecret := os.Getenv("JWT_SECRET"); envSecret != "" {
+		cfg.JWTSecret = envSecret
+	}
+
+	if envPhotos := os.Getenv("PHOTO_STORAGE_DIR"); envPhotos != "" {
+		cfg.PhotoStorageDir = envPhotos
+	}
+
+	if envAdmins := os.Getenv("ADMIN_EMAILS"); envAdmins != "" {
+		cfg.AdminEmails = strings.Split(envAdmins, ",")
+	}
+
+	if envMigrate := os.Getenv("AUTO_MIGRATE"); envMigrate != "" {
+		autoMigrate, err := strconv.ParseBool(envMigrate)
+		if err != nil {
+			return nil, fmt.Errorf("AUTO_MIGRATE: %w", err)
+		}
+		cfg.AutoMigrate = autoMigrate
+	}
+
+	if envBackend := os.Getenv("STORAGE_BACKEND"); envBackend != "" {
+		cfg.StorageBackend = envBackend
+	}
+	if envSnapshot := os.Getenv("MEMORY_SNAPSHOT_PATH"); envSnapshot != "" {
+		cfg.MemorySnapshotPath = envSnapshot
+	}
+	if envSQLite := os.Getenv("SQLITE_PATH"); envSQLite != "" {
+		cfg.SQLitePath = envSQLite
+	}
+
+	return &cfg, nil
+}
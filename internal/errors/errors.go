@@ -1,20 +1,65 @@
-package errors
-
-import (
-	"errors"
-)
-
-var (
-	// 400 Errors
-	ErrInvalidInputData      = errors.New("Invalid input data. Please check your request payload.")
-	ErrInvalidPlaceData      = errors.New("Invalid place data.")
-	ErrInvalidUserData		 = errors.New("Invalid user data.")
-	// 404 Errors
-	ErrUserNotFound          = errors.New("User not found.")
-	ErrPlaceNotFound         = errors.New("Place not found.")
-	// 409 Errors
-	ErrConflict              = errors.New("Username or email already exists.")
-	// 500 Errors
-	ErrInternalServer        = errors.New("An unexpected server error occurred.")
-	ErrJSONMarshalFailed     = errors.New("Failed to process internal data.")
+package errors
+
+import (
+	"errors"
+)
+
+var (
+	// 400 Errors
+	ErrInvalidInputData      = errors.New("Invalid input data. Please check your request payload.")
+	ErrInvalidPlaceData      = errors.New("Invalid place data.")
+	ErrInvalidUserData		 = errors.New("Invalid user data.")
+	ErrInvalidAPIKeyData     = errors.New("Invalid API key data.")
+	ErrInvalidVisitData      = errors.New("Invalid visit data.")
+	ErrInvalidReviewData     = errors.New("Invalid review data.")
+	ErrInvalidQuery          = errors.New("Invalid query parameters.")
+	ErrInvalidTagData        = errors.New("Invalid tag data.")
+	ErrInvalidPhotoData      = errors.New("The photo could not be read as an image.")
+	ErrNothingToRevert       = errors.New("This history entry has no version to revert to.")
+	ErrInvalidJSON           = errors.New("The request body is not valid JSON.")
+	ErrValidation            = errors.New("The request has invalid fields.")
+	ErrInvalidID             = errors.New("The id in the path must be a valid UUID.")
+	ErrUnknownTag            = errors.New("One or more of the given tags do not exist.")
+	// 401 Errors
+	ErrUnauthorized          = errors.New("Authentication required.")
+	ErrInvalidCredentials    = errors.New("Invalid email or password.")
+	ErrInvalidToken          = errors.New("Invalid or expired token.")
+	ErrTokenReused           = errors.New("Refresh token reuse detected. Please log in again.")
+	ErrTokenRevoked          = errors.New("Token has already been revoked.")
+	// 403 Errors
+	ErrForbidden             = errors.New("You are not allowed to perform this action.")
+	// 404 Errors
+	ErrUserNotFound          = errors.New("User not found.")
+	ErrPlaceNotFound         = errors.New("Place not found.")
+	ErrSessionNotFound       = errors.New("Session not found.")
+	ErrTokenNotFound         = errors.New("Token not found.")
+	ErrAPIKeyNotFound        = errors.New("API key not found.")
+	ErrVisitNotFound         = errors.New("Visit not found.")
+	ErrReviewNotFound        = errors.New("Review not found.")
+	ErrTagNotFound           = errors.New("Tag not found.")
+	ErrPhotoNotFound         = errors.New("Photo not found.")
+	ErrAuditEntryNotFound    = errors.New("History entry not found.")
+	// 409 Errors
+	ErrConflict              = errors.New("An account with this email already exists.")
+	ErrDuplicate             = errors.New("A record with the same unique values already exists.")
+	ErrTagExists             = errors.New("A tag with this name already exists.")
+	ErrSessionExists         = errors.New("A session with this id already exists.")
+	ErrTokenExists           = errors.New("A token with this id already exists.")
+	ErrPhotoExists           = errors.New("A photo with this id already exists.")
+	ErrReviewExists          = errors.New("A review with this id already exists.")
+	ErrVisitExists           = errors.New("A visit with this id already exists.")
+	// 412 Errors
+	ErrVersionMismatch       = errors.New("The resource was changed since it was read. Fetch it again and retry.")
+	// 413 Errors
+	ErrPhotoTooLarge         = errors.New("Photo is too large.")
+	ErrBodyTooLarge          = errors.New("The request body is too large.")
+	// 415 Errors
+	ErrUnsupportedPhotoType  = errors.New("Photos must be JPEG, PNG or GIF images.")
+	// 428 Errors
+	ErrPreconditionRequired  = errors.New("This request must be conditional. Send the resource's ETag in If-Match.")
+	// 500 Errors
+	ErrInternalServer        = errors.New("An unexpected server error occurred.")
+	ErrJSONMarshalFailed     = errors.New("Failed to process internal data.")
+	// 503 Errors
+	ErrServerBusy            = errors.New("The server is handling too many requests. Try again shortly.")
 )
\ No newline at end of file
@@ -1,7 +1,25 @@
-package errors
-
-
-type ErrorResponse struct {
-	Code 	int 	`json:"code"`
-	Message string	`json:"message"`
-}
+package errors
+
+
+// ErrorResponse is an RFC 7807 problem details body, sent with the
+// application/problem+json content type. Type is always about:blank, so
+// Title is the HTTP status text; Code tells errors with the same status
+// apart and is meant for programs, Detail for people.
+type ErrorResponse struct {
+	Type      string       `json:"type"`
+	Title     string       `json:"title"`
+	Status    int          `json:"status"`
+	Detail    string       `json:"detail,omitempty"`
+	Instance  string       `json:"instance,omitempty"`
+	Code      string       `json:"code"`
+	RequestID string       `json:"requestId,omitempty"`
+	Errors    []FieldError `json:"errors,omitempty"`
+}
+
+// FieldError describes one request field that failed validation. Field is
+// the JSON path of the field and Code the rule it broke.
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
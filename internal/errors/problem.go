@@ -0,0 +1,171 @@
+package errors
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+)
+
+// ProblemContentType is the media type of ErrorResponse bodies.
+const ProblemContentType = "application/problem+json"
+
+// kind is how a domain error is reported over HTTP.
+type kind struct {
+	err    error
+	status int
+	code   string
+}
+
+// kinds maps every domain error to its status and code. Errors are matched
+// with errors.Is, so wrapped errors are reported like the error they wrap.
+var kinds = []kind{
+	{ErrInvalidInputData, http.StatusBadRequest, "invalid_input"},
+	{ErrInvalidPlaceData, http.StatusBadRequest, "invalid_place"},
+	{ErrInvalidUserData, http.StatusBadRequest, "invalid_user"},
+	{ErrInvalidAPIKeyData, http.StatusBadRequest, "invalid_api_key"},
+	{ErrInvalidVisitData, http.StatusBadRequest, "invalid_visit"},
+	{ErrInvalidReviewData, http.StatusBadRequest, "invalid_review"},
+	{ErrInvalidQuery, http.StatusBadRequest, "invalid_query"},
+	{ErrInvalidTagData, http.StatusBadRequest, "invalid_tag"},
+	{ErrInvalidPhotoData, http.StatusBadRequest, "invalid_photo"},
+	{ErrNothingToRevert, http.StatusBadRequest, "nothing_to_revert"},
+	{ErrInvalidJSON, http.StatusBadRequest, "invalid_json"},
+	{ErrValidation, http.StatusBadRequest, "validation_failed"},
+	{ErrInvalidID, http.StatusBadRequest, "invalid_id"},
+	{ErrUnknownTag, http.StatusBadRequest, "unknown_tag"},
+
+	{ErrUnauthorized, http.StatusUnauthorized, "unauthorized"},
+	{ErrInvalidCredentials, http.StatusUnauthorized, "invalid_credentials"},
+	{ErrInvalidToken, http.StatusUnauthorized, "invalid_token"},
+	{ErrTokenReused, http.StatusUnauthorized, "token_reused"},
+	{ErrTokenRevoked, http.StatusUnauthorized, "token_revoked"},
+
+	{ErrForbidden, http.StatusForbidden, "forbidden"},
+
+	{ErrUserNotFound, http.StatusNotFound, "user_not_found"},
+	{ErrPlaceNotFound, http.StatusNotFound, "place_not_found"},
+	{ErrSessionNotFound, http.StatusNotFound, "session_not_found"},
+	{ErrTokenNotFound, http.StatusNotFound, "token_not_found"},
+	{ErrAPIKeyNotFound, http.StatusNotFound, "api_key_not_found"},
+	{ErrVisitNotFound, http.StatusNotFound, "visit_not_found"},
+	{ErrReviewNotFound, http.StatusNotFound, "review_not_found"},
+	{ErrTagNotFound, http.StatusNotFound, "tag_not_found"},
+	{ErrPhotoNotFound, http.StatusNotFound, "photo_not_found"},
+	{ErrAuditEntryNotFound, http.StatusNotFound, "history_entry_not_found"},
+
+	{ErrConflict, http.StatusConflict, "email_taken"},
+	{ErrTagExists, http.StatusConflict, "tag_exists"},
+	{ErrSessionExists, http.StatusConflict, "session_exists"},
+	{ErrTokenExists, http.StatusConflict, "token_exists"},
+	{ErrPhotoExists, http.StatusConflict, "photo_exists"},
+	{ErrReviewExists, http.StatusConflict, "review_exists"},
+	{ErrVisitExists, http.StatusConflict, "visit_exists"},
+	{ErrDuplicate, http.StatusConflict, "duplicate"},
+
+	{ErrVersionMismatch, http.StatusPreconditionFailed, "version_mismatch"},
+	{ErrPhotoTooLarge, http.StatusRequestEntityTooLarge, "photo_too_large"},
+	{ErrBodyTooLarge, http.StatusRequestEntityTooLarge, "body_too_large"},
+	{ErrUnsupportedPhotoType, http.StatusUnsupportedMediaType, "unsupported_photo_type"},
+	{ErrPreconditionRequired, http.StatusPreconditionRequired, "precondition_required"},
+
+	{ErrInternalServer, http.StatusInternalServerError, "internal_error"},
+	{ErrJSONMarshalFailed, http.StatusInternalServerError, "internal_error"},
+
+	{ErrServerBusy, http.StatusServiceUnavailable, "server_busy"},
+}
+
+// Status returns the HTTP status and error code for err. Errors that are
+// not domain errors are internal server errors.
+func Status(err error) (int, string) {
+	for _, k := range kinds {
+		if errors.Is(err, k.err) {
+			return k.status, k.code
+		}
+	}
+	return http.StatusInternalServerError, "internal_error"
+}
+
+// detailed is a domain error with a more specific message for the client.
+type detailed struct {
+	err    error
+	detail string
+}
+
+func (d *detailed) Error() string { return d.detail }
+
+func (d *detailed) Unwrap() error { return d.err }
+
+// WithDetail wraps err so clients see the formatted message instead of the
+// generic one. The result still matches err with errors.Is.
+func WithDetail(err error, format string, args ...any) error {
+	return &detailed{err: err, detail: fmt.Sprintf(format, args...)}
+}
+
+// validation carries the fields that made a request invalid.
+type validation struct {
+	fields []FieldError
+}
+
+func (v *validation) Error() string { return ErrValidation.Error() }
+
+func (v *validation) Unwrap() error { return ErrValidation }
+
+// Validation returns an ErrValidation listing the fields at fault.
+func Validation(fields []FieldError) error {
+	return &validation{fields: fields}
+}
+
+// Problem builds the response body for err. The text of errors that are not
+// domain errors may contain internals, so it is replaced by a generic
+// message and logged along with the request id instead.
+func Problem(r *http.Request, err error) ErrorResponse {
+	status, code := Status(err)
+	p := ErrorResponse{
+		Type:      "about:blank",
+		Title:     http.StatusText(status),
+		Status:    status,
+		Detail:    err.Error(),
+		Instance:  r.URL.Path,
+		Code:      code,
+		RequestID: RequestID(r.Context()),
+	}
+
+	var v *validation
+	if errors.As(err, &v) {
+		p.Errors = v.fields
+	}
+
+	if status == http.StatusInternalServerError && !errors.Is(err, ErrInternalServer) && !errors.Is(err, ErrJSONMarshalFailed) {
+		slog.Error("Request failed", "method", r.Method, "path", r.URL.Path, "request_id", p.RequestID, "error", err)
+		p.Detail = ErrInternalServer.Error()
+	}
+	return p
+}
+
+// Write sends err to the client as a problem details response.
+func Write(w http.ResponseWriter, r *http.Request, err error) {
+	p := Problem(r, err)
+
+	w.Header().Set("Content-Type", ProblemContentType)
+	if p.Status == http.StatusUnauthorized {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="api"`)
+	}
+	w.WriteHeader(p.Status)
+	json.NewEncoder(w).Encode(p)
+}
+
+type requestIDKey struct{}
+
+// WithRequestID returns a copy of ctx carrying the id of the current request.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestID returns the id of the current request, or "" outside of one.
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
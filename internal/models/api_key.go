@@ -0,0 +1,36 @@
+package models
+
+import "time"
+
+const (
+	APIKeyScopeReadOnly  = "read-only"
+	APIKeyScopeReadWrite = "read-write"
+)
+
+// APIKey is a personal credential for scripts and integrations. Only the
+// SHA-256 hash of the key is stored; Prefix is kept so users can tell their
+// keys apart in listings.
+type APIKey struct {
+	Id         string     `gorm:"primaryKey;type:uuid;default:gen_random_uuid()" json:"id"`
+	UserID     string     `gorm:"type:uuid;not null;index" json:"userId"`
+	Name       string     `gorm:"type:varchar(100);not null" json:"name"`
+	Prefix     string     `gorm:"type:varchar(16);not null" json:"prefix"`
+	KeyHash    string     `gorm:"type:varchar(64);not null;uniqueIndex" json:"-"`
+	Scope      string     `gorm:"type:varchar(20);not null" json:"scope"`
+	ExpiresAt  *time.Time `json:"expiresAt,omitempty"`
+	LastUsedAt *time.Time `json:"lastUsedAt,omitempty"`
+	CreatedAt  time.Time  `json:"createdAt"`
+}
+
+type APIKeyCreateRequest struct {
+	Name      string     `json:"name" validate:"required,min=1,max=100"`
+	Scope     string     `json:"scope,omitempty" validate:"omitempty,oneof=read-only read-write"`
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+}
+
+// APIKeyCreated is returned once, when the key is created. The plain key
+// cannot be retrieved again afterwards.
+type APIKeyCreated struct {
+	APIKey
+	Key string `json:"key"`
+}
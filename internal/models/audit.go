@@ -0,0 +1,39 @@
+package models
+
+import "time"
+
+const (
+	AuditEntityPlace = "place"
+	AuditEntityUser  = "user"
+
+	AuditCreate  = "create"
+	AuditUpdate  = "update"
+	AuditDelete  = "delete"
+	AuditRestore = "restore"
+	AuditPurge   = "purge"
+	AuditRevert  = "revert"
+)
+
+// AuditEntry records one change to a place or user: who made it, when, and
+// how each field changed. Snapshot holds the audited fields as they were
+// after the change, which is what a revert goes back to. Entries are kept
+// after their entity is purged.
+type AuditEntry struct {
+	Id         string         `gorm:"primaryKey;type:uuid;default:gen_random_uuid()" json:"id"`
+	EntityType string         `gorm:"type:varchar(20);not null;index:idx_audit_entries_entity" json:"entityType"`
+	EntityID   string         `gorm:"type:uuid;not null;index:idx_audit_entries_entity" json:"entityId"`
+	Action     string         `gorm:"type:varchar(20);not null" json:"action"`
+	ActorID    *string        `gorm:"type:uuid" json:"actorId"`
+	Changes    []FieldChange  `gorm:"type:jsonb;serializer:json;not null" json:"changes"`
+	Snapshot   map[string]any `gorm:"type:jsonb;serializer:json" json:"snapshot,omitempty"`
+	RevertOf   *string        `gorm:"type:uuid" json:"revertOf,omitempty"`
+	CreatedAt  time.Time      `gorm:"index:idx_audit_entries_entity,sort:desc" json:"createdAt"`
+}
+
+// FieldChange is the before and after value of one field. Before is null
+// for created fields.
+type FieldChange struct {
+	Field  string `json:"field"`
+	Before any    `json:"before"`
+	After  any    `json:"after"`
+}
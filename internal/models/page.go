@@ -0,0 +1,12 @@
+package models
+
+// Page is one page of a paginated listing. Total is left out when counting
+// would be expensive, which is the case when paging by cursor. NextCursor is
+// set when more items follow.
+type Page[T any] struct {
+	Items      []T    `json:"items"`
+	Total      *int64 `json:"total,omitempty"`
+	Limit      int    `json:"limit"`
+	Offset     int    `json:"offset"`
+	NextCursor string `json:"nextCursor,omitempty"`
+}
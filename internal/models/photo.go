@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+// Photo describes an uploaded picture of a place. The image bytes and their
+// thumbnail live in the blob store; Checksum is the SHA-256 of the stored
+// original and doubles as its ETag.
+type Photo struct {
+	Id            string    `gorm:"primaryKey;type:uuid;default:gen_random_uuid()" json:"id"`
+	PlaceID       string    `gorm:"type:uuid;not null;index" json:"placeId"`
+	UploadedBy    *string   `gorm:"type:uuid" json:"uploadedBy"`
+	ContentType   string    `gorm:"type:varchar(50);not null" json:"contentType"`
+	ThumbnailType string    `gorm:"type:varchar(50);not null" json:"-"`
+	Size          int64     `gorm:"not null" json:"size"`
+	Width         int       `gorm:"not null" json:"width"`
+	Height        int       `gorm:"not null" json:"height"`
+	Checksum      string    `gorm:"type:char(64);not null" json:"-"`
+	CreatedAt     time.Time `json:"createdAt"`
+
+	URL          string `gorm:"-" json:"url"`
+	ThumbnailURL string `gorm:"-" json:"thumbnailUrl"`
+}
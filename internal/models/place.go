@@ -1,61 +1,115 @@
-package models
-
-import (
-	"database/sql/driver"
-	"encoding/json"
-	"errors"
-	"time"
-
-	"gorm.io/gorm"
-)
-
-type Location struct {
-	Latitude  float64 `json:"latitude" validate:"required,latitude"`
-	Longitude float64 `json:"longitude" validate:"required,longitude"`
-}
-
-// Value implements driver.Valuer interface for JSONB storage
-func (l Location) Value() (driver.Value, error) {
-	return json.Marshal(l)
-}
-
-// Scan implements sql.Scanner interface for JSONB retrieval
-func (l *Location) Scan(value interface{}) error {
-	if value == nil {
-		return nil
-	}
-
-	bytes, ok := value.([]byte)
-	if !ok {
-		return errors.New("failed to unmarshal JSONB value")
-	}
-
-	return json.Unmarshal(bytes, l)
-}
-
-type PlaceCreateRequest struct {
-	Name        string      `json:"name" validate:"required,min=5,max=100"`
-	Description string      `json:"description" validate:"required,min=10,max=1000"`
-	Location    Location    `json:"location" validate:"required"`
-	Address     string      `json:"address" validate:"required,max=255"`
-	Rating      int         `json:"averageRating" validate:"required,min=1,max=5"`
-}
-
-type Place struct {
-	gorm.Model
-	Id 			string 		`gorm:"primaryKey;type:uuid;default:gen_random_uuid()" json:"id"`
-	Name 		string 		`gorm:"type:varchar(255);not null" json:"name"`
-	Description string 		`gorm:"type:text" json:"description"`
-	Location	Location	`gorm:"type:jsonb" json:"location"`
-	Address		string 		`gorm:"type:varchar(255)" json:"address"`
-	Rating		int 		`gorm:"type:numeric" json:"rating"`
-	CreatedAt 	time.Time	`json:"createdAt"`
-}
-
-type PlaceUpdateRequest struct {
-	Name        *string     `json:"name,omitempty" validate:"omitempty,min=5,max=100"`
-	Description *string     `json:"description,omitempty" validate:"omitempty,min=10,max=1000"`
-	Location    *Location   `json:"location,omitempty" validate:"omitempty"`
-	Address     *string     `json:"address,omitempty" validate:"omitempty,max=255"`
-	Rating      *int        `json:"averageRating,omitempty" validate:"omitempty,min=1,max=5"`
-}
\ No newline at end of file
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Location is stored as plain latitude and longitude columns so the
+// database can index them for distance queries.
+type Location struct {
+	Latitude  float64 `gorm:"type:double precision;not null" json:"latitude" validate:"required,latitude"`
+	Longitude float64 `gorm:"type:double precision;not null" json:"longitude" validate:"required,longitude"`
+}
+
+type PlaceCreateRequest struct {
+	Name        string      `json:"name" validate:"required,min=5,max=100"`
+	Description string      `json:"description" validate:"required,min=10,max=1000"`
+	Location    Location    `json:"location" validate:"required"`
+	Address     string      `json:"address" validate:"required,max=255"`
+	Tags        []string    `json:"tags,omitempty" validate:"omitempty,max=20,dive,required,max=50"`
+}
+
+type Place struct {
+	gorm.Model
+	Id 			string 		`gorm:"primaryKey;type:uuid;default:gen_random_uuid()" json:"id"`
+	Name 		string 		`gorm:"type:varchar(255);not null" json:"name"`
+	Description string 		`gorm:"type:text" json:"description"`
+	Location	Location	`gorm:"embedded" json:"location"`
+	Address		string 		`gorm:"type:varchar(255)" json:"address"`
+	AverageRating float64	`gorm:"type:numeric(3,2);not null;default:0;<-:false" json:"averageRating"`
+	ReviewCount	int			`gorm:"not null;default:0;<-:false" json:"reviewCount"`
+	CreatedBy	*string		`gorm:"type:uuid" json:"createdBy"`
+	Tags		[]Tag		`gorm:"many2many:place_tags;joinForeignKey:PlaceID;joinReferences:TagID" json:"tags"`
+	// Version is bumped on every write and guards updates with If-Match.
+	Version		int64		`gorm:"not null;default:1" json:"version"`
+	CreatedAt 	time.Time	`json:"createdAt"`
+}
+
+// Clone returns a deep copy of p that shares no memory with it.
+func (p *Place) Clone() *Place {
+	c := *p
+	if p.CreatedBy != nil {
+		createdBy := *p.CreatedBy
+		c.CreatedBy = &createdBy
+	}
+	if p.Tags != nil {
+		c.Tags = append(make([]Tag, 0, len(p.Tags)), p.Tags...)
+	}
+	return &c
+}
+
+type PlaceUpdateRequest struct {
+	Name        *string     `json:"name,omitempty" validate:"omitempty,min=5,max=100"`
+	Description *string     `json:"description,omitempty" validate:"omitempty,min=10,max=1000"`
+	Location    *Location   `json:"location,omitempty" validate:"omitempty"`
+	Address     *string     `json:"address,omitempty" validate:"omitempty,max=255"`
+	// Tags replaces the place's tags when present; an empty list clears them.
+	Tags        *[]string   `json:"tags,omitempty" validate:"omitempty,max=20,dive,required,max=50"`
+}
+
+// PlaceWithDistance is a place returned by a geospatial query together with
+// its great-circle distance from the query's origin.
+type PlaceWithDistance struct {
+	Place
+	DistanceMeters float64 `gorm:"column:distance_m;->" json:"distanceMeters"`
+}
+
+// BoundingBox is given as minLng,minLat,maxLng,maxLat. A box whose MinLng
+// is greater than its MaxLng crosses the antimeridian.
+type BoundingBox struct {
+	MinLng float64
+	MinLat float64
+	MaxLng float64
+	MaxLat float64
+}
+
+// Center returns the middle of the box, used as the origin for distances.
+func (b BoundingBox) Center() (lat, lng float64) {
+	lat = (b.MinLat + b.MaxLat) / 2
+	if b.MinLng <= b.MaxLng {
+		return lat, (b.MinLng + b.MaxLng) / 2
+	}
+	lng = (b.MinLng + b.MaxLng + 360) / 2
+	if lng > 180 {
+		lng -= 360
+	}
+	return lat, lng
+}
+
+// GeoQuery selects places around Latitude/Longitude, either within
+// RadiusMeters or inside BBox, nearest first.
+type GeoQuery struct {
+	Latitude     float64
+	Longitude    float64
+	RadiusMeters float64
+	BBox         *BoundingBox
+	Limit        int
+	Offset       int
+}
+// SearchQuery is a free-text search over a place's name, address and
+// description. Every word of Text also matches as a prefix so the query
+// can drive autocomplete.
+type SearchQuery struct {
+	Text   string
+	Limit  int
+	Offset int
+}
+
+// PlaceSearchResult is a place matched by a SearchQuery with its relevance;
+// higher scores rank first.
+type PlaceSearchResult struct {
+	Place
+	Score float64 `gorm:"column:score;->" json:"score"`
+}
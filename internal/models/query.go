@@ -0,0 +1,98 @@
+package models
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strconv"
+	"time"
+)
+
+const (
+	PlaceSortName      = "name"
+	PlaceSortCreatedAt = "createdAt"
+	PlaceSortRating    = "averageRating"
+
+	UserSortName      = "username"
+	UserSortEmail     = "email"
+	UserSortCreatedAt = "createdAt"
+)
+
+// Cursor marks the last item of a page for keyset pagination. It records
+// the sort it was issued for so it cannot be replayed against another one.
+type Cursor struct {
+	Sort  string `json:"s"`
+	Desc  bool   `json:"d,omitempty"`
+	Value string `json:"v"`
+	Id    string `json:"id"`
+}
+
+// Encode returns the cursor as an opaque URL-safe token.
+func (c Cursor) Encode() string {
+	raw, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+// DecodeCursor parses a token produced by Cursor.Encode.
+func DecodeCursor(token string) (*Cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, err
+	}
+	var c Cursor
+	if err := json.Unmarshal(raw, &c); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// PlaceQuery selects a page of places. Either Offset or Cursor is used, not
+// both. Zero-valued filters are ignored. Tags holds slugs; a place has to
+// carry all of them to match.
+type PlaceQuery struct {
+	Limit        int
+	Offset       int
+	Cursor       *Cursor
+	Sort         string
+	Desc         bool
+	NamePrefix   string
+	MinRating    *float64
+	MaxRating    *float64
+	CreatedAfter *time.Time
+	Tags         []string
+}
+
+// UserQuery selects a page of users. Name and Email match case-insensitive
+// substrings.
+type UserQuery struct {
+	Limit  int
+	Offset int
+	Cursor *Cursor
+	Sort   string
+	Desc   bool
+	Name   string
+	Email  string
+}
+
+// SortValue returns the value of the given sort field in cursor form.
+func (p Place) SortValue(sort string) string {
+	switch sort {
+	case PlaceSortCreatedAt:
+		return p.CreatedAt.UTC().Format(time.RFC3339Nano)
+	case PlaceSortRating:
+		return strconv.FormatFloat(p.AverageRating, 'f', -1, 64)
+	default:
+		return p.Name
+	}
+}
+
+// SortValue returns the value of the given sort field in cursor form.
+func (u User) SortValue(sort string) string {
+	switch sort {
+	case UserSortCreatedAt:
+		return u.CreatedAt.UTC().Format(time.RFC3339Nano)
+	case UserSortEmail:
+		return u.Email
+	default:
+		return u.Name
+	}
+}
@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+// Review is a user's rating of a place. Each user has at most one review per
+// place; posting again edits it. Hidden reviews are left out of public
+// listings and of the place's average rating.
+type Review struct {
+	Id        string     `gorm:"primaryKey;type:uuid;default:gen_random_uuid()" json:"id"`
+	PlaceID   string     `gorm:"type:uuid;not null;uniqueIndex:uq_reviews_place_user" json:"placeId"`
+	UserID    string     `gorm:"type:uuid;not null;uniqueIndex:uq_reviews_place_user" json:"userId"`
+	Rating    int        `gorm:"type:smallint;not null" json:"rating"`
+	Comment   string     `gorm:"type:text" json:"comment"`
+	Hidden    bool       `gorm:"not null;default:false" json:"hidden"`
+	HiddenBy  *string    `gorm:"type:uuid" json:"hiddenBy,omitempty"`
+	HiddenAt  *time.Time `json:"hiddenAt,omitempty"`
+	CreatedAt time.Time  `json:"createdAt"`
+	UpdatedAt time.Time  `json:"updatedAt"`
+}
+
+type ReviewRequest struct {
+	Rating  int    `json:"rating" validate:"required,min=1,max=5"`
+	Comment string `json:"comment" validate:"omitempty,max=2000"`
+}
+
+type ReviewVisibilityRequest struct {
+	Hidden *bool `json:"hidden" validate:"required"`
+}
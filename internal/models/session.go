@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+// Session is a server-side login session. Id holds the SHA-256 hash of the
+// token handed to the client, so a leaked table does not leak live sessions.
+type Session struct {
+	Id        string    `gorm:"primaryKey;type:varchar(64)" json:"-"`
+	UserID    string    `gorm:"type:uuid;not null;index" json:"userId"`
+	ExpiresAt time.Time `gorm:"not null" json:"expiresAt"`
+	CreatedAt time.Time `json:"createdAt"`
+}
@@ -0,0 +1,44 @@
+package models
+
+import (
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Tag classifies places, e.g. "Museum" or "Beach". Places refer to tags by
+// slug in requests and filters.
+type Tag struct {
+	Id        string    `gorm:"primaryKey;type:uuid;default:gen_random_uuid()" json:"id"`
+	Name      string    `gorm:"type:varchar(50);not null" json:"name"`
+	Slug      string    `gorm:"type:varchar(50);not null;uniqueIndex" json:"slug"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+type TagRequest struct {
+	Name string `json:"name" validate:"required,min=2,max=50"`
+}
+
+// TagFacet counts the places carrying a tag among those matching a query.
+type TagFacet struct {
+	Id    string `json:"id"`
+	Name  string `json:"name"`
+	Slug  string `json:"slug"`
+	Count int64  `json:"count"`
+}
+
+// PlacePage is a page of places together with the tag facets of the whole
+// result set. Like Total, Facets is only filled in for the first page.
+type PlacePage struct {
+	Page[Place]
+	Facets []TagFacet `json:"facets,omitempty"`
+}
+
+// Slugify turns a tag name into its slug: lower-case letters and digits
+// separated by single dashes, so "Art & Museums" becomes "art-museums".
+func Slugify(name string) string {
+	words := strings.FieldsFunc(strings.ToLower(name), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	return strings.Join(words, "-")
+}
@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+// RefreshToken is a stored, single-use refresh token. Id holds the SHA-256
+// hash of the token. Tokens issued from the same login share a FamilyID so
+// the whole chain can be revoked when reuse of a rotated token is detected.
+type RefreshToken struct {
+	Id        string     `gorm:"primaryKey;type:varchar(64)" json:"-"`
+	UserID    string     `gorm:"type:uuid;not null;index" json:"userId"`
+	FamilyID  string     `gorm:"type:uuid;not null;index" json:"familyId"`
+	ExpiresAt time.Time  `gorm:"not null" json:"expiresAt"`
+	RevokedAt *time.Time `json:"revokedAt,omitempty"`
+	CreatedAt time.Time  `json:"createdAt"`
+}
+
+type RefreshRequest struct {
+	RefreshToken string `json:"refreshToken" validate:"required"`
+}
+
+type TokenPair struct {
+	AccessToken  string `json:"accessToken"`
+	RefreshToken string `json:"refreshToken"`
+	TokenType    string `json:"tokenType"`
+	ExpiresIn    int    `json:"expiresIn"`
+}
@@ -1,29 +1,88 @@
-package models
-
-import (
-	"time"
-	"gorm.io/gorm"
-)
-
-type User struct {
-	gorm.Model
-	Id 			string 		`gorm:"primaryKey;type:uuid;default:gen_random_uuid()" json:"id"`
-	Name 		string 		`gorm:"type:varchar(255);not null" json:"username"`
-	Email 		string 		`gorm:"uniqueIndex;type:varchar(255);not null" json:"email"`
-	CreatedAt 	time.Time 	`json:"createdAt"`
-}
-
-type UserCreateRequest struct {
-	Name        string  `json:"username" validate:"required,min=3,max=50"`
-	Email       string  `json:"email" validate:"required,email"`
-}
-
-type UserUpdateRequest struct {
-	Name        *string     `json:"username,omitempty" validate:"omitempty,min=3,max=50"`
-	Email       *string     `json:"email,omitempty" validate:"omitempty,email"`
-}
-
-type UserPlace struct {
-	UserID    string `gorm:"primaryKey;type:uuid" json:"user_id" validate:"required,uuid"`
-	PlaceID   string `gorm:"primaryKey;type:uuid" json:"place_id" validate:"required,uuid"`
+package models
+
+import (
+	"strings"
+	"time"
+	"gorm.io/gorm"
+)
+
+const (
+	RoleAdmin  = "admin"
+	RoleEditor = "editor"
+	RoleMember = "member"
+)
+
+type User struct {
+	gorm.Model
+	Id 			string 		`gorm:"primaryKey;type:uuid;default:gen_random_uuid()" json:"id"`
+	Name 		string 		`gorm:"type:varchar(255);not null" json:"username"`
+	Email 		string 		`gorm:"uniqueIndex:idx_users_email,expression:lower(email),where:deleted_at IS NULL;type:varchar(255);not null" json:"email"`
+	PasswordHash string		`gorm:"type:varchar(255)" json:"-"`
+	Role 		string 		`gorm:"type:varchar(20);not null;default:member" json:"role"`
+	// Version is bumped on every write and guards updates with If-Match.
+	Version		int64		`gorm:"not null;default:1" json:"version"`
+	CreatedAt 	time.Time 	`json:"createdAt"`
+}
+
+// NormalizeEmail trims an email address and lower-cases it, so addresses
+// differing only in case belong to the same account.
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// UserSummary is the public view of a user shown to other users.
+type UserSummary struct {
+	Id          string  `json:"id"`
+	Name        string  `json:"username"`
+}
+
+type UserCreateRequest struct {
+	Name        string  `json:"username" validate:"required,min=3,max=50"`
+	Email       string  `json:"email" validate:"required,email"`
+}
+
+type UserUpdateRequest struct {
+	Name        *string     `json:"username,omitempty" validate:"omitempty,min=3,max=50"`
+	Email       *string     `json:"email,omitempty" validate:"omitempty,email"`
+}
+
+type UserRoleRequest struct {
+	Role        string  `json:"role" validate:"required,oneof=admin editor member"`
+}
+
+type RegisterRequest struct {
+	Name        string  `json:"username" validate:"required,min=3,max=50"`
+	Email       string  `json:"email" validate:"required,email"`
+	Password    string  `json:"password" validate:"required,min=8,max=72"`
+}
+
+type LoginRequest struct {
+	Email       string  `json:"email" validate:"required,email"`
+	Password    string  `json:"password" validate:"required"`
+}
+
+// UserPlace is a single visit of a user to a place. A user may visit the
+// same place any number of times; each visit is its own record.
+type UserPlace struct {
+	Id        string     `gorm:"primaryKey;type:uuid;default:gen_random_uuid()" json:"id"`
+	UserID    string     `gorm:"type:uuid;not null;index" json:"user_id" validate:"required,uuid"`
+	PlaceID   string     `gorm:"type:uuid;not null;index" json:"place_id" validate:"required,uuid"`
+	VisitedAt time.Time  `gorm:"not null" json:"visitedAt"`
+	Note      *string    `gorm:"type:text" json:"note,omitempty"`
+	Rating    *int       `gorm:"type:smallint" json:"rating,omitempty"`
+	PhotoRef  *string    `gorm:"type:varchar(512)" json:"photoRef,omitempty"`
+	CreatedAt time.Time  `json:"createdAt"`
+}
+
+type VisitCreateRequest struct {
+	VisitedAt   *time.Time  `json:"visitedAt,omitempty"`
+	Note        *string     `json:"note,omitempty" validate:"omitempty,max=2000"`
+	Rating      *int        `json:"rating,omitempty" validate:"omitempty,min=1,max=5"`
+	PhotoRef    *string     `json:"photoRef,omitempty" validate:"omitempty,max=512"`
+}
+
+// VisitHistory lists a user's visits to one place, most recent first.
+type VisitHistory struct {
+	Visited     bool            `json:"visited"`
+	Visits      []UserPlace     `json:"visits"`
 }
\ No newline at end of file
@@ -1,195 +1,787 @@
-package places
-
-import (
-	"encoding/json"
-	"fmt"
-	"net/http"
-	"strings"
-
-	er "deu/internal/errors"
-	"deu/internal/models"
-
-	"github.com/go-playground/validator/v10"
-)
-
-var validate *validator.Validate
-
-func init() {
-	validate = validator.New()
-}
-
-func writeJSON(w http.ResponseWriter, status int, data interface{}) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(status)
-	if data != nil {
-		json.NewEncoder(w).Encode(data)
-	}
-}
-
-func validateRequest(s interface{}) map[string]string {
-	err := validate.Struct(s)
-	if err == nil {
-		return nil
-	}
-
-	errorsMap := make(map[string]string)
-	for _, err := range err.(validator.ValidationErrors) {
-		errorsMap[err.Field()] = fmt.Sprintf("Field '%s' failed validation: tag '%s'. Required value: %s",
-			err.Field(),
-			err.Tag(),
-			err.Param(),
-		)
-	}
-
-	return errorsMap
-}
-
-func validateAndGetID(w http.ResponseWriter, parts []string) (string, bool) {
-	if len(parts) < 3 || parts[2] == "" {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Place ID missing in path"})
-		return "", false
-	}
-	id := parts[2]
-	
-	if err := validate.Var(id, "required,uuid"); err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Place ID must be a valid UUID"})
-		return "", false
-	}
-	return id, true
-}
-
-type Handler struct {
-	Service       *PlaceService
-	AllowDeletion bool
-}
-
-// GET /places
-func (h *Handler) GetAll(w http.ResponseWriter, r *http.Request) {
-	resp, err := h.Service.GetAll(r.Context())
-	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
-		return
-	}
-
-	writeJSON(w, http.StatusOK, resp)
-}
-
-// GET /places/{id}
-func (h *Handler) GetById(w http.ResponseWriter, r *http.Request) {
-	parts := strings.Split(r.URL.Path, "/")
-	
-	id, ok := validateAndGetID(w, parts)
-	if !ok {
-		return
-	}
-
-	p, err := h.Service.GetById(r.Context(), id)
-	if err != nil {
-		if err == er.ErrPlaceNotFound {
-			writeJSON(w, http.StatusNotFound, map[string]string{"error": "Place not found"})
-			return
-		}
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
-		return
-	}
-
-	writeJSON(w, http.StatusOK, p)
-}
-
-// POST /places
-func (h *Handler) Create(w http.ResponseWriter, r *http.Request) {
-	var p models.PlaceCreateRequest
-	if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid JSON format"})
-		return
-	}
-	
-	if errorsMap := validateRequest(p); errorsMap != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"validation_errors": errorsMap})
-		return
-	}
-
-	place, err := h.Service.Create(r.Context(), &p)
-	if err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
-		return
-	}
-
-	writeJSON(w, http.StatusCreated, *place)
-}
-
-// PATCH /places/{id}
-func (h *Handler) Update(w http.ResponseWriter, r *http.Request) {
-	parts := strings.Split(r.URL.Path, "/")
-	
-	id, ok := validateAndGetID(w, parts)
-	if !ok {
-		return
-	}
-	
-	var p models.PlaceUpdateRequest
-	if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid JSON format"})
-		return
-	}
-	
-	if errorsMap := validateRequest(p); errorsMap != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"validation_errors": errorsMap})
-		return
-	}
-
-	err := h.Service.Update(r.Context(), id, &p)
-	if err != nil {
-		if err == er.ErrPlaceNotFound {
-			writeJSON(w, http.StatusNotFound, map[string]string{"error": "Place not found"})
-			return
-		}
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
-		return
-	}
-
-	writeJSON(w, http.StatusOK, map[string]string{"status": "updated"})
-}
-
-// DELETE /places/{id}
-func (h *Handler) DeleteById(w http.ResponseWriter, r *http.Request) {
-	if !h.AllowDeletion {
-		writeJSON(w, http.StatusForbidden, map[string]string{"error": "Place deletion is disabled by system configuration"})
-		return
-	}
-
-	parts := strings.Split(r.URL.Path, "/")
-	
-	id, ok := validateAndGetID(w, parts)
-	if !ok {
-		return
-	}
-
-	err := h.Service.DeleteById(r.Context(), id)
-	if err != nil {
-		if err == er.ErrPlaceNotFound {
-			writeJSON(w, http.StatusNotFound, map[string]string{"error": "Place not found"})
-			return
-		}
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
-		return
-	}
-
-	writeJSON(w, http.StatusOK, map[string]string{"status": "deleted"})
-}
-
-// DELETE /places
-func (h *Handler) DeleteAll(w http.ResponseWriter, r *http.Request) {
-	if !h.AllowDeletion {
-		writeJSON(w, http.StatusForbidden, map[string]string{"error": "Place deletion is disabled by system configuration"})
-		return
-	}
-
-	err := h.Service.DeleteAll(r.Context())
-	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
-		return
-	}
-
-	writeJSON(w, http.StatusOK, map[string]string{"status": "all deleted"})
-}
\ No newline at end of file
+package places
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	er "deu/internal/errors"
+	"deu/internal/models"
+	"deu/pkg/httpx"
+)
+
+type Handler struct {
+	Service       *PlaceService
+	AllowDeletion bool
+	// MaxPhotoBytes caps the size of an uploaded photo; zero means
+	// defaultMaxPhotoBytes.
+	MaxPhotoBytes int64
+	// TrashRetention is how long deleted places stay restorable before
+	// DELETE /trash/places may purge them.
+	TrashRetention time.Duration
+}
+
+// parsePlaceQuery reads paging, sorting and filter parameters for GET /places.
+func parsePlaceQuery(w http.ResponseWriter, r *http.Request) (models.PlaceQuery, bool) {
+	limit, offset, ok := httpx.Pagination(w, r)
+	if !ok {
+		return models.PlaceQuery{}, false
+	}
+
+	query := r.URL.Query()
+	q := models.PlaceQuery{
+		Limit:      limit,
+		Offset:     offset,
+		Sort:       models.PlaceSortName,
+		NamePrefix: query.Get("name"),
+	}
+
+	if v := query.Get("sort"); v != "" {
+		switch v {
+		case models.PlaceSortName, models.PlaceSortCreatedAt, models.PlaceSortRating:
+			q.Sort = v
+		default:
+			er.Write(w, r, er.WithDetail(er.ErrInvalidQuery, "sort must be one of name, createdAt, averageRating"))
+			return q, false
+		}
+	}
+	switch query.Get("order") {
+	case "", "asc":
+	case "desc":
+		q.Desc = true
+	default:
+		er.Write(w, r, er.WithDetail(er.ErrInvalidQuery, "order must be asc or desc"))
+		return q, false
+	}
+
+	for name, target := range map[string]**float64{"minRating": &q.MinRating, "maxRating": &q.MaxRating} {
+		if v := query.Get(name); v != "" {
+			f, err := strconv.ParseFloat(v, 64)
+			if err != nil || f < 0 || f > 5 {
+				er.Write(w, r, er.WithDetail(er.ErrInvalidQuery, "%s must be a number between 0 and 5", name))
+				return q, false
+			}
+			*target = &f
+		}
+	}
+	if v := query.Get("createdAfter"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			er.Write(w, r, er.WithDetail(er.ErrInvalidQuery, "createdAfter must be an RFC 3339 timestamp"))
+			return q, false
+		}
+		q.CreatedAfter = &t
+	}
+
+	// Tags may be repeated (?tag=a&tag=b) or comma-separated (?tag=a,b).
+	var tags []string
+	for _, v := range query["tag"] {
+		tags = append(tags, strings.Split(v, ",")...)
+	}
+	q.Tags = slugs(tags)
+
+	if v := query.Get("cursor"); v != "" {
+		cursor, err := models.DecodeCursor(v)
+		if err != nil {
+			er.Write(w, r, er.WithDetail(er.ErrInvalidQuery, "Invalid cursor"))
+			return q, false
+		}
+		q.Cursor = cursor
+	}
+
+	return q, true
+}
+
+// maxRadiusMeters is roughly half the Earth's circumference.
+const maxRadiusMeters = 20000000
+
+// parseCoordinate reads a required latitude or longitude query parameter.
+func parseCoordinate(w http.ResponseWriter, r *http.Request, name string, limit float64) (float64, bool) {
+	f, err := strconv.ParseFloat(r.URL.Query().Get(name), 64)
+	if err != nil || f < -limit || f > limit {
+		er.Write(w, r, er.WithDetail(er.ErrInvalidQuery, "%s must be a number between %g and %g", name, -limit, limit))
+		return 0, false
+	}
+	return f, true
+}
+
+// parseBoundingBox reads bbox=minLng,minLat,maxLng,maxLat.
+func parseBoundingBox(w http.ResponseWriter, r *http.Request, value string) (*models.BoundingBox, bool) {
+	fields := strings.Split(value, ",")
+	if len(fields) == 4 {
+		var v [4]float64
+		valid := true
+		for i, field := range fields {
+			f, err := strconv.ParseFloat(strings.TrimSpace(field), 64)
+			if err != nil {
+				valid = false
+				break
+			}
+			v[i] = f
+		}
+		b := models.BoundingBox{MinLng: v[0], MinLat: v[1], MaxLng: v[2], MaxLat: v[3]}
+		if valid && b.MinLat <= b.MaxLat &&
+			b.MinLat >= -90 && b.MaxLat <= 90 &&
+			b.MinLng >= -180 && b.MinLng <= 180 && b.MaxLng >= -180 && b.MaxLng <= 180 {
+			return &b, true
+		}
+	}
+
+	er.Write(w, r, er.WithDetail(er.ErrInvalidQuery, "bbox must be minLng,minLat,maxLng,maxLat in degrees"))
+	return nil, false
+}
+
+// GET /places/nearby
+func (h *Handler) Nearby(w http.ResponseWriter, r *http.Request) {
+	lat, ok := parseCoordinate(w, r, "lat", 90)
+	if !ok {
+		return
+	}
+	lng, ok := parseCoordinate(w, r, "lng", 180)
+	if !ok {
+		return
+	}
+
+	radius, err := strconv.ParseFloat(r.URL.Query().Get("radius_m"), 64)
+	if err != nil || radius <= 0 || radius > maxRadiusMeters {
+		er.Write(w, r, er.WithDetail(er.ErrInvalidQuery, "radius_m must be a number between 0 and %d", maxRadiusMeters))
+		return
+	}
+
+	limit, offset, ok := httpx.Pagination(w, r)
+	if !ok {
+		return
+	}
+
+	page, err := h.Service.Nearby(r.Context(), models.GeoQuery{Latitude: lat, Longitude: lng, RadiusMeters: radius, Limit: limit, Offset: offset})
+	if err != nil {
+		er.Write(w, r, err)
+		return
+	}
+
+	httpx.JSON(w, http.StatusOK, page)
+}
+
+// GET /places/search?q=
+func (h *Handler) Search(w http.ResponseWriter, r *http.Request) {
+	text := r.URL.Query().Get("q")
+	if strings.TrimSpace(text) == "" {
+		er.Write(w, r, er.WithDetail(er.ErrInvalidQuery, "q is required"))
+		return
+	}
+
+	limit, offset, ok := httpx.Pagination(w, r)
+	if !ok {
+		return
+	}
+
+	page, err := h.Service.Search(r.Context(), models.SearchQuery{Text: text, Limit: limit, Offset: offset})
+	if err != nil {
+		if errors.Is(err, er.ErrInvalidQuery) {
+			er.Write(w, r, er.WithDetail(er.ErrInvalidQuery, "q must be at most %d characters", maxSearchLength))
+			return
+		}
+		er.Write(w, r, err)
+		return
+	}
+
+	httpx.JSON(w, http.StatusOK, page)
+}
+
+// withinBoundingBox serves GET /places?bbox=..., which is sorted by distance
+// from the middle of the box rather than by the usual sort parameters.
+func (h *Handler) withinBoundingBox(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	if query.Has("cursor") || query.Has("sort") || query.Has("order") {
+		er.Write(w, r, er.WithDetail(er.ErrInvalidQuery, "bbox results are sorted by distance and cannot be combined with cursor, sort or order"))
+		return
+	}
+
+	bbox, ok := parseBoundingBox(w, r, query.Get("bbox"))
+	if !ok {
+		return
+	}
+
+	limit, offset, ok := httpx.Pagination(w, r)
+	if !ok {
+		return
+	}
+
+	lat, lng := bbox.Center()
+	page, err := h.Service.Nearby(r.Context(), models.GeoQuery{Latitude: lat, Longitude: lng, BBox: bbox, Limit: limit, Offset: offset})
+	if err != nil {
+		er.Write(w, r, err)
+		return
+	}
+
+	httpx.JSON(w, http.StatusOK, page)
+}
+
+// GET /places
+func (h *Handler) GetAll(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Query().Has("bbox") {
+		h.withinBoundingBox(w, r)
+		return
+	}
+
+	q, ok := parsePlaceQuery(w, r)
+	if !ok {
+		return
+	}
+
+	resp, err := h.Service.List(r.Context(), q)
+	if err != nil {
+		if errors.Is(err, er.ErrInvalidQuery) {
+			er.Write(w, r, er.WithDetail(er.ErrInvalidQuery, "Invalid cursor for this sort, or cursor combined with offset"))
+			return
+		}
+		er.Write(w, r, err)
+		return
+	}
+
+	httpx.JSON(w, http.StatusOK, resp)
+}
+
+// GET /places/{id}
+func (h *Handler) GetById(w http.ResponseWriter, r *http.Request) {
+	id, ok := httpx.PathUUID(w, r, "id")
+	if !ok {
+		return
+	}
+
+	p, err := h.Service.GetById(r.Context(), id)
+	if err != nil {
+		er.Write(w, r, err)
+		return
+	}
+
+	etag := httpx.VersionETag(p.Version)
+	w.Header().Set("ETag", etag)
+	if httpx.ETagMatches(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	httpx.JSON(w, http.StatusOK, p)
+}
+
+// GET /places/{id}/visitors
+func (h *Handler) ListVisitors(w http.ResponseWriter, r *http.Request) {
+	id, ok := httpx.PathUUID(w, r, "id")
+	if !ok {
+		return
+	}
+
+	limit, offset, ok := httpx.Pagination(w, r)
+	if !ok {
+		return
+	}
+
+	page, err := h.Service.ListVisitors(r.Context(), id, limit, offset)
+	if err != nil {
+		er.Write(w, r, err)
+		return
+	}
+
+	httpx.JSON(w, http.StatusOK, page)
+}
+
+// POST /places
+func (h *Handler) Create(w http.ResponseWriter, r *http.Request) {
+	var p models.PlaceCreateRequest
+	if !httpx.Decode(w, r, &p) {
+		return
+	}
+
+	place, err := h.Service.Create(r.Context(), &p)
+	if err != nil {
+		er.Write(w, r, err)
+		return
+	}
+
+	httpx.JSON(w, http.StatusCreated, *place)
+}
+
+// PATCH /places/{id}
+func (h *Handler) Update(w http.ResponseWriter, r *http.Request) {
+	id, ok := httpx.PathUUID(w, r, "id")
+	if !ok {
+		return
+	}
+	
+	var p models.PlaceUpdateRequest
+	if !httpx.Decode(w, r, &p) {
+		return
+	}
+
+	version, ok := httpx.IfMatchVersion(w, r)
+	if !ok {
+		return
+	}
+
+	place, err := h.Service.Update(r.Context(), id, &p, version)
+	if err != nil {
+		er.Write(w, r, err)
+		return
+	}
+
+	w.Header().Set("ETag", httpx.VersionETag(place.Version))
+	httpx.Status(w, http.StatusOK, "updated")
+}
+
+// DELETE /places/{id}
+func (h *Handler) DeleteById(w http.ResponseWriter, r *http.Request) {
+	if !h.AllowDeletion {
+		er.Write(w, r, er.WithDetail(er.ErrForbidden, "Place deletion is disabled by system configuration"))
+		return
+	}
+
+	id, ok := httpx.PathUUID(w, r, "id")
+	if !ok {
+		return
+	}
+
+	version, ok := httpx.IfMatchVersion(w, r)
+	if !ok {
+		return
+	}
+
+	err := h.Service.DeleteById(r.Context(), id, version)
+	if err != nil {
+		er.Write(w, r, err)
+		return
+	}
+
+	httpx.Status(w, http.StatusOK, "deleted")
+}
+
+// DELETE /places
+func (h *Handler) DeleteAll(w http.ResponseWriter, r *http.Request) {
+	if !h.AllowDeletion {
+		er.Write(w, r, er.WithDetail(er.ErrForbidden, "Place deletion is disabled by system configuration"))
+		return
+	}
+
+	err := h.Service.DeleteAll(r.Context())
+	if err != nil {
+		er.Write(w, r, err)
+		return
+	}
+
+	httpx.Status(w, http.StatusOK, "all deleted")
+}
+
+// GET /admin/cache
+func (h *Handler) CacheStats(w http.ResponseWriter, r *http.Request) {
+	httpx.JSON(w, http.StatusOK, h.Service.CacheStats())
+}
+
+// GET /trash/places
+func (h *Handler) ListDeleted(w http.ResponseWriter, r *http.Request) {
+	limit, offset, ok := httpx.Pagination(w, r)
+	if !ok {
+		return
+	}
+
+	page, err := h.Service.ListDeleted(r.Context(), limit, offset)
+	if err != nil {
+		er.Write(w, r, err)
+		return
+	}
+
+	httpx.JSON(w, http.StatusOK, page)
+}
+
+// POST /places/{id}/restore
+func (h *Handler) Restore(w http.ResponseWriter, r *http.Request) {
+	id, ok := httpx.PathUUID(w, r, "id")
+	if !ok {
+		return
+	}
+
+	err := h.Service.Restore(r.Context(), id)
+	if err != nil {
+		er.Write(w, r, err)
+		return
+	}
+
+	httpx.Status(w, http.StatusOK, "restored")
+}
+
+// DELETE /trash/places
+//
+// Permanently removes places that have been in the trash for longer than
+// the retention period.
+func (h *Handler) Purge(w http.ResponseWriter, r *http.Request) {
+	purged, err := h.Service.Purge(r.Context(), time.Now().Add(-h.TrashRetention))
+	if err != nil {
+		er.Write(w, r, err)
+		return
+	}
+
+	httpx.JSON(w, http.StatusOK, map[string]int{"purged": purged})
+}
+
+// GET /places/{id}/history
+func (h *Handler) History(w http.ResponseWriter, r *http.Request) {
+	id, ok := httpx.PathUUID(w, r, "id")
+	if !ok {
+		return
+	}
+
+	limit, offset, ok := httpx.Pagination(w, r)
+	if !ok {
+		return
+	}
+
+	page, err := h.Service.History(r.Context(), id, limit, offset)
+	if err != nil {
+		er.Write(w, r, err)
+		return
+	}
+
+	httpx.JSON(w, http.StatusOK, page)
+}
+
+// POST /places/{id}/history/{entry_id}/revert
+func (h *Handler) Revert(w http.ResponseWriter, r *http.Request) {
+	id, ok := httpx.PathUUID(w, r, "id")
+	if !ok {
+		return
+	}
+
+	entryID, ok := httpx.PathUUID(w, r, "entry_id")
+	if !ok {
+		return
+	}
+
+	err := h.Service.Revert(r.Context(), id, entryID)
+	if err != nil {
+		er.Write(w, r, err)
+		return
+	}
+
+	httpx.Status(w, http.StatusOK, "reverted")
+}
+
+// POST /places/{id}/reviews
+func (h *Handler) SaveReview(w http.ResponseWriter, r *http.Request) {
+	placeID, ok := httpx.PathUUID(w, r, "id")
+	if !ok {
+		return
+	}
+
+	var req models.ReviewRequest
+	if !httpx.Decode(w, r, &req) {
+		return
+	}
+
+	review, created, err := h.Service.SaveReview(r.Context(), placeID, &req)
+	if err != nil {
+		er.Write(w, r, err)
+		return
+	}
+
+	if created {
+		httpx.JSON(w, http.StatusCreated, review)
+		return
+	}
+	httpx.JSON(w, http.StatusOK, review)
+}
+
+// GET /places/{id}/reviews
+func (h *Handler) ListReviews(w http.ResponseWriter, r *http.Request) {
+	placeID, ok := httpx.PathUUID(w, r, "id")
+	if !ok {
+		return
+	}
+
+	limit, offset, ok := httpx.Pagination(w, r)
+	if !ok {
+		return
+	}
+
+	page, err := h.Service.ListReviews(r.Context(), placeID, limit, offset)
+	if err != nil {
+		er.Write(w, r, err)
+		return
+	}
+
+	httpx.JSON(w, http.StatusOK, page)
+}
+
+// PUT /places/{id}/reviews/{review_id}/visibility
+func (h *Handler) SetReviewVisibility(w http.ResponseWriter, r *http.Request) {
+	placeID, ok := httpx.PathUUID(w, r, "id")
+	if !ok {
+		return
+	}
+	reviewID, ok := httpx.PathUUID(w, r, "review_id")
+	if !ok {
+		return
+	}
+
+	var req models.ReviewVisibilityRequest
+	if !httpx.Decode(w, r, &req) {
+		return
+	}
+
+	if err := h.Service.SetReviewHidden(r.Context(), placeID, reviewID, *req.Hidden); err != nil {
+		er.Write(w, r, err)
+		return
+	}
+
+	httpx.JSON(w, http.StatusOK, map[string]interface{}{"hidden": *req.Hidden})
+}
+
+// DELETE /places/{id}/reviews/{review_id}
+func (h *Handler) DeleteReview(w http.ResponseWriter, r *http.Request) {
+	placeID, ok := httpx.PathUUID(w, r, "id")
+	if !ok {
+		return
+	}
+	reviewID, ok := httpx.PathUUID(w, r, "review_id")
+	if !ok {
+		return
+	}
+
+	if err := h.Service.DeleteReview(r.Context(), placeID, reviewID); err != nil {
+		er.Write(w, r, err)
+		return
+	}
+
+	httpx.Status(w, http.StatusOK, "review deleted")
+}
+
+// GET /tags
+func (h *Handler) ListTags(w http.ResponseWriter, r *http.Request) {
+	tags, err := h.Service.ListTags(r.Context())
+	if err != nil {
+		er.Write(w, r, err)
+		return
+	}
+
+	httpx.JSON(w, http.StatusOK, tags)
+}
+
+// POST /tags
+func (h *Handler) CreateTag(w http.ResponseWriter, r *http.Request) {
+	var req models.TagRequest
+	if !httpx.Decode(w, r, &req) {
+		return
+	}
+
+	tag, err := h.Service.CreateTag(r.Context(), &req)
+	if err != nil {
+		er.Write(w, r, err)
+		return
+	}
+
+	httpx.JSON(w, http.StatusCreated, tag)
+}
+
+// PATCH /tags/{id}
+func (h *Handler) UpdateTag(w http.ResponseWriter, r *http.Request) {
+	id, ok := httpx.PathUUID(w, r, "id")
+	if !ok {
+		return
+	}
+
+	var req models.TagRequest
+	if !httpx.Decode(w, r, &req) {
+		return
+	}
+
+	tag, err := h.Service.UpdateTag(r.Context(), id, &req)
+	if err != nil {
+		er.Write(w, r, err)
+		return
+	}
+
+	httpx.JSON(w, http.StatusOK, tag)
+}
+
+// DELETE /tags/{id}
+func (h *Handler) DeleteTag(w http.ResponseWriter, r *http.Request) {
+	id, ok := httpx.PathUUID(w, r, "id")
+	if !ok {
+		return
+	}
+
+	if err := h.Service.DeleteTag(r.Context(), id); err != nil {
+		er.Write(w, r, err)
+		return
+	}
+
+	httpx.Status(w, http.StatusOK, "tag deleted")
+}
+
+const defaultMaxPhotoBytes = 10 << 20
+
+// photoCacheControl lets clients and proxies keep photos forever: a photo's
+// bytes never change once uploaded.
+const photoCacheControl = "public, max-age=31536000, immutable"
+
+// POST /places/{id}/photos
+//
+// Expects multipart/form-data with the image in a "photo" file field and an
+// optional "strip_gps" boolean field.
+func (h *Handler) UploadPhoto(w http.ResponseWriter, r *http.Request) {
+	placeID, ok := httpx.PathUUID(w, r, "id")
+	if !ok {
+		return
+	}
+
+	maxBytes := h.MaxPhotoBytes
+	if maxBytes <= 0 {
+		maxBytes = defaultMaxPhotoBytes
+	}
+
+	// Leave room for the multipart framing and the other form fields.
+	r.Body = http.MaxBytesReader(w, r.Body, maxBytes+1<<20)
+	if err := r.ParseMultipartForm(1 << 20); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			er.Write(w, r, er.ErrPhotoTooLarge)
+			return
+		}
+		er.Write(w, r, er.WithDetail(er.ErrInvalidPhotoData, "Expected a multipart/form-data body"))
+		return
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	stripGPS := false
+	if v := r.FormValue("strip_gps"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			er.Write(w, r, er.WithDetail(er.ErrInvalidPhotoData, "strip_gps must be true or false"))
+			return
+		}
+		stripGPS = b
+	}
+
+	file, _, err := r.FormFile("photo")
+	if err != nil {
+		er.Write(w, r, er.WithDetail(er.ErrInvalidPhotoData, "photo file missing"))
+		return
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, maxBytes+1))
+	if err != nil {
+		er.Write(w, r, er.WithDetail(er.ErrInvalidPhotoData, "Failed to read photo"))
+		return
+	}
+	if int64(len(data)) > maxBytes {
+		er.Write(w, r, er.ErrPhotoTooLarge)
+		return
+	}
+
+	photo, err := h.Service.UploadPhoto(r.Context(), placeID, data, stripGPS)
+	if err != nil {
+		er.Write(w, r, err)
+		return
+	}
+
+	httpx.JSON(w, http.StatusCreated, photo)
+}
+
+// GET /places/{id}/photos
+func (h *Handler) ListPhotos(w http.ResponseWriter, r *http.Request) {
+	placeID, ok := httpx.PathUUID(w, r, "id")
+	if !ok {
+		return
+	}
+
+	limit, offset, ok := httpx.Pagination(w, r)
+	if !ok {
+		return
+	}
+
+	page, err := h.Service.ListPhotos(r.Context(), placeID, limit, offset)
+	if err != nil {
+		er.Write(w, r, err)
+		return
+	}
+
+	httpx.JSON(w, http.StatusOK, page)
+}
+
+// GET /places/{id}/photos/{photo_id}
+func (h *Handler) GetPhoto(w http.ResponseWriter, r *http.Request) {
+	h.servePhoto(w, r, false)
+}
+
+// GET /places/{id}/photos/{photo_id}/thumbnail
+func (h *Handler) GetPhotoThumbnail(w http.ResponseWriter, r *http.Request) {
+	h.servePhoto(w, r, true)
+}
+
+func (h *Handler) servePhoto(w http.ResponseWriter, r *http.Request, thumbnail bool) {
+	placeID, ok := httpx.PathUUID(w, r, "id")
+	if !ok {
+		return
+	}
+	photoID, ok := httpx.PathUUID(w, r, "photo_id")
+	if !ok {
+		return
+	}
+
+	photo, rc, err := h.Service.OpenPhoto(r.Context(), placeID, photoID, thumbnail)
+	if err != nil {
+		er.Write(w, r, err)
+		return
+	}
+	defer rc.Close()
+
+	etag, contentType := `"`+photo.Checksum+`"`, photo.ContentType
+	if thumbnail {
+		etag, contentType = `"`+photo.Checksum+`-thumb"`, photo.ThumbnailType
+	}
+
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", photoCacheControl)
+	w.Header().Set("Last-Modified", photo.CreatedAt.UTC().Format(http.TimeFormat))
+
+	if match := r.Header.Get("If-None-Match"); match != "" {
+		if httpx.ETagMatches(match, etag) {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+	} else if since, err := http.ParseTime(r.Header.Get("If-Modified-Since")); err == nil && !photo.CreatedAt.Truncate(time.Second).After(since) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	if !thumbnail {
+		w.Header().Set("Content-Length", strconv.FormatInt(photo.Size, 10))
+	}
+	w.WriteHeader(http.StatusOK)
+	if r.Method != http.MethodHead {
+		io.Copy(w, rc)
+	}
+}
+
+// DELETE /places/{id}/photos/{photo_id}
+func (h *Handler) DeletePhoto(w http.ResponseWriter, r *http.Request) {
+	placeID, ok := httpx.PathUUID(w, r, "id")
+	if !ok {
+		return
+	}
+	photoID, ok := httpx.PathUUID(w, r, "photo_id")
+	if !ok {
+		return
+	}
+
+	if err := h.Service.DeletePhoto(r.Context(), placeID, photoID); err != nil {
+		er.Write(w, r, err)
+		return
+	}
+
+	httpx.Status(w, http.StatusOK, "photo deleted")
+}
@@ -0,0 +1,120 @@
+package places
+
+import (
+    "context"
+    "encoding/json"
+    "sort"
+
+    "deu/internal/audit"
+    er "deu/internal/errors"
+    "deu/internal/models"
+)
+
+// placeState is the part of a place that is audited, keyed by its JSON
+// field names. Tags are kept as sorted slugs.
+func placeState(p *models.Place) map[string]any {
+    tags := make([]string, 0, len(p.Tags))
+    for _, t := range p.Tags {
+        tags = append(tags, t.Slug)
+    }
+    sort.Strings(tags)
+
+    return map[string]any{
+        "name":          p.Name,
+        "description":   p.Description,
+        "location":      p.Location,
+        "address":       p.Address,
+        "tags":          tags,
+        "averageRating": p.AverageRating,
+        "reviewCount":   p.ReviewCount,
+    }
+}
+
+// record adds a change to a place's history. after is the place's state
+// once the change is done, or its last state when it was deleted.
+func (s *PlaceService) record(ctx context.Context, action, id string, before, after *models.Place) {
+    change := audit.Change{EntityType: models.AuditEntityPlace, EntityID: id, Action: action}
+    if before != nil {
+        change.Before = placeState(before)
+    }
+    if after != nil {
+        change.After = placeState(after)
+    }
+    s.audit.Record(ctx, change)
+}
+
+// recordUpdate records how a place changed. before and after must be read
+// in the transaction that made the change, so that the entry holds that
+// change alone.
+func (s *PlaceService) recordUpdate(ctx context.Context, action string, before, after *models.Place, revertOf *string) {
+    s.audit.Record(ctx, audit.Change{
+        EntityType: models.AuditEntityPlace,
+        EntityID:   before.Id,
+        Action:     action,
+        Before:     placeState(before),
+        After:      placeState(after),
+        RevertOf:   revertOf,
+    })
+}
+
+// History returns a page of a place's changes, newest first. It is kept
+// for places in the trash and after they are purged.
+func (s *PlaceService) History(ctx context.Context, placeID string, limit, offset int) (*models.Page[models.AuditEntry], error) {
+    if placeID == "" {
+        return nil, er.ErrInvalidPlaceData
+    }
+    return s.audit.History(ctx, models.AuditEntityPlace, placeID, limit, offset)
+}
+
+// Revert sets a place's name, description, location, address and tags back
+// to how they were after the given history entry. The rating is derived
+// from reviews and is left alone, as are tags that have since been deleted.
+func (s *PlaceService) Revert(ctx context.Context, placeID, entryID string) error {
+    if placeID == "" || entryID == "" {
+        return er.ErrInvalidPlaceData
+    }
+
+    entry, err := s.audit.Entry(ctx, models.AuditEntityPlace, placeID, entryID)
+    if err != nil {
+        return err
+    }
+    if entry.Snapshot == nil {
+        return er.ErrNothingToRevert
+    }
+
+    var version struct {
+        Name        string          `json:"name"`
+        Description string          `json:"description"`
+        Location    models.Location `json:"location"`
+        Address     string          `json:"address"`
+        Tags        []string        `json:"tags"`
+    }
+    raw, err := json.Marshal(entry.Snapshot)
+    if err != nil {
+        return err
+    }
+    if err := json.Unmarshal(raw, &version); err != nil {
+        return err
+    }
+
+    tags := []string{}
+    if len(version.Tags) > 0 {
+        existing, err := s.tagRepo.GetBySlugs(ctx, version.Tags)
+        if err != nil {
+            return err
+        }
+        for _, t := range existing {
+            tags = append(tags, t.Slug)
+        }
+    }
+
+    req := &models.PlaceUpdateRequest{
+        Name:        &version.Name,
+        Description: &version.Description,
+        Location:    &version.Location,
+        Address:     &version.Address,
+        Tags:        &tags,
+    }
+    _, err = s.update(ctx, placeID, req, 0, models.AuditRevert, &entry.Id)
+    return err
+}
@@ -0,0 +1,258 @@
+package places
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	_ "image/gif"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+
+	"golang.org/x/image/draw"
+
+	er "deu/internal/errors"
+)
+
+const (
+	// maxPhotoPixels guards against decompression bombs: a small file can
+	// declare enormous dimensions.
+	maxPhotoPixels = 50_000_000
+
+	// thumbnailSize is the length of a thumbnail's longer edge.
+	thumbnailSize = 320
+)
+
+// photoTypes are the accepted upload types, as sniffed from the content.
+var photoTypes = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/gif":  true,
+}
+
+// processedPhoto is an upload ready to be stored.
+type processedPhoto struct {
+	data          []byte
+	contentType   string
+	width, height int
+	thumbnail     []byte
+	thumbnailType string
+}
+
+// processPhoto checks that data is an image of an accepted type, removes
+// its GPS metadata if asked to and renders its thumbnail. The original
+// pixels are never re-encoded.
+func processPhoto(data []byte, stripGPS bool) (*processedPhoto, error) {
+	contentType := http.DetectContentType(data)
+	if !photoTypes[contentType] {
+		return nil, er.ErrUnsupportedPhotoType
+	}
+
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil || cfg.Width <= 0 || cfg.Height <= 0 {
+		return nil, er.ErrInvalidPhotoData
+	}
+	if cfg.Width*cfg.Height > maxPhotoPixels {
+		return nil, er.ErrPhotoTooLarge
+	}
+
+	if stripGPS {
+		switch contentType {
+		case "image/jpeg":
+			data = stripJPEGLocation(data)
+		case "image/png":
+			data = stripPNGLocation(data)
+		}
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, er.ErrInvalidPhotoData
+	}
+
+	thumb, thumbType, err := renderThumbnail(img, contentType)
+	if err != nil {
+		return nil, err
+	}
+
+	return &processedPhoto{
+		data:          data,
+		contentType:   contentType,
+		width:         cfg.Width,
+		height:        cfg.Height,
+		thumbnail:     thumb,
+		thumbnailType: thumbType,
+	}, nil
+}
+
+// renderThumbnail scales img so its longer edge is at most thumbnailSize.
+// PNG sources keep PNG for their transparency; everything else becomes JPEG.
+func renderThumbnail(img image.Image, contentType string) ([]byte, string, error) {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w > thumbnailSize || h > thumbnailSize {
+		if w >= h {
+			w, h = thumbnailSize, max(1, h*thumbnailSize/w)
+		} else {
+			w, h = max(1, w*thumbnailSize/h), thumbnailSize
+		}
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, b, draw.Src, nil)
+
+	var buf bytes.Buffer
+	if contentType == "image/png" {
+		if err := png.Encode(&buf, dst); err != nil {
+			return nil, "", err
+		}
+		return buf.Bytes(), "image/png", nil
+	}
+	if err := jpeg.Encode(&buf, dst, &jpeg.Options{Quality: 80}); err != nil {
+		return nil, "", err
+	}
+	return buf.Bytes(), "image/jpeg", nil
+}
+
+var (
+	exifHeader = []byte("Exif\x00\x00")
+	xmpHeader  = []byte("http://ns.adobe.com/xap/1.0/\x00")
+)
+
+// stripJPEGLocation removes location data from a JPEG without touching the
+// image data. The GPS directory of the EXIF block is blanked so the rest of
+// the EXIF data, such as orientation, survives; an EXIF block that cannot be
+// parsed is dropped whole. XMP packets, which may repeat the location, are
+// always dropped.
+func stripJPEGLocation(data []byte) []byte {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return data
+	}
+
+	out := make([]byte, 0, len(data))
+	out = append(out, data[:2]...)
+	i := 2
+	for i+4 <= len(data) && data[i] == 0xFF {
+		marker := data[i+1]
+		// Start of scan: the rest of the file is image data.
+		if marker == 0xDA || marker == 0xD9 {
+			break
+		}
+		length := int(binary.BigEndian.Uint16(data[i+2:]))
+		end := i + 2 + length
+		if length < 2 || end > len(data) {
+			break
+		}
+
+		segment := data[i:end]
+		payload := segment[4:]
+		if marker == 0xE1 {
+			switch {
+			case bytes.HasPrefix(payload, xmpHeader):
+				i = end
+				continue
+			case bytes.HasPrefix(payload, exifHeader):
+				segment = append([]byte(nil), segment...)
+				if !blankGPS(segment[4+len(exifHeader):]) {
+					i = end
+					continue
+				}
+			}
+		}
+		out = append(out, segment...)
+		i = end
+	}
+	return append(out, data[i:]...)
+}
+
+// exifTypeSizes is the byte size of each TIFF field type.
+var exifTypeSizes = map[uint16]uint64{
+	1: 1, 2: 1, 3: 2, 4: 4, 5: 8, 6: 1, 7: 1, 8: 2, 9: 4, 10: 8, 11: 4, 12: 8,
+}
+
+// blankGPS zeroes the GPS directory of a TIFF-structured EXIF block in
+// place and reports whether the block was well-formed enough to do so.
+func blankGPS(tiff []byte) bool {
+	if len(tiff) < 8 {
+		return false
+	}
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return false
+	}
+
+	entries := func(offset uint32) (int, bool) {
+		if uint64(offset)+2 > uint64(len(tiff)) {
+			return 0, false
+		}
+		n := int(order.Uint16(tiff[offset:]))
+		return n, uint64(offset)+2+uint64(n)*12+4 <= uint64(len(tiff))
+	}
+
+	ifd0 := order.Uint32(tiff[4:])
+	n, ok := entries(ifd0)
+	if !ok {
+		return false
+	}
+
+	for k := 0; k < n; k++ {
+		entry := tiff[ifd0+2+uint32(k)*12:]
+		if order.Uint16(entry) != 0x8825 {
+			continue
+		}
+
+		gps := order.Uint32(entry[8:])
+		m, ok := entries(gps)
+		if !ok {
+			return false
+		}
+		for j := 0; j < m; j++ {
+			field := tiff[gps+2+uint32(j)*12:][:12]
+			size := exifTypeSizes[order.Uint16(field[2:])] * uint64(order.Uint32(field[4:]))
+			if size > 4 {
+				offset := uint64(order.Uint32(field[8:]))
+				if offset+size > uint64(len(tiff)) {
+					return false
+				}
+				clear(tiff[offset : offset+size])
+			}
+			clear(field)
+		}
+		order.PutUint16(tiff[gps:], 0)
+	}
+	return true
+}
+
+var pngSignature = []byte("\x89PNG\r\n\x1a\n")
+
+// stripPNGLocation drops the chunks of a PNG that can carry a location: the
+// eXIf chunk and XMP packets stored as iTXt.
+func stripPNGLocation(data []byte) []byte {
+	if !bytes.HasPrefix(data, pngSignature) {
+		return data
+	}
+
+	out := append([]byte(nil), pngSignature...)
+	i := len(pngSignature)
+	for i+8 <= len(data) {
+		length := uint64(binary.BigEndian.Uint32(data[i:]))
+		end := uint64(i) + 12 + length
+		if end > uint64(len(data)) {
+			break
+		}
+
+		chunk := data[i:end]
+		kind := string(chunk[4:8])
+		drop := kind == "eXIf" || (kind == "iTXt" && bytes.HasPrefix(chunk[8:], []byte("XML:com.adobe.xmp\x00")))
+		if !drop {
+			out = append(out, chunk...)
+		}
+		i = int(end)
+	}
+	return append(out, data[i:]...)
+}
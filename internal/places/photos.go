@@ -0,0 +1,172 @@
+package places
+
+import (
+    "bytes"
+    "context"
+    "crypto/sha256"
+    "encoding/hex"
+    "errors"
+    "io"
+    "log/slog"
+    "time"
+
+    "github.com/google/uuid"
+
+    "deu/internal/auth"
+    er "deu/internal/errors"
+    "deu/internal/models"
+    "deu/pkg/blob"
+)
+
+// photoKey is where a photo's bytes live in the blob store. Every blob of a
+// place shares the "places/{id}/" prefix so they can be removed together.
+func photoKey(placeID, photoID string, thumbnail bool) string {
+    key := "places/" + placeID + "/" + photoID
+    if thumbnail {
+        key += "_thumb"
+    }
+    return key
+}
+
+func withURLs(p *models.Photo) {
+    p.URL = "/places/" + p.PlaceID + "/photos/" + p.Id
+    p.ThumbnailURL = p.URL + "/thumbnail"
+}
+
+// UploadPhoto stores a new photo of a place together with its thumbnail.
+// With stripGPS set, location metadata is removed before anything is stored.
+func (s *PlaceService) UploadPhoto(ctx context.Context, placeID string, data []byte, stripGPS bool) (*models.Photo, error) {
+    callerID, ok := auth.UserIDFromContext(ctx)
+    if !ok {
+        return nil, er.ErrUnauthorized
+    }
+
+    if _, err := s.GetById(ctx, placeID); err != nil {
+        return nil, err
+    }
+
+    processed, err := processPhoto(data, stripGPS)
+    if err != nil {
+        return nil, err
+    }
+
+    sum := sha256.Sum256(processed.data)
+    photo := models.Photo{
+        Id:            uuid.New().String(),
+        PlaceID:       placeID,
+        UploadedBy:    &callerID,
+        ContentType:   processed.contentType,
+        ThumbnailType: processed.thumbnailType,
+        Size:          int64(len(processed.data)),
+        Width:         processed.width,
+        Height:        processed.height,
+        Checksum:      hex.EncodeToString(sum[:]),
+        CreatedAt:     time.Now().UTC(),
+    }
+
+    key, thumbKey := photoKey(placeID, photo.Id, false), photoKey(placeID, photo.Id, true)
+    if err := s.blobs.Put(ctx, key, bytes.NewReader(processed.data)); err != nil {
+        return nil, err
+    }
+    if err := s.blobs.Put(ctx, thumbKey, bytes.NewReader(processed.thumbnail)); err != nil {
+        s.blobs.Delete(ctx, key)
+        return nil, err
+    }
+    if err := s.photoRepo.Create(ctx, &photo); err != nil {
+        s.blobs.Delete(ctx, key)
+        s.blobs.Delete(ctx, thumbKey)
+        return nil, err
+    }
+
+    withURLs(&photo)
+    return &photo, nil
+}
+
+func (s *PlaceService) ListPhotos(ctx context.Context, placeID string, limit, offset int) (*models.Page[models.Photo], error) {
+    if _, err := s.GetById(ctx, placeID); err != nil {
+        return nil, err
+    }
+
+    photos, total, err := s.photoRepo.ListByPlace(ctx, placeID, limit, offset)
+    if err != nil {
+        return nil, err
+    }
+    for i := range photos {
+        withURLs(&photos[i])
+    }
+
+    return &models.Page[models.Photo]{Items: photos, Total: &total, Limit: limit, Offset: offset}, nil
+}
+
+// getPhoto loads a photo and checks that it belongs to the place, which
+// must not be in the trash.
+func (s *PlaceService) getPhoto(ctx context.Context, placeID, photoID string) (*models.Photo, error) {
+    if _, err := s.GetById(ctx, placeID); err != nil {
+        return nil, err
+    }
+
+    photo, err := s.photoRepo.GetByID(ctx, photoID)
+    if err != nil {
+        return nil, err
+    }
+    if photo.PlaceID != placeID {
+        return nil, er.ErrPhotoNotFound
+    }
+    return photo, nil
+}
+
+// OpenPhoto returns a photo's metadata and a reader over its bytes, or over
+// its thumbnail's. The caller must close the reader.
+func (s *PlaceService) OpenPhoto(ctx context.Context, placeID, photoID string, thumbnail bool) (*models.Photo, io.ReadCloser, error) {
+    photo, err := s.getPhoto(ctx, placeID, photoID)
+    if err != nil {
+        return nil, nil, err
+    }
+
+    rc, err := s.blobs.Get(ctx, photoKey(placeID, photoID, thumbnail))
+    if errors.Is(err, blob.ErrNotFound) {
+        return nil, nil, er.ErrPhotoNotFound
+    }
+    if err != nil {
+        return nil, nil, err
+    }
+    return photo, rc, nil
+}
+
+// DeletePhoto lets the uploader or a moderator remove a photo.
+func (s *PlaceService) DeletePhoto(ctx context.Context, placeID, photoID string) error {
+    caller, ok := auth.PrincipalFromContext(ctx)
+    if !ok {
+        return er.ErrUnauthorized
+    }
+
+    photo, err := s.getPhoto(ctx, placeID, photoID)
+    if err != nil {
+        return err
+    }
+    uploader := photo.UploadedBy != nil && *photo.UploadedBy == caller.UserID
+    if !uploader && !caller.HasRole(moderatorRoles...) {
+        return er.ErrForbidden
+    }
+
+    if err := s.photoRepo.Delete(ctx, photoID); err != nil {
+        return err
+    }
+    for _, thumbnail := range []bool{false, true} {
+        if err := s.blobs.Delete(ctx, photoKey(placeID, photoID, thumbnail)); err != nil {
+            slog.Warn("Failed to delete photo blob", "photo_id", photoID, "error", err)
+        }
+    }
+    return nil
+}
+
+// removePhotos cleans up after a purged place. The place is already gone,
+// so failures are logged rather than returned.
+func (s *PlaceService) removePhotos(ctx context.Context, placeID string) {
+    if err := s.photoRepo.DeleteByPlace(ctx, placeID); err != nil {
+        slog.Warn("Failed to delete photos of place", "place_id", placeID, "error", err)
+    }
+    if err := s.blobs.DeletePrefix(ctx, "places/"+placeID+"/"); err != nil {
+        slog.Warn("Failed to delete photo blobs of place", "place_id", placeID, "error", err)
+    }
+}
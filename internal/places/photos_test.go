@@ -0,0 +1,196 @@
+package places
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"testing"
+
+	"deu/internal/audit"
+	"deu/internal/auth"
+	"deu/internal/models"
+	"deu/internal/repository"
+	"deu/pkg/blob"
+	"deu/pkg/cache"
+)
+
+// gpsLatitude is the latitude written into the test photos, 48° 51' 24.12"
+// as three rationals. Finding its bytes in a stored file means the location
+// leaked.
+var gpsLatitude = []byte{
+	48, 0, 0, 0, 1, 0, 0, 0,
+	51, 0, 0, 0, 1, 0, 0, 0,
+	0xEC, 0x09, 0, 0, 100, 0, 0, 0,
+}
+
+// xmpLocation is an XMP packet repeating the location.
+var xmpLocation = []byte(`<x:xmpmeta xmlns:x="adobe:ns:meta/"><exif:GPSLatitude>48,51.402N</exif:GPSLatitude></x:xmpmeta>`)
+
+// exifWithGPS returns a little-endian TIFF block as found in EXIF data: IFD0
+// holds an orientation and a pointer to a GPS directory with a latitude.
+func exifWithGPS() []byte {
+	le := binary.LittleEndian
+	tiff := []byte("II\x2a\x00\x08\x00\x00\x00")
+
+	// IFD0 at 8: two entries and no next IFD.
+	ifd0 := make([]byte, 2+2*12+4)
+	le.PutUint16(ifd0, 2)
+	le.PutUint16(ifd0[2:], 0x0112) // Orientation
+	le.PutUint16(ifd0[4:], 3)      // SHORT
+	le.PutUint32(ifd0[6:], 1)
+	le.PutUint16(ifd0[10:], 6)
+	le.PutUint16(ifd0[14:], 0x8825) // GPS IFD pointer
+	le.PutUint16(ifd0[16:], 4)      // LONG
+	le.PutUint32(ifd0[18:], 1)
+	le.PutUint32(ifd0[22:], uint32(len(tiff)+len(ifd0)))
+	tiff = append(tiff, ifd0...)
+
+	// The GPS IFD: the latitude reference inline, the latitude out of line.
+	gps := make([]byte, 2+2*12+4)
+	le.PutUint16(gps, 2)
+	le.PutUint16(gps[2:], 0x0001) // GPSLatitudeRef
+	le.PutUint16(gps[4:], 2)      // ASCII
+	le.PutUint32(gps[6:], 2)
+	copy(gps[10:], "N\x00")
+	le.PutUint16(gps[14:], 0x0002) // GPSLatitude
+	le.PutUint16(gps[16:], 5)      // RATIONAL
+	le.PutUint32(gps[18:], 3)
+	le.PutUint32(gps[22:], uint32(len(tiff)+len(gps)))
+	tiff = append(tiff, gps...)
+	return append(tiff, gpsLatitude...)
+}
+
+func testImage() image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, 8, 6))
+	for x := 0; x < 8; x++ {
+		for y := 0; y < 6; y++ {
+			img.Set(x, y, color.RGBA{uint8(x * 30), uint8(y * 40), 128, 255})
+		}
+	}
+	return img
+}
+
+// jpegWithLocation returns a JPEG carrying the location in both its EXIF
+// and its XMP segment.
+func jpegWithLocation(t *testing.T) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, testImage(), nil); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+
+	segment := func(payload []byte) []byte {
+		s := []byte{0xFF, 0xE1, 0, 0}
+		binary.BigEndian.PutUint16(s[2:], uint16(len(payload)+2))
+		return append(s, payload...)
+	}
+	out := append([]byte(nil), data[:2]...)
+	out = append(out, segment(append([]byte("Exif\x00\x00"), exifWithGPS()...))...)
+	out = append(out, segment(append([]byte("http://ns.adobe.com/xap/1.0/\x00"), xmpLocation...))...)
+	return append(out, data[2:]...)
+}
+
+// pngWithLocation returns a PNG carrying the location in an eXIf chunk and
+// in an XMP iTXt chunk.
+func pngWithLocation(t *testing.T) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, testImage()); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+
+	chunk := func(kind string, payload []byte) []byte {
+		c := make([]byte, 4, 12+len(payload))
+		binary.BigEndian.PutUint32(c, uint32(len(payload)))
+		c = append(c, kind...)
+		c = append(c, payload...)
+		return binary.BigEndian.AppendUint32(c, crc32.ChecksumIEEE(c[4:]))
+	}
+	// The signature is 8 bytes and IHDR 25; the extra chunks follow IHDR.
+	out := append([]byte(nil), data[:33]...)
+	out = append(out, chunk("eXIf", exifWithGPS())...)
+	out = append(out, chunk("iTXt", append([]byte("XML:com.adobe.xmp\x00\x00\x00\x00\x00"), xmpLocation...))...)
+	return append(out, data[33:]...)
+}
+
+func newPhotoService(t *testing.T) *PlaceService {
+	t.Helper()
+	blobs, err := blob.NewLocalStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := repository.NewMemoryBackend()
+	if err := b.Places.Create(context.Background(), &models.Place{Id: "p1", Name: "Louvre Museum"}); err != nil {
+		t.Fatal(err)
+	}
+	return NewPlaceService(b.Places, b.UserPlaces, b.Reviews, b.Tags, b.Photos, b.UnitOfWork, blobs, audit.NewTrail(b.Audit), cache.Nop[*models.Place]{}, cache.NewLocalBus())
+}
+
+func readPhoto(t *testing.T, s *PlaceService, ctx context.Context, photoID string, thumbnail bool) []byte {
+	t.Helper()
+	_, rc, err := s.OpenPhoto(ctx, "p1", photoID, thumbnail)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rc.Close()
+	data, err := io.ReadAll(rc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestUploadPhotoStripsLocation(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     func(t *testing.T) []byte
+		stripGPS bool
+		// kept are byte strings that must survive the stripping.
+		kept [][]byte
+	}{
+		{"jpeg", jpegWithLocation, true, [][]byte{[]byte("Exif\x00\x00")}},
+		{"png", pngWithLocation, true, nil},
+		{"jpeg without stripping", jpegWithLocation, false, [][]byte{gpsLatitude, xmpLocation}},
+		{"png without stripping", pngWithLocation, false, [][]byte{gpsLatitude, xmpLocation}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newPhotoService(t)
+			ctx := auth.WithPrincipal(context.Background(), auth.Principal{UserID: "00000000-0000-0000-0000-000000000001", Role: models.RoleMember})
+
+			photo, err := s.UploadPhoto(ctx, "p1", tt.data(t), tt.stripGPS)
+			if err != nil {
+				t.Fatal(err)
+			}
+			stored := readPhoto(t, s, ctx, photo.Id, false)
+			thumbnail := readPhoto(t, s, ctx, photo.Id, true)
+
+			if _, _, err := image.Decode(bytes.NewReader(stored)); err != nil {
+				t.Errorf("the stored photo no longer decodes: %v", err)
+			}
+			for _, kept := range tt.kept {
+				if !bytes.Contains(stored, kept) {
+					t.Errorf("the stored photo lost %q", kept)
+				}
+			}
+			if !tt.stripGPS {
+				return
+			}
+			for what, data := range map[string][]byte{"stored photo": stored, "thumbnail": thumbnail} {
+				for _, leak := range [][]byte{gpsLatitude, xmpLocation, []byte("eXIf")} {
+					if bytes.Contains(data, leak) {
+						t.Errorf("the %s still contains %q", what, leak)
+					}
+				}
+			}
+		})
+	}
+}
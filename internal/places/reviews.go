@@ -0,0 +1,177 @@
+package places
+
+import (
+    "context"
+    "time"
+
+    "github.com/google/uuid"
+
+    "deu/internal/auth"
+    er "deu/internal/errors"
+    "deu/internal/models"
+    repo "deu/internal/repository"
+    "deu/pkg/cache"
+)
+
+// moderatorRoles may hide reviews and see hidden ones in listings.
+var moderatorRoles = []string{models.RoleAdmin, models.RoleEditor}
+
+// evict drops a place from the caches of all instances after it or its
+// review aggregates changed.
+func (s *PlaceService) evict(ctx context.Context, id string) {
+    s.invalidate(ctx, cache.Invalidation{Key: id})
+}
+
+// SaveReview creates the caller's review of a place or edits it if one
+// exists. The returned flag reports whether a new review was created; it
+// is read off the stored review, so of two first reviews racing, only the
+// one that was inserted reports it.
+func (s *PlaceService) SaveReview(ctx context.Context, placeID string, req *models.ReviewRequest) (*models.Review, bool, error) {
+    if placeID == "" {
+        return nil, false, er.ErrInvalidReviewData
+    }
+
+    callerID, ok := auth.UserIDFromContext(ctx)
+    if !ok {
+        return nil, false, er.ErrUnauthorized
+    }
+
+    id := uuid.New().String()
+    now := time.Now().UTC()
+    review := models.Review{
+        Id:        id,
+        PlaceID:   placeID,
+        UserID:    callerID,
+        Rating:    req.Rating,
+        Comment:   req.Comment,
+        CreatedAt: now,
+        UpdatedAt: now,
+    }
+
+    var before, after *models.Place
+    err := s.uow.WithTx(ctx, func(tx repo.Repos) error {
+        var err error
+        if before, err = tx.Places.GetByID(ctx, placeID); err != nil {
+            return err
+        }
+        if err := tx.Reviews.Save(ctx, &review); err != nil {
+            return err
+        }
+        after, err = tx.Places.GetByID(ctx, placeID)
+        return err
+    })
+    if err != nil {
+        return nil, false, err
+    }
+    s.evict(ctx, placeID)
+    s.recordUpdate(ctx, models.AuditUpdate, before, after, nil)
+
+    return &review, review.Id == id, nil
+}
+
+// ListReviews returns a page of a place's reviews, newest first. Hidden
+// reviews are only included for moderators.
+func (s *PlaceService) ListReviews(ctx context.Context, placeID string, limit, offset int) (*models.Page[models.Review], error) {
+    if placeID == "" {
+        return nil, er.ErrInvalidPlaceData
+    }
+
+    if _, err := s.GetById(ctx, placeID); err != nil {
+        return nil, err
+    }
+
+    caller, _ := auth.PrincipalFromContext(ctx)
+    includeHidden := caller.HasRole(moderatorRoles...)
+
+    reviews, total, err := s.reviewRepo.ListByPlace(ctx, placeID, includeHidden, limit, offset)
+    if err != nil {
+        return nil, err
+    }
+
+    return &models.Page[models.Review]{Items: reviews, Total: &total, Limit: limit, Offset: offset}, nil
+}
+
+// SetReviewHidden hides or restores a review. Only moderators may do this.
+func (s *PlaceService) SetReviewHidden(ctx context.Context, placeID, reviewID string, hidden bool) error {
+    if placeID == "" || reviewID == "" {
+        return er.ErrInvalidReviewData
+    }
+
+    caller, ok := auth.PrincipalFromContext(ctx)
+    if !ok {
+        return er.ErrUnauthorized
+    }
+    if !caller.HasRole(moderatorRoles...) {
+        return er.ErrForbidden
+    }
+
+    var before, after *models.Place
+    err := s.uow.WithTx(ctx, func(tx repo.Repos) error {
+        review, err := tx.Reviews.GetByID(ctx, reviewID)
+        if err != nil {
+            return err
+        }
+        if review.PlaceID != placeID {
+            return er.ErrReviewNotFound
+        }
+        if before, err = tx.Places.GetByID(ctx, placeID); err != nil {
+            return err
+        }
+
+        if err := tx.Reviews.SetHidden(ctx, reviewID, hidden, caller.UserID, time.Now().UTC()); err != nil {
+            return err
+        }
+        after, err = tx.Places.GetByID(ctx, placeID)
+        return err
+    })
+    if err != nil {
+        return err
+    }
+    s.evict(ctx, placeID)
+    s.recordUpdate(ctx, models.AuditUpdate, before, after, nil)
+
+    return nil
+}
+
+// DeleteReview removes a review. Authors may delete their own; admins may
+// delete any.
+func (s *PlaceService) DeleteReview(ctx context.Context, placeID, reviewID string) error {
+    if placeID == "" || reviewID == "" {
+        return er.ErrInvalidReviewData
+    }
+
+    caller, ok := auth.PrincipalFromContext(ctx)
+    if !ok {
+        return er.ErrUnauthorized
+    }
+
+    var before, after *models.Place
+    err := s.uow.WithTx(ctx, func(tx repo.Repos) error {
+        review, err := tx.Reviews.GetByID(ctx, reviewID)
+        if err != nil {
+            return err
+        }
+        if review.PlaceID != placeID {
+            return er.ErrReviewNotFound
+        }
+        if review.UserID != caller.UserID && !caller.IsAdmin() {
+            return er.ErrForbidden
+        }
+        if before, err = tx.Places.GetByID(ctx, placeID); err != nil {
+            return err
+        }
+
+        if err := tx.Reviews.Delete(ctx, reviewID); err != nil {
+            return err
+        }
+        after, err = tx.Places.GetByID(ctx, placeID)
+        return err
+    })
+    if err != nil {
+        return err
+    }
+    s.evict(ctx, placeID)
+    s.recordUpdate(ctx, models.AuditUpdate, before, after, nil)
+
+    return nil
+}
@@ -1,145 +1,336 @@
-package places
-
-import (
-    "time"
-    "context"
-    "sync"
-	"github.com/google/uuid"
-
-    er "deu/internal/errors"
-    repo "deu/internal/repository"
-	"deu/internal/models"
-)
-
-type PlaceService struct {
-    repo        repo.PlaceRepository
-    enableCache bool
-    cache       map[string]*models.Place
-    mu          sync.RWMutex
-}
-
-func NewPlaceService(repo repo.PlaceRepository, enableCache bool) *PlaceService {
-    return &PlaceService{
-        repo:        repo,
-        enableCache: enableCache,
-        cache:       make(map[string]*models.Place),
-    }
-}
-
-func (s *PlaceService) GetAll(ctx context.Context) ([]models.Place, error) {
-    return s.repo.GetAll(ctx)
-}
-
-func (s *PlaceService) GetById(ctx context.Context, id string) (*models.Place, error) {
-    if id == "" {
-        return nil, er.ErrInvalidPlaceData
-    }
-
-    if s.enableCache {
-        s.mu.RLock()
-        if place, found := s.cache[id]; found {
-            s.mu.RUnlock()
-            return place, nil
-        }
-        s.mu.RUnlock()
-    }
-
-    place, err := s.repo.GetByID(ctx, id)
-    if err != nil {
-        return nil, err
-    }
-
-    if s.enableCache {
-        s.mu.Lock()
-        s.cache[id] = place
-        s.mu.Unlock()
-    }
-
-    return place, nil
-}
-
-func (s *PlaceService) Create(ctx context.Context, p *models.PlaceCreateRequest) (*models.Place, error) {
-
-    if p.Name == "" {
-        return nil, er.ErrInvalidPlaceData
-    }
-
-	id := uuid.New()
-
-	place := models.Place{
-		Id: 			id.String(),
-		Name: 			p.Name,
-		Description:	p.Description,
-		Location: 		p.Location,
-		Address: 		p.Address,
-		Rating: 		p.Rating,
-		CreatedAt: 		time.Now(),
-	}
-
-	err := s.repo.Create(ctx, &place)
-	if err != nil {
-		return nil, err
-	}
-
-	if s.enableCache {
-		s.mu.Lock()
-		s.cache[place.Id] = &place
-		s.mu.Unlock()
-	}
-
-    return &place, nil
-}
-
-func (s *PlaceService) Update(ctx context.Context, id string, p *models.PlaceUpdateRequest) error {
-
-    if id == "" {
-        return er.ErrInvalidPlaceData
-    }
-
-    err := s.repo.Update(ctx, id, p)
-    if err != nil {
-        return err
-    }
-
-    if s.enableCache {
-        s.mu.Lock()
-        delete(s.cache, id)
-        s.mu.Unlock()
-    }
-
-    return nil
-}
-
-func (s *PlaceService) DeleteById(ctx context.Context, id string) error {
-    if id == "" {
-        return er.ErrInvalidPlaceData
-    }
-    
-    err := s.repo.Delete(ctx, id)
-    if err != nil {
-        return err
-    }
-
-    if s.enableCache {
-        s.mu.Lock()
-        delete(s.cache, id)
-        s.mu.Unlock()
-    }
-
-    return nil
-}
-
-func (s *PlaceService) DeleteAll(ctx context.Context) error {
-    err := s.repo.DeleteAll(ctx)
-    if err != nil {
-        return err
-    }
-    
-    if s.enableCache {
-        s.mu.Lock()
-        s.cache = make(map[string]*models.Place)
-        s.mu.Unlock()
-    }
-    
-    return nil
+package places
+
+import (
+    "time"
+    "context"
+    "log/slog"
+    "strings"
+	"github.com/google/uuid"
+
+    "deu/internal/audit"
+    "deu/internal/auth"
+    er "deu/internal/errors"
+    repo "deu/internal/repository"
+	"deu/internal/models"
+    "deu/pkg/blob"
+    "deu/pkg/cache"
+)
+
+// maxSearchLength bounds the search text so a query cannot expand into an
+// arbitrarily large tsquery.
+const maxSearchLength = 200
+
+type PlaceService struct {
+    repo        repo.PlaceRepository
+    userPlaceRepo repo.UserPlaceRepository
+    reviewRepo  repo.ReviewRepository
+    tagRepo     repo.TagRepository
+    photoRepo   repo.PhotoRepository
+    // uow runs the operations that read and write several rows in one
+    // transaction.
+    uow         repo.UnitOfWork
+    blobs       blob.Store
+    audit       *audit.Trail
+    // cache holds places by id for GetById. Pass cache.Nop to disable it.
+    cache       cache.Cache[*models.Place]
+    // invalidations tells the other instances which places changed.
+    invalidations cache.Bus
+}
+
+func NewPlaceService(repo repo.PlaceRepository, userPlaceRepo repo.UserPlaceRepository, reviewRepo repo.ReviewRepository, tagRepo repo.TagRepository, photoRepo repo.PhotoRepository, uow repo.UnitOfWork, blobs blob.Store, trail *audit.Trail, placeCache cache.Cache[*models.Place], invalidations cache.Bus) *PlaceService {
+    invalidations.Subscribe(cache.Evictor(placeCache))
+    return &PlaceService{
+        repo:        repo,
+        userPlaceRepo: userPlaceRepo,
+        reviewRepo:  reviewRepo,
+        tagRepo:     tagRepo,
+        photoRepo:   photoRepo,
+        uow:         uow,
+        blobs:       blobs,
+        audit:       trail,
+        cache:       placeCache,
+        invalidations: invalidations,
+    }
+}
+
+// invalidate drops cached places here and publishes the invalidation to the
+// other instances. The change is already made, so a failure to publish is
+// logged rather than returned; the other instances catch up within the
+// cache TTL.
+func (s *PlaceService) invalidate(ctx context.Context, inv cache.Invalidation) {
+    cache.Evictor(s.cache)(inv)
+    if err := s.invalidations.Publish(ctx, inv); err != nil {
+        slog.Warn("Failed to publish cache invalidation", "place_id", inv.Key, "all", inv.All, "error", err)
+    }
+}
+
+// authorizeOwner allows the caller to modify place if they created it or hold
+// one of the override roles.
+func authorizeOwner(ctx context.Context, place *models.Place, overrideRoles ...string) error {
+    caller, ok := auth.PrincipalFromContext(ctx)
+    if !ok {
+        return er.ErrUnauthorized
+    }
+    if caller.HasRole(overrideRoles...) {
+        return nil
+    }
+    if place.CreatedBy != nil && *place.CreatedBy == caller.UserID {
+        return nil
+    }
+    return er.ErrForbidden
+}
+
+// List returns one page of places. One extra row is fetched to tell whether
+// a next cursor is needed; the total and tag facets are only computed for
+// offset paging.
+func (s *PlaceService) List(ctx context.Context, q models.PlaceQuery) (*models.PlacePage, error) {
+    if q.Cursor != nil && (q.Offset != 0 || q.Cursor.Sort != q.Sort || q.Cursor.Desc != q.Desc) {
+        return nil, er.ErrInvalidQuery
+    }
+
+    limit := q.Limit
+    q.Limit = limit + 1
+    places, err := s.repo.List(ctx, q)
+    if err != nil {
+        return nil, err
+    }
+
+    page := &models.PlacePage{Page: models.Page[models.Place]{Limit: limit, Offset: q.Offset}}
+    if len(places) > limit {
+        places = places[:limit]
+        last := places[limit-1]
+        page.NextCursor = models.Cursor{Sort: q.Sort, Desc: q.Desc, Value: last.SortValue(q.Sort), Id: last.Id}.Encode()
+    }
+    page.Items = places
+
+    if q.Cursor == nil {
+        total, err := s.repo.Count(ctx, q)
+        if err != nil {
+            return nil, err
+        }
+        page.Total = &total
+
+        page.Facets, err = s.repo.Facets(ctx, q)
+        if err != nil {
+            return nil, err
+        }
+    }
+
+    return page, nil
+}
+
+// Nearby returns places around a point, nearest first, each with its
+// distance in meters.
+func (s *PlaceService) Nearby(ctx context.Context, q models.GeoQuery) (*models.Page[models.PlaceWithDistance], error) {
+    if q.RadiusMeters <= 0 && q.BBox == nil {
+        return nil, er.ErrInvalidQuery
+    }
+
+    places, total, err := s.repo.Nearby(ctx, q)
+    if err != nil {
+        return nil, err
+    }
+
+    return &models.Page[models.PlaceWithDistance]{Items: places, Total: &total, Limit: q.Limit, Offset: q.Offset}, nil
+}
+
+// Search ranks places by how well their name, address and description match
+// the text, tolerating typos and treating each word as a prefix.
+func (s *PlaceService) Search(ctx context.Context, q models.SearchQuery) (*models.Page[models.PlaceSearchResult], error) {
+    q.Text = strings.TrimSpace(q.Text)
+    if q.Text == "" || len(q.Text) > maxSearchLength {
+        return nil, er.ErrInvalidQuery
+    }
+
+    places, total, err := s.repo.Search(ctx, q)
+    if err != nil {
+        return nil, err
+    }
+
+    return &models.Page[models.PlaceSearchResult]{Items: places, Total: &total, Limit: q.Limit, Offset: q.Offset}, nil
+}
+
+func (s *PlaceService) GetById(ctx context.Context, id string) (*models.Place, error) {
+    if id == "" {
+        return nil, er.ErrInvalidPlaceData
+    }
+
+    return s.cache.GetOrLoad(ctx, id, func(ctx context.Context) (*models.Place, error) {
+        return s.repo.GetByID(ctx, id)
+    })
+}
+
+func (s *PlaceService) ListVisitors(ctx context.Context, placeID string, limit, offset int) (*models.Page[models.UserSummary], error) {
+    if placeID == "" {
+        return nil, er.ErrInvalidPlaceData
+    }
+
+    if _, err := s.GetById(ctx, placeID); err != nil {
+        return nil, err
+    }
+
+    users, total, err := s.userPlaceRepo.ListVisitors(ctx, placeID, limit, offset)
+    if err != nil {
+        return nil, err
+    }
+
+    visitors := make([]models.UserSummary, 0, len(users))
+    for _, u := range users {
+        visitors = append(visitors, models.UserSummary{Id: u.Id, Name: u.Name})
+    }
+
+    return &models.Page[models.UserSummary]{Items: visitors, Total: &total, Limit: limit, Offset: offset}, nil
+}
+
+func (s *PlaceService) Create(ctx context.Context, p *models.PlaceCreateRequest) (*models.Place, error) {
+
+    if p.Name == "" {
+        return nil, er.ErrInvalidPlaceData
+    }
+
+    callerID, ok := auth.UserIDFromContext(ctx)
+    if !ok {
+        return nil, er.ErrUnauthorized
+    }
+
+    tags, err := s.resolveTags(ctx, p.Tags)
+    if err != nil {
+        return nil, err
+    }
+
+	id := uuid.New()
+
+	place := models.Place{
+		Id: 			id.String(),
+		Name: 			p.Name,
+		Description:	p.Description,
+		Location: 		p.Location,
+		Address: 		p.Address,
+		CreatedBy: 		&callerID,
+		CreatedAt: 		time.Now(),
+		Tags: 			tags,
+		Version:		1,
+	}
+
+	err = s.repo.Create(ctx, &place)
+	if err != nil {
+		return nil, err
+	}
+	s.record(ctx, models.AuditCreate, place.Id, nil, &place)
+
+	s.cache.Set(place.Id, &place)
+
+    return &place, nil
+}
+
+// Update applies p if the place is still at version, which is 0 to skip the
+// check, and returns the updated place.
+func (s *PlaceService) Update(ctx context.Context, id string, p *models.PlaceUpdateRequest, version int64) (*models.Place, error) {
+    return s.update(ctx, id, p, version, models.AuditUpdate, nil)
+}
+
+// update applies p and records it in the place's history under action.
+func (s *PlaceService) update(ctx context.Context, id string, p *models.PlaceUpdateRequest, version int64, action string, revertOf *string) (*models.Place, error) {
+    if id == "" {
+        return nil, er.ErrInvalidPlaceData
+    }
+
+    // Resolved before the transaction: the tag repository is not part of
+    // it, and SetTags reports a tag deleted in between as unknown.
+    var tags []models.Tag
+    if p.Tags != nil {
+        var err error
+        if tags, err = s.resolveTags(ctx, *p.Tags); err != nil {
+            return nil, err
+        }
+    }
+
+    var before, after *models.Place
+    err := s.uow.WithTx(ctx, func(tx repo.Repos) error {
+        var err error
+        before, err = tx.Places.GetByID(ctx, id)
+        if err != nil {
+            return err
+        }
+        if err := authorizeOwner(ctx, before, models.RoleAdmin, models.RoleEditor); err != nil {
+            return err
+        }
+        if version != 0 && before.Version != version {
+            return er.ErrVersionMismatch
+        }
+
+        if err := tx.Places.Update(ctx, id, p, version); err != nil {
+            return err
+        }
+        if p.Tags != nil {
+            if err := tx.Places.SetTags(ctx, id, tags); err != nil {
+                return err
+            }
+        }
+        after, err = tx.Places.GetByID(ctx, id)
+        return err
+    })
+    if err != nil {
+        return nil, err
+    }
+
+    s.evict(ctx, id)
+    s.recordUpdate(ctx, action, before, after, revertOf)
+
+    return after, nil
+}
+
+// DeleteById moves the place to the trash if it is still at version, which
+// is 0 to skip the check.
+func (s *PlaceService) DeleteById(ctx context.Context, id string, version int64) error {
+    if id == "" {
+        return er.ErrInvalidPlaceData
+    }
+
+    var place *models.Place
+    err := s.uow.WithTx(ctx, func(tx repo.Repos) error {
+        var err error
+        place, err = tx.Places.GetByID(ctx, id)
+        if err != nil {
+            return err
+        }
+        if err := authorizeOwner(ctx, place, models.RoleAdmin); err != nil {
+            return err
+        }
+        if version != 0 && place.Version != version {
+            return er.ErrVersionMismatch
+        }
+        return tx.Places.Delete(ctx, id, version)
+    })
+    if err != nil {
+        return err
+    }
+    s.record(ctx, models.AuditDelete, id, nil, place)
+
+    s.evict(ctx, id)
+
+    return nil
+}
+
+func (s *PlaceService) DeleteAll(ctx context.Context) error {
+    var ids []string
+    err := s.uow.WithTx(ctx, func(tx repo.Repos) error {
+        var err error
+        ids, err = tx.Places.DeleteAll(ctx)
+        return err
+    })
+    if err != nil {
+        return err
+    }
+    for _, id := range ids {
+        s.record(ctx, models.AuditDelete, id, nil, nil)
+    }
+
+    s.evictAll(ctx)
+    return nil
+}
+
+// CacheStats reports how the place cache has been used.
+func (s *PlaceService) CacheStats() cache.Stats {
+    return s.cache.Stats()
 }
\ No newline at end of file
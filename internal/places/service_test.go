@@ -0,0 +1,222 @@
+package places
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"deu/internal/audit"
+	"deu/internal/auth"
+	"deu/internal/models"
+	"deu/internal/repository"
+	"deu/pkg/cache"
+)
+
+// newTestServices returns two place services over the same repositories,
+// each with its own cache, exchanging invalidations like two instances of
+// the server.
+func newTestServices(t *testing.T) (*PlaceService, *PlaceService) {
+	t.Helper()
+	b := repository.NewMemoryBackend()
+	bus := cache.NewLocalBus()
+	trail := audit.NewTrail(b.Audit)
+
+	newService := func() *PlaceService {
+		placeCache := cache.NewLRU(cache.Options[*models.Place]{Clone: (*models.Place).Clone})
+		return NewPlaceService(b.Places, b.UserPlaces, b.Reviews, b.Tags, b.Photos, b.UnitOfWork, nil, trail, placeCache, bus)
+	}
+	return newService(), newService()
+}
+
+func adminContext() context.Context {
+	return auth.WithPrincipal(context.Background(), auth.Principal{UserID: "00000000-0000-0000-0000-000000000001", Role: models.RoleAdmin})
+}
+
+func TestWritesOnOneInstanceEvictOthersCache(t *testing.T) {
+	tests := []struct {
+		name  string
+		write func(ctx context.Context, s *PlaceService, id string) error
+		// gone is set when the place no longer exists after the write.
+		gone bool
+	}{
+		{"update", func(ctx context.Context, s *PlaceService, id string) error {
+			name := "Renamed place"
+			_, err := s.Update(ctx, id, &models.PlaceUpdateRequest{Name: &name}, 0)
+			return err
+		}, false},
+		{"delete", func(ctx context.Context, s *PlaceService, id string) error {
+			return s.DeleteById(ctx, id, 0)
+		}, true},
+		{"delete all", func(ctx context.Context, s *PlaceService, id string) error {
+			return s.DeleteAll(ctx)
+		}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := adminContext()
+			writer, reader := newTestServices(t)
+
+			place, err := writer.Create(ctx, &models.PlaceCreateRequest{
+				Name:        "Original place",
+				Description: "A place to be cached",
+				Location:    models.Location{Latitude: 48.85, Longitude: 2.35},
+				Address:     "1 Example Street",
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := reader.GetById(ctx, place.Id); err != nil {
+				t.Fatal(err)
+			}
+			if _, ok := reader.cache.Get(place.Id); !ok {
+				t.Fatal("the reader did not cache the place")
+			}
+
+			if err := tt.write(ctx, writer, place.Id); err != nil {
+				t.Fatal(err)
+			}
+
+			if _, ok := reader.cache.Get(place.Id); ok {
+				t.Fatal("the reader still caches the place after the writer changed it")
+			}
+			got, err := reader.GetById(ctx, place.Id)
+			if tt.gone {
+				if err == nil {
+					t.Errorf("the reader still returns the place after the writer removed it")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Name != "Renamed place" {
+				t.Errorf("the reader returned name %q, want the writer's update", got.Name)
+			}
+		})
+	}
+}
+
+// interleavingUnitOfWork runs another writer's change right after each
+// transaction commits.
+type interleavingUnitOfWork struct {
+	repository.UnitOfWork
+	interleave func()
+}
+
+func (u interleavingUnitOfWork) WithTx(ctx context.Context, fn func(tx repository.Repos) error) error {
+	err := u.UnitOfWork.WithTx(ctx, fn)
+	if err == nil {
+		u.interleave()
+	}
+	return err
+}
+
+func TestHistoryEntryHoldsOnlyItsOwnChange(t *testing.T) {
+	tests := []struct {
+		name string
+		// write changes the place and returns the fields it changed.
+		write func(ctx context.Context, s *PlaceService, id string) ([]string, error)
+	}{
+		{"update", func(ctx context.Context, s *PlaceService, id string) ([]string, error) {
+			name := "Renamed place"
+			_, err := s.Update(ctx, id, &models.PlaceUpdateRequest{Name: &name}, 0)
+			return []string{"name"}, err
+		}},
+		{"review", func(ctx context.Context, s *PlaceService, id string) ([]string, error) {
+			_, _, err := s.SaveReview(ctx, id, &models.ReviewRequest{Rating: 4})
+			return []string{"averageRating", "reviewCount"}, err
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := adminContext()
+			b := repository.NewMemoryBackend()
+			var placeID string
+			otherWriter := func() {
+				description := "Changed by someone else"
+				if err := b.Places.Update(ctx, placeID, &models.PlaceUpdateRequest{Description: &description}, 0); err != nil {
+					t.Error(err)
+				}
+			}
+			uow := interleavingUnitOfWork{b.UnitOfWork, otherWriter}
+			s := NewPlaceService(b.Places, b.UserPlaces, b.Reviews, b.Tags, b.Photos, uow, nil, audit.NewTrail(b.Audit), cache.Nop[*models.Place]{}, cache.NewLocalBus())
+
+			place := &models.Place{Id: "00000000-0000-0000-0000-0000000000a1", Name: "Original place", Version: 1}
+			if err := b.Places.Create(ctx, place); err != nil {
+				t.Fatal(err)
+			}
+			placeID = place.Id
+
+			want, err := tt.write(ctx, s, placeID)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			history, err := s.History(ctx, placeID, 1, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(history.Items) != 1 {
+				t.Fatalf("got %d history entries, want 1", len(history.Items))
+			}
+			var got []string
+			for _, c := range history.Items[0].Changes {
+				got = append(got, c.Field)
+			}
+			if strings.Join(got, ",") != strings.Join(want, ",") {
+				t.Errorf("the entry changes %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+// racingReviews saves rival just before the review it is asked to save, as
+// a request racing the one under test would.
+type racingReviews struct {
+	repository.ReviewRepository
+	rival models.Review
+}
+
+func (r racingReviews) Save(ctx context.Context, rv *models.Review) error {
+	if err := r.ReviewRepository.Save(ctx, &r.rival); err != nil {
+		return err
+	}
+	return r.ReviewRepository.Save(ctx, rv)
+}
+
+// racingUnitOfWork hands out racingReviews.
+type racingUnitOfWork struct {
+	repository.UnitOfWork
+	rival models.Review
+}
+
+func (u racingUnitOfWork) WithTx(ctx context.Context, fn func(tx repository.Repos) error) error {
+	return u.UnitOfWork.WithTx(ctx, func(tx repository.Repos) error {
+		tx.Reviews = racingReviews{tx.Reviews, u.rival}
+		return fn(tx)
+	})
+}
+
+func TestRacingFirstReviewsCreateOne(t *testing.T) {
+	ctx := adminContext()
+	b := repository.NewMemoryBackend()
+	place := &models.Place{Id: "00000000-0000-0000-0000-0000000000a1", Name: "Original place", Version: 1}
+	if err := b.Places.Create(ctx, place); err != nil {
+		t.Fatal(err)
+	}
+	caller, _ := auth.UserIDFromContext(ctx)
+	rival := models.Review{Id: "00000000-0000-0000-0000-0000000000d1", PlaceID: place.Id, UserID: caller, Rating: 5}
+	uow := racingUnitOfWork{b.UnitOfWork, rival}
+	s := NewPlaceService(b.Places, b.UserPlaces, b.Reviews, b.Tags, b.Photos, uow, nil, audit.NewTrail(b.Audit), cache.Nop[*models.Place]{}, cache.NewLocalBus())
+
+	review, created, err := s.SaveReview(ctx, place.Id, &models.ReviewRequest{Rating: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created || review.Id != rival.Id {
+		t.Errorf("SaveReview returned review %s, created %v; want the rival's review %s, not created", review.Id, created, rival.Id)
+	}
+	if review.Rating != 3 {
+		t.Errorf("SaveReview returned rating %d, want 3", review.Rating)
+	}
+}
@@ -0,0 +1,125 @@
+package places
+
+import (
+    "context"
+    "time"
+
+    "github.com/google/uuid"
+
+    er "deu/internal/errors"
+    "deu/internal/models"
+    "deu/pkg/cache"
+)
+
+// slugs turns tag names or slugs into distinct slugs, keeping their order.
+func slugs(names []string) []string {
+    seen := make(map[string]bool, len(names))
+    result := make([]string, 0, len(names))
+    for _, name := range names {
+        slug := models.Slugify(name)
+        if slug == "" || seen[slug] {
+            continue
+        }
+        seen[slug] = true
+        result = append(result, slug)
+    }
+    return result
+}
+
+// resolveTags looks up the tags named in a place request. Every tag must
+// already exist; unknown ones are reported as er.ErrUnknownTag.
+func (s *PlaceService) resolveTags(ctx context.Context, names []string) ([]models.Tag, error) {
+    wanted := slugs(names)
+    if len(wanted) == 0 {
+        return []models.Tag{}, nil
+    }
+
+    tags, err := s.tagRepo.GetBySlugs(ctx, wanted)
+    if err != nil {
+        return nil, err
+    }
+    if len(tags) != len(wanted) {
+        return nil, er.ErrUnknownTag
+    }
+    return tags, nil
+}
+
+// evictAll empties the caches of all instances after a change that may
+// touch any place.
+func (s *PlaceService) evictAll(ctx context.Context) {
+    s.invalidate(ctx, cache.Invalidation{All: true})
+}
+
+func (s *PlaceService) ListTags(ctx context.Context) ([]models.Tag, error) {
+    return s.tagRepo.List(ctx)
+}
+
+// checkSlugFree fails with ErrTagExists when another tag already uses slug.
+func (s *PlaceService) checkSlugFree(ctx context.Context, slug, exceptID string) error {
+    existing, err := s.tagRepo.GetBySlugs(ctx, []string{slug})
+    if err != nil {
+        return err
+    }
+    for _, t := range existing {
+        if t.Id != exceptID {
+            return er.ErrTagExists
+        }
+    }
+    return nil
+}
+
+func (s *PlaceService) CreateTag(ctx context.Context, req *models.TagRequest) (*models.Tag, error) {
+    slug := models.Slugify(req.Name)
+    if slug == "" {
+        return nil, er.ErrInvalidTagData
+    }
+    if err := s.checkSlugFree(ctx, slug, ""); err != nil {
+        return nil, err
+    }
+
+    tag := models.Tag{
+        Id:        uuid.New().String(),
+        Name:      req.Name,
+        Slug:      slug,
+        CreatedAt: time.Now(),
+    }
+    if err := s.tagRepo.Create(ctx, &tag); err != nil {
+        return nil, err
+    }
+    return &tag, nil
+}
+
+// UpdateTag renames a tag. Its slug follows the new name, so filters using
+// the old slug stop matching.
+func (s *PlaceService) UpdateTag(ctx context.Context, id string, req *models.TagRequest) (*models.Tag, error) {
+    tag, err := s.tagRepo.GetByID(ctx, id)
+    if err != nil {
+        return nil, err
+    }
+
+    slug := models.Slugify(req.Name)
+    if slug == "" {
+        return nil, er.ErrInvalidTagData
+    }
+    if err := s.checkSlugFree(ctx, slug, id); err != nil {
+        return nil, err
+    }
+
+    tag.Name = req.Name
+    tag.Slug = slug
+    if err := s.tagRepo.Update(ctx, tag); err != nil {
+        return nil, err
+    }
+
+    s.evictAll(ctx)
+    return tag, nil
+}
+
+func (s *PlaceService) DeleteTag(ctx context.Context, id string) error {
+    if err := s.tagRepo.Delete(ctx, id); err != nil {
+        return err
+    }
+
+    s.evictAll(ctx)
+    return nil
+}
@@ -0,0 +1,51 @@
+package places
+
+import (
+    "context"
+    "time"
+
+    er "deu/internal/errors"
+    "deu/internal/models"
+)
+
+// ListDeleted returns one page of the places in the trash, most recently
+// deleted first.
+func (s *PlaceService) ListDeleted(ctx context.Context, limit, offset int) (*models.Page[models.Place], error) {
+    places, total, err := s.repo.ListDeleted(ctx, limit, offset)
+    if err != nil {
+        return nil, err
+    }
+
+    return &models.Page[models.Place]{Items: places, Total: &total, Limit: limit, Offset: offset}, nil
+}
+
+// Restore takes a place out of the trash. Its reviews, visits, tags and
+// photos were never removed, so they come back with it.
+func (s *PlaceService) Restore(ctx context.Context, id string) error {
+    if id == "" {
+        return er.ErrInvalidPlaceData
+    }
+
+    if err := s.repo.Restore(ctx, id); err != nil {
+        return err
+    }
+    if place, err := s.repo.GetByID(ctx, id); err == nil {
+        s.record(ctx, models.AuditRestore, id, nil, place)
+    }
+    return nil
+}
+
+// Purge permanently removes the places deleted before the cutoff and their
+// photo blobs, and reports how many places were removed.
+func (s *PlaceService) Purge(ctx context.Context, before time.Time) (int, error) {
+    ids, err := s.repo.Purge(ctx, before)
+    if err != nil {
+        return 0, err
+    }
+
+    for _, id := range ids {
+        s.removePhotos(ctx, id)
+        s.record(ctx, models.AuditPurge, id, nil, nil)
+    }
+    return len(ids), nil
+}
@@ -0,0 +1,16 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"deu/internal/models"
+)
+
+type APIKeyRepository interface {
+	Create(ctx context.Context, k *models.APIKey) error
+	ListByUser(ctx context.Context, userID string) ([]models.APIKey, error)
+	GetByHash(ctx context.Context, keyHash string) (*models.APIKey, error)
+	Delete(ctx context.Context, userID, id string) error
+	TouchLastUsed(ctx context.Context, id string, at time.Time) error
+}
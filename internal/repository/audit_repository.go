@@ -0,0 +1,14 @@
+package repository
+
+import (
+	"context"
+
+	"deu/internal/models"
+)
+
+// AuditRepository is an append-only log of changes to places and users.
+type AuditRepository interface {
+	Create(ctx context.Context, e *models.AuditEntry) error
+	GetByID(ctx context.Context, id string) (*models.AuditEntry, error)
+	ListByEntity(ctx context.Context, entityType, entityID string, limit, offset int) ([]models.AuditEntry, int64, error)
+}
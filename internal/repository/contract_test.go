@@ -0,0 +1,635 @@
+package repository_test
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+
+	er "deu/internal/errors"
+	"deu/internal/models"
+	"deu/internal/repository"
+	"deu/pkg/db"
+)
+
+// backend is one storage backend under test. The contract tests below run
+// against each of them and expect the same behaviour from all.
+type backend struct {
+	users      repository.UserRepository
+	places     repository.PlaceRepository
+	userPlaces repository.UserPlaceRepository
+	reviews    repository.ReviewRepository
+	uow        repository.UnitOfWork
+}
+
+// forEachBackend runs test once per backend, each time on empty storage.
+// Postgres is only tested when TEST_DATABASE_URL is set; the tests wipe the
+// users and places of that database.
+func forEachBackend(t *testing.T, test func(t *testing.T, b backend)) {
+	t.Run("memory", func(t *testing.T) {
+		m := repository.NewMemoryBackend()
+		test(t, backend{m.Users, m.Places, m.UserPlaces, m.Reviews, m.UnitOfWork})
+	})
+
+	t.Run("sqlite", func(t *testing.T) {
+		gormDB := db.InitSQLite(filepath.Join(t.TempDir(), "contract.db"))
+		t.Cleanup(func() { closeDB(t, gormDB) })
+		migrate(t, gormDB, db.SQLiteMigrations())
+		test(t, backend{
+			repository.NewSQLiteUserRepository(gormDB),
+			repository.NewSQLitePlaceRepository(gormDB),
+			repository.NewSQLiteUserPlaceRepository(gormDB),
+			repository.NewPostgresReviewRepository(gormDB),
+			repository.NewSQLiteUnitOfWork(gormDB),
+		})
+	})
+
+	t.Run("postgres", func(t *testing.T) {
+		dsn := os.Getenv("TEST_DATABASE_URL")
+		if dsn == "" {
+			t.Skip("TEST_DATABASE_URL is not set")
+		}
+		gormDB := db.InitDB(dsn)
+		t.Cleanup(func() { closeDB(t, gormDB) })
+		migrate(t, gormDB, db.PostgresMigrations())
+		// Everything else references users or places.
+		if err := gormDB.Exec("TRUNCATE users, places CASCADE").Error; err != nil {
+			t.Fatal(err)
+		}
+		test(t, backend{
+			repository.NewPostgresUserRepository(gormDB),
+			repository.NewPostgresPlaceRepository(gormDB),
+			repository.NewPostgresUserPlaceRepository(gormDB),
+			repository.NewPostgresReviewRepository(gormDB),
+			repository.NewPostgresUnitOfWork(gormDB),
+		})
+	})
+}
+
+func migrate(t *testing.T, gormDB *gorm.DB, migrations fs.FS) {
+	t.Helper()
+	migrator, err := db.NewMigrator(gormDB, migrations)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := migrator.Up(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func closeDB(t *testing.T, gormDB *gorm.DB) {
+	sqlDB, err := gormDB.DB()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if err := sqlDB.Close(); err != nil {
+		t.Error(err)
+	}
+}
+
+// The ids are uuids because Postgres stores them as such.
+const (
+	adaID    = "00000000-0000-0000-0000-00000000000a"
+	bobID    = "00000000-0000-0000-0000-00000000000b"
+	louvreID = "00000000-0000-0000-0000-0000000000a1"
+	towerID  = "00000000-0000-0000-0000-0000000000a2"
+)
+
+func createUser(t *testing.T, b backend, id, email string) *models.User {
+	t.Helper()
+	u := &models.User{Id: id, Name: "user " + id[len(id)-2:], Email: email, Role: models.RoleMember, Version: 1}
+	if err := b.users.Create(context.Background(), u); err != nil {
+		t.Fatalf("creating user %s: %v", email, err)
+	}
+	return u
+}
+
+func createPlace(t *testing.T, b backend, id, name string, createdBy *string) *models.Place {
+	t.Helper()
+	p := &models.Place{
+		Id:          id,
+		Name:        name,
+		Description: "A place for the contract tests",
+		Location:    models.Location{Latitude: 48.8606111, Longitude: 2.337644},
+		Address:     "Rue de Rivoli, Paris",
+		CreatedBy:   createdBy,
+		Version:     1,
+	}
+	if err := b.places.Create(context.Background(), p); err != nil {
+		t.Fatalf("creating place %s: %v", name, err)
+	}
+	return p
+}
+
+func addVisit(t *testing.T, b backend, id, userID, placeID string, at time.Time) {
+	t.Helper()
+	visit := &models.UserPlace{Id: id, UserID: userID, PlaceID: placeID, VisitedAt: at}
+	if err := b.userPlaces.AddVisitedPlace(context.Background(), visit); err != nil {
+		t.Fatalf("adding visit %s: %v", id, err)
+	}
+}
+
+func expectErr(t *testing.T, err, want error, what string) {
+	t.Helper()
+	if !errors.Is(err, want) {
+		t.Errorf("%s returned %v, want %v", what, err, want)
+	}
+}
+
+func placeIDs(places []models.Place) []string {
+	ids := make([]string, 0, len(places))
+	for _, p := range places {
+		ids = append(ids, p.Id)
+	}
+	return ids
+}
+
+func userIDs(users []models.User) []string {
+	ids := make([]string, 0, len(users))
+	for _, u := range users {
+		ids = append(ids, u.Id)
+	}
+	return ids
+}
+
+func TestContractUsersRoundTrip(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, b backend) {
+		ctx := context.Background()
+		createUser(t, b, adaID, "ada@example.com")
+
+		got, err := b.users.GetByID(ctx, adaID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Email != "ada@example.com" || got.Role != models.RoleMember || got.Version != 1 {
+			t.Errorf("GetByID = %+v, want the created user", got)
+		}
+
+		got, err = b.users.GetByEmail(ctx, "Ada@Example.com")
+		if err != nil || got.Id != adaID {
+			t.Errorf("GetByEmail in another case = %v, %v, want user %s", got, err, adaID)
+		}
+
+		_, err = b.users.GetByID(ctx, bobID)
+		expectErr(t, err, er.ErrUserNotFound, "GetByID of an unknown user")
+		_, err = b.users.GetByEmail(ctx, "bob@example.com")
+		expectErr(t, err, er.ErrUserNotFound, "GetByEmail of an unknown email")
+	})
+}
+
+func TestContractDuplicateEmailConflicts(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, b backend) {
+		ctx := context.Background()
+		createUser(t, b, adaID, "ada@example.com")
+
+		tests := []struct {
+			name  string
+			email string
+		}{
+			{"same email", "ada@example.com"},
+			{"email in another case", "ADA@example.com"},
+		}
+		for _, tt := range tests {
+			err := b.users.Create(ctx, &models.User{Id: bobID, Name: "bob", Email: tt.email, Role: models.RoleMember, Version: 1})
+			expectErr(t, err, er.ErrConflict, "Create with the "+tt.name)
+		}
+
+		createUser(t, b, bobID, "bob@example.com")
+		taken := "Ada@example.com"
+		err := b.users.Update(ctx, bobID, &models.UserUpdateRequest{Email: &taken}, 0)
+		expectErr(t, err, er.ErrConflict, "Update to a taken email")
+		if got, err := b.users.GetByID(ctx, bobID); err != nil || got.Email != "bob@example.com" {
+			t.Errorf("after the conflict GetByID = %v, %v, want the email unchanged", got, err)
+		}
+	})
+}
+
+func TestContractDeletedUsersFreeTheirEmail(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, b backend) {
+		ctx := context.Background()
+		createUser(t, b, adaID, "ada@example.com")
+		if err := b.users.Delete(ctx, adaID, 0); err != nil {
+			t.Fatal(err)
+		}
+
+		createUser(t, b, bobID, "ada@example.com")
+		expectErr(t, b.users.Restore(ctx, adaID), er.ErrConflict, "Restore of a user whose email was taken")
+
+		if err := b.users.Delete(ctx, bobID, 0); err != nil {
+			t.Fatal(err)
+		}
+		if err := b.users.Restore(ctx, adaID); err != nil {
+			t.Fatalf("Restore once the email is free again: %v", err)
+		}
+		if got, err := b.users.GetByEmail(ctx, "ada@example.com"); err != nil || got.Id != adaID {
+			t.Errorf("GetByEmail = %v, %v, want the restored user", got, err)
+		}
+	})
+}
+
+func TestContractVersionChecks(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, b backend) {
+		ctx := context.Background()
+		createUser(t, b, adaID, "ada@example.com")
+		createPlace(t, b, louvreID, "Louvre Museum", nil)
+		name := "Renamed"
+
+		if err := b.users.Update(ctx, adaID, &models.UserUpdateRequest{Name: &name}, 1); err != nil {
+			t.Fatal(err)
+		}
+		if got, err := b.users.GetByID(ctx, adaID); err != nil || got.Version != 2 {
+			t.Fatalf("after an update GetByID = %v, %v, want version 2", got, err)
+		}
+		expectErr(t, b.users.Update(ctx, adaID, &models.UserUpdateRequest{Name: &name}, 1), er.ErrVersionMismatch, "user Update at a stale version")
+		expectErr(t, b.users.Delete(ctx, adaID, 1), er.ErrVersionMismatch, "user Delete at a stale version")
+		expectErr(t, b.users.Update(ctx, bobID, &models.UserUpdateRequest{Name: &name}, 1), er.ErrUserNotFound, "Update of an unknown user")
+		expectErr(t, b.users.Delete(ctx, bobID, 0), er.ErrUserNotFound, "Delete of an unknown user")
+
+		if err := b.places.Update(ctx, louvreID, &models.PlaceUpdateRequest{Name: &name}, 1); err != nil {
+			t.Fatal(err)
+		}
+		if got, err := b.places.GetByID(ctx, louvreID); err != nil || got.Version != 2 {
+			t.Fatalf("after an update GetByID = %v, %v, want version 2", got, err)
+		}
+		expectErr(t, b.places.Update(ctx, louvreID, &models.PlaceUpdateRequest{Name: &name}, 1), er.ErrVersionMismatch, "place Update at a stale version")
+		expectErr(t, b.places.Delete(ctx, louvreID, 1), er.ErrVersionMismatch, "place Delete at a stale version")
+		expectErr(t, b.places.Update(ctx, towerID, &models.PlaceUpdateRequest{Name: &name}, 1), er.ErrPlaceNotFound, "Update of an unknown place")
+		expectErr(t, b.places.Delete(ctx, towerID, 0), er.ErrPlaceNotFound, "Delete of an unknown place")
+
+		if err := b.places.Delete(ctx, louvreID, 2); err != nil {
+			t.Errorf("place Delete at the current version: %v", err)
+		}
+	})
+}
+
+func TestContractLocationRoundTrips(t *testing.T) {
+	tests := []struct {
+		name     string
+		location models.Location
+	}{
+		{"north east", models.Location{Latitude: 48.8606111, Longitude: 2.337644}},
+		{"south west", models.Location{Latitude: -33.8567844, Longitude: -151.2152967}},
+		{"extremes", models.Location{Latitude: -90, Longitude: 180}},
+		{"null island", models.Location{Latitude: 0, Longitude: 0}},
+		{"full precision", models.Location{Latitude: 1.0 / 3, Longitude: -2.0 / 3}},
+	}
+	forEachBackend(t, func(t *testing.T, b backend) {
+		ctx := context.Background()
+		createUser(t, b, adaID, "ada@example.com")
+		place := createPlace(t, b, louvreID, "Louvre Museum", nil)
+		addVisit(t, b, "00000000-0000-0000-0000-0000000000c1", adaID, louvreID, time.Now())
+
+		for _, tt := range tests {
+			location := tt.location
+			if err := b.places.Update(ctx, louvreID, &models.PlaceUpdateRequest{Location: &location}, 0); err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := b.places.GetByID(ctx, place.Id)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Location != tt.location {
+				t.Errorf("%s: GetByID returned %+v, want %+v", tt.name, got.Location, tt.location)
+			}
+
+			listed, err := b.places.List(ctx, models.PlaceQuery{Limit: 10, Sort: models.PlaceSortName})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(listed) != 1 || listed[0].Location != tt.location {
+				t.Errorf("%s: List returned %+v, want one place at %+v", tt.name, listed, tt.location)
+			}
+
+			visited, _, err := b.userPlaces.ListVisitedPlaces(ctx, adaID, 10, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(visited) != 1 || visited[0].Location != tt.location {
+				t.Errorf("%s: ListVisitedPlaces returned %+v, want one place at %+v", tt.name, visited, tt.location)
+			}
+		}
+
+		// Create stores the location it is given, not only Update.
+		created := createPlace(t, b, towerID, "Eiffel Tower", nil)
+		if got, err := b.places.GetByID(ctx, towerID); err != nil || got.Location != created.Location {
+			t.Errorf("GetByID of a created place = %v, %v, want it at %+v", got, err, created.Location)
+		}
+	})
+}
+
+func TestContractSoftDeleteAndRestore(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, b backend) {
+		ctx := context.Background()
+		createUser(t, b, adaID, "ada@example.com")
+		createPlace(t, b, louvreID, "Louvre Museum", nil)
+
+		if err := b.users.Delete(ctx, adaID, 0); err != nil {
+			t.Fatal(err)
+		}
+		if err := b.places.Delete(ctx, louvreID, 0); err != nil {
+			t.Fatal(err)
+		}
+
+		_, err := b.users.GetByID(ctx, adaID)
+		expectErr(t, err, er.ErrUserNotFound, "GetByID of a deleted user")
+		_, err = b.places.GetByID(ctx, louvreID)
+		expectErr(t, err, er.ErrPlaceNotFound, "GetByID of a deleted place")
+
+		deletedUsers, total, err := b.users.ListDeleted(ctx, 10, 0)
+		if err != nil || total != 1 || !reflect.DeepEqual(userIDs(deletedUsers), []string{adaID}) {
+			t.Errorf("users ListDeleted = %v, %d, %v, want the deleted user", userIDs(deletedUsers), total, err)
+		}
+		deletedPlaces, total, err := b.places.ListDeleted(ctx, 10, 0)
+		if err != nil || total != 1 || !reflect.DeepEqual(placeIDs(deletedPlaces), []string{louvreID}) {
+			t.Errorf("places ListDeleted = %v, %d, %v, want the deleted place", placeIDs(deletedPlaces), total, err)
+		}
+
+		if err := b.users.Restore(ctx, adaID); err != nil {
+			t.Fatal(err)
+		}
+		if err := b.places.Restore(ctx, louvreID); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := b.users.GetByID(ctx, adaID); err != nil {
+			t.Errorf("GetByID of a restored user: %v", err)
+		}
+		if _, err := b.places.GetByID(ctx, louvreID); err != nil {
+			t.Errorf("GetByID of a restored place: %v", err)
+		}
+
+		expectErr(t, b.users.Restore(ctx, adaID), er.ErrUserNotFound, "Restore of a live user")
+		expectErr(t, b.places.Restore(ctx, louvreID), er.ErrPlaceNotFound, "Restore of a live place")
+	})
+}
+
+func TestContractVisits(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, b backend) {
+		ctx := context.Background()
+		createUser(t, b, adaID, "ada@example.com")
+		createUser(t, b, bobID, "bob@example.com")
+		createPlace(t, b, louvreID, "Louvre Museum", nil)
+		createPlace(t, b, towerID, "Eiffel Tower", nil)
+
+		now := time.Now().UTC().Truncate(time.Second)
+		addVisit(t, b, "00000000-0000-0000-0000-0000000000c1", adaID, louvreID, now.Add(-48*time.Hour))
+		addVisit(t, b, "00000000-0000-0000-0000-0000000000c2", adaID, louvreID, now)
+		addVisit(t, b, "00000000-0000-0000-0000-0000000000c3", adaID, towerID, now)
+		addVisit(t, b, "00000000-0000-0000-0000-0000000000c4", bobID, louvreID, now)
+
+		visits, err := b.userPlaces.ListVisits(ctx, adaID, louvreID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var ids []string
+		for _, v := range visits {
+			ids = append(ids, v.Id)
+		}
+		if want := []string{"00000000-0000-0000-0000-0000000000c2", "00000000-0000-0000-0000-0000000000c1"}; !reflect.DeepEqual(ids, want) {
+			t.Errorf("ListVisits = %v, want the newest visit first %v", ids, want)
+		}
+
+		visited, total, err := b.userPlaces.ListVisitedPlaces(ctx, adaID, 10, 0)
+		if err != nil || total != 2 || !reflect.DeepEqual(placeIDs(visited), []string{towerID, louvreID}) {
+			t.Errorf("ListVisitedPlaces = %v, %d, %v, want both places once, by name", placeIDs(visited), total, err)
+		}
+		visitors, total, err := b.userPlaces.ListVisitors(ctx, louvreID, 10, 0)
+		if err != nil || total != 2 {
+			t.Errorf("ListVisitors = %v, %d, %v, want both users", userIDs(visitors), total, err)
+		}
+
+		expectErr(t, b.userPlaces.RemoveVisit(ctx, bobID, "00000000-0000-0000-0000-0000000000c1"), er.ErrVisitNotFound, "RemoveVisit of another user's visit")
+		if err := b.userPlaces.RemoveVisit(ctx, adaID, "00000000-0000-0000-0000-0000000000c1"); err != nil {
+			t.Fatal(err)
+		}
+		if err := b.userPlaces.RemoveVisitedPlace(ctx, adaID, louvreID); err != nil {
+			t.Fatal(err)
+		}
+		if visits, err := b.userPlaces.ListVisits(ctx, adaID, louvreID); err != nil || len(visits) != 0 {
+			t.Errorf("ListVisits after RemoveVisitedPlace = %d visits, %v, want none", len(visits), err)
+		}
+		if visits, err := b.userPlaces.ListVisits(ctx, bobID, louvreID); err != nil || len(visits) != 1 {
+			t.Errorf("another user's visits = %d, %v, want 1 left", len(visits), err)
+		}
+
+		// Soft-deleted users and places are left out of the lists.
+		if err := b.places.Delete(ctx, towerID, 0); err != nil {
+			t.Fatal(err)
+		}
+		if err := b.users.Delete(ctx, bobID, 0); err != nil {
+			t.Fatal(err)
+		}
+		if visited, total, err := b.userPlaces.ListVisitedPlaces(ctx, adaID, 10, 0); err != nil || total != 0 {
+			t.Errorf("ListVisitedPlaces = %v, %d, %v, want the deleted place left out", placeIDs(visited), total, err)
+		}
+		if visitors, total, err := b.userPlaces.ListVisitors(ctx, louvreID, 10, 0); err != nil || total != 0 {
+			t.Errorf("ListVisitors = %v, %d, %v, want the deleted user left out", userIDs(visitors), total, err)
+		}
+	})
+}
+
+func TestContractPurgeCascades(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, b backend) {
+		ctx := context.Background()
+		ada := createUser(t, b, adaID, "ada@example.com")
+		createUser(t, b, bobID, "bob@example.com")
+		createPlace(t, b, louvreID, "Louvre Museum", &ada.Id)
+		createPlace(t, b, towerID, "Eiffel Tower", &ada.Id)
+		now := time.Now()
+		addVisit(t, b, "00000000-0000-0000-0000-0000000000c1", adaID, louvreID, now)
+		addVisit(t, b, "00000000-0000-0000-0000-0000000000c2", bobID, louvreID, now)
+		addVisit(t, b, "00000000-0000-0000-0000-0000000000c3", bobID, towerID, now)
+
+		// Purging a user removes their visits and leaves their places
+		// without an owner.
+		if err := b.users.Delete(ctx, adaID, 0); err != nil {
+			t.Fatal(err)
+		}
+		purged, err := b.users.Purge(ctx, time.Now().Add(time.Hour))
+		if err != nil || !reflect.DeepEqual(purged, []string{adaID}) {
+			t.Fatalf("users Purge = %v, %v, want the deleted user", purged, err)
+		}
+		expectErr(t, b.users.Restore(ctx, adaID), er.ErrUserNotFound, "Restore of a purged user")
+		if visits, err := b.userPlaces.ListVisits(ctx, adaID, louvreID); err != nil || len(visits) != 0 {
+			t.Errorf("visits of a purged user = %d, %v, want none", len(visits), err)
+		}
+		for _, id := range []string{louvreID, towerID} {
+			place, err := b.places.GetByID(ctx, id)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if place.CreatedBy != nil {
+				t.Errorf("place %s is still created by %s after they were purged", id, *place.CreatedBy)
+			}
+		}
+
+		// Purging a place removes the visits to it, and only those.
+		if err := b.places.Delete(ctx, louvreID, 0); err != nil {
+			t.Fatal(err)
+		}
+		purged, err = b.places.Purge(ctx, time.Now().Add(time.Hour))
+		if err != nil || !reflect.DeepEqual(purged, []string{louvreID}) {
+			t.Fatalf("places Purge = %v, %v, want the deleted place", purged, err)
+		}
+		expectErr(t, b.places.Restore(ctx, louvreID), er.ErrPlaceNotFound, "Restore of a purged place")
+		if visits, err := b.userPlaces.ListVisits(ctx, bobID, louvreID); err != nil || len(visits) != 0 {
+			t.Errorf("visits to a purged place = %d, %v, want none", len(visits), err)
+		}
+		if visits, err := b.userPlaces.ListVisits(ctx, bobID, towerID); err != nil || len(visits) != 1 {
+			t.Errorf("visits to another place = %d, %v, want 1 left", len(visits), err)
+		}
+
+		// Purge leaves what was deleted after the cutoff.
+		if err := b.places.Delete(ctx, towerID, 0); err != nil {
+			t.Fatal(err)
+		}
+		purged, err = b.places.Purge(ctx, time.Now().Add(-time.Hour))
+		if err != nil || len(purged) != 0 {
+			t.Errorf("Purge before the deletion = %v, %v, want nothing purged", purged, err)
+		}
+	})
+}
+
+var errRollback = errors.New("roll back")
+
+func TestContractReviewSaveReturnsStoredReview(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, b backend) {
+		ctx := context.Background()
+		createUser(t, b, adaID, "ada@example.com")
+		createPlace(t, b, louvreID, "Louvre", nil)
+
+		created := time.Now().UTC().Truncate(time.Second)
+		first := &models.Review{Id: "00000000-0000-0000-0000-0000000000d1", PlaceID: louvreID, UserID: adaID, Rating: 5, CreatedAt: created, UpdatedAt: created}
+		if err := b.reviews.Save(ctx, first); err != nil {
+			t.Fatal(err)
+		}
+
+		// A second first review, as when two requests race, edits the
+		// stored one instead of adding another.
+		edited := created.Add(time.Minute)
+		second := &models.Review{Id: "00000000-0000-0000-0000-0000000000d2", PlaceID: louvreID, UserID: adaID, Rating: 2, Comment: "Too crowded", CreatedAt: edited, UpdatedAt: edited}
+		if err := b.reviews.Save(ctx, second); err != nil {
+			t.Fatal(err)
+		}
+		if second.Id != first.Id || second.Rating != 2 || second.Comment != "Too crowded" || !second.CreatedAt.Equal(created) {
+			t.Errorf("Save returned review %s rated %d (%q) created at %v, want %s rated 2 created at %v",
+				second.Id, second.Rating, second.Comment, second.CreatedAt, first.Id, created)
+		}
+
+		stored, err := b.reviews.GetByUserAndPlace(ctx, adaID, louvreID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if stored.Id != first.Id || stored.Rating != 2 {
+			t.Errorf("stored review %s rated %d, want %s rated 2", stored.Id, stored.Rating, first.Id)
+		}
+		if _, err := b.reviews.GetByID(ctx, second.Id); err != nil {
+			t.Errorf("GetByID of the returned id: %v", err)
+		}
+		place, err := b.places.GetByID(ctx, louvreID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if place.ReviewCount != 1 || place.AverageRating != 2 {
+			t.Errorf("place rated %v by %d reviews, want 2 by 1", place.AverageRating, place.ReviewCount)
+		}
+	})
+}
+
+func TestContractUnitOfWork(t *testing.T) {
+	// write creates a user and a place and records a visit, all through
+	// the transaction.
+	write := func(ctx context.Context, tx repository.Repos) error {
+		user := &models.User{Id: bobID, Name: "bob", Email: "bob@example.com", Role: models.RoleMember, Version: 1}
+		if err := tx.Users.Create(ctx, user); err != nil {
+			return err
+		}
+		place := &models.Place{Id: towerID, Name: "Eiffel Tower", Location: models.Location{Latitude: 48.8583701, Longitude: 2.2944813}, Version: 1}
+		if err := tx.Places.Create(ctx, place); err != nil {
+			return err
+		}
+		name := "Renamed"
+		if err := tx.Users.Update(ctx, adaID, &models.UserUpdateRequest{Name: &name}, 0); err != nil {
+			return err
+		}
+		return tx.UserPlaces.AddVisitedPlace(ctx, &models.UserPlace{Id: "00000000-0000-0000-0000-0000000000c1", UserID: adaID, PlaceID: towerID, VisitedAt: time.Now()})
+	}
+
+	tests := []struct {
+		name      string
+		fn        func(ctx context.Context, tx repository.Repos) error
+		panics    bool
+		committed bool
+	}{
+		{"commit", write, false, true},
+		{"error", func(ctx context.Context, tx repository.Repos) error {
+			if err := write(ctx, tx); err != nil {
+				return err
+			}
+			return errRollback
+		}, false, false},
+		{"panic", func(ctx context.Context, tx repository.Repos) error {
+			if err := write(ctx, tx); err != nil {
+				return err
+			}
+			panic(errRollback)
+		}, true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			forEachBackend(t, func(t *testing.T, b backend) {
+				ctx := context.Background()
+				createUser(t, b, adaID, "ada@example.com")
+
+				var err error
+				func() {
+					defer func() {
+						if r := recover(); (r != nil) != tt.panics {
+							t.Errorf("WithTx panicked with %v", r)
+						}
+					}()
+					err = b.uow.WithTx(ctx, func(tx repository.Repos) error { return tt.fn(ctx, tx) })
+				}()
+				if tt.committed && err != nil {
+					t.Fatal(err)
+				}
+				if !tt.committed && !tt.panics {
+					expectErr(t, err, errRollback, "WithTx")
+				}
+
+				_, userErr := b.users.GetByID(ctx, bobID)
+				_, placeErr := b.places.GetByID(ctx, towerID)
+				ada, err := b.users.GetByID(ctx, adaID)
+				if err != nil {
+					t.Fatal(err)
+				}
+				visits, err := b.userPlaces.ListVisits(ctx, adaID, towerID)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				if tt.committed {
+					if userErr != nil || placeErr != nil || ada.Name != "Renamed" || len(visits) != 1 {
+						t.Errorf("after a commit: user %v, place %v, name %q, %d visits; want every write kept", userErr, placeErr, ada.Name, len(visits))
+					}
+					return
+				}
+				expectErr(t, userErr, er.ErrUserNotFound, "GetByID of a user created in a rolled back transaction")
+				expectErr(t, placeErr, er.ErrPlaceNotFound, "GetByID of a place created in a rolled back transaction")
+				if ada.Name == "Renamed" || ada.Version != 1 {
+					t.Errorf("a rolled back update left name %q and version %d", ada.Name, ada.Version)
+				}
+				if len(visits) != 0 {
+					t.Errorf("a rolled back transaction left %d visits", len(visits))
+				}
+			})
+		})
+	}
+}
@@ -0,0 +1,48 @@
+package repository
+
+import (
+	"errors"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"gorm.io/gorm"
+)
+
+// uniqueViolation is the SQLSTATE Postgres reports for a write that breaks
+// a unique constraint or index.
+const uniqueViolation = "23505"
+
+// foreignKeyViolation is the SQLSTATE Postgres reports for a write that
+// references a row that does not exist.
+const foreignKeyViolation = "23503"
+
+// isDuplicate reports whether err comes from a write that broke a unique
+// constraint, either as the raw driver error or as gorm's translation of it.
+func isDuplicate(err error) bool {
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return true
+	}
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == uniqueViolation
+}
+
+// translateDuplicate replaces a unique violation with the repository's
+// domain error for it and passes any other error through.
+func translateDuplicate(err, duplicate error) error {
+	if err != nil && isDuplicate(err) {
+		return duplicate
+	}
+	return err
+}
+
+// translateForeignKey replaces a foreign key violation with the repository's
+// domain error for it and passes any other error through.
+func translateForeignKey(err, missing error) error {
+	if err == nil {
+		return nil
+	}
+	var pgErr *pgconn.PgError
+	if errors.Is(err, gorm.ErrForeignKeyViolated) || errors.As(err, &pgErr) && pgErr.Code == foreignKeyViolation {
+		return missing
+	}
+	return err
+}
@@ -0,0 +1,34 @@
+package repository
+
+import (
+	"math"
+
+	"deu/internal/models"
+)
+
+// earthRadiusMeters matches the sphere used by Postgres' earthdistance
+// extension so both backends report the same distances.
+const earthRadiusMeters = 6378168.0
+
+// haversineMeters returns the great-circle distance between two points.
+func haversineMeters(lat1, lng1, lat2, lng2 float64) float64 {
+	toRad := func(deg float64) float64 { return deg * math.Pi / 180 }
+
+	dLat := toRad(lat2 - lat1)
+	dLng := toRad(lng2 - lng1)
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(toRad(lat1))*math.Cos(toRad(lat2))*math.Sin(dLng/2)*math.Sin(dLng/2)
+	return 2 * earthRadiusMeters * math.Asin(math.Min(1, math.Sqrt(a)))
+}
+
+// inBoundingBox reports whether loc lies inside b, including boxes that
+// cross the antimeridian.
+func inBoundingBox(b models.BoundingBox, loc models.Location) bool {
+	if loc.Latitude < b.MinLat || loc.Latitude > b.MaxLat {
+		return false
+	}
+	if b.MinLng <= b.MaxLng {
+		return loc.Longitude >= b.MinLng && loc.Longitude <= b.MaxLng
+	}
+	return loc.Longitude >= b.MinLng || loc.Longitude <= b.MaxLng
+}
@@ -0,0 +1,98 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	er "deu/internal/errors"
+	"deu/internal/models"
+)
+
+type MemoryAPIKeyRepository struct {
+	mu   sync.RWMutex
+	keys map[string]models.APIKey
+}
+
+func NewMemoryAPIKeyRepository() *MemoryAPIKeyRepository {
+	return &MemoryAPIKeyRepository{
+		keys: make(map[string]models.APIKey),
+	}
+}
+
+func (r *MemoryAPIKeyRepository) Create(ctx context.Context, k *models.APIKey) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.keys[k.Id] = *k
+	return nil
+}
+
+func (r *MemoryAPIKeyRepository) ListByUser(ctx context.Context, userID string) ([]models.APIKey, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	result := make([]models.APIKey, 0)
+	for _, k := range r.keys {
+		if k.UserID == userID {
+			result = append(result, k)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].CreatedAt.After(result[j].CreatedAt)
+	})
+	return result, nil
+}
+
+func (r *MemoryAPIKeyRepository) GetByHash(ctx context.Context, keyHash string) (*models.APIKey, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, k := range r.keys {
+		if k.KeyHash == keyHash {
+			return &k, nil
+		}
+	}
+	return nil, er.ErrAPIKeyNotFound
+}
+
+func (r *MemoryAPIKeyRepository) Delete(ctx context.Context, userID, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	k, ok := r.keys[id]
+	if !ok || k.UserID != userID {
+		return er.ErrAPIKeyNotFound
+	}
+
+	delete(r.keys, id)
+	return nil
+}
+
+func (r *MemoryAPIKeyRepository) TouchLastUsed(ctx context.Context, id string, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	k, ok := r.keys[id]
+	if !ok {
+		return er.ErrAPIKeyNotFound
+	}
+
+	k.LastUsedAt = &at
+	r.keys[id] = k
+	return nil
+}
+
+// forgetUsers removes the API keys of purged users.
+func (r *MemoryAPIKeyRepository) forgetUsers(userIDs []string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	purged := idSet(userIDs)
+	for id, k := range r.keys {
+		if purged[k.UserID] {
+			delete(r.keys, id)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package repository
+
+import (
+	"context"
+	"sync"
+
+	er "deu/internal/errors"
+	"deu/internal/models"
+)
+
+// MemoryAuditRepository keeps entries in the order they were recorded.
+type MemoryAuditRepository struct {
+	mu      sync.RWMutex
+	entries []models.AuditEntry
+}
+
+func NewMemoryAuditRepository() *MemoryAuditRepository {
+	return &MemoryAuditRepository{}
+}
+
+func (r *MemoryAuditRepository) Create(ctx context.Context, e *models.AuditEntry) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.entries = append(r.entries, *e)
+	return nil
+}
+
+func (r *MemoryAuditRepository) GetByID(ctx context.Context, id string) (*models.AuditEntry, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, e := range r.entries {
+		if e.Id == id {
+			return &e, nil
+		}
+	}
+	return nil, er.ErrAuditEntryNotFound
+}
+
+func (r *MemoryAuditRepository) ListByEntity(ctx context.Context, entityType, entityID string, limit, offset int) ([]models.AuditEntry, int64, error) {
+	r.mu.RLock()
+	result := make([]models.AuditEntry, 0)
+	for i := len(r.entries) - 1; i >= 0; i-- {
+		if e := r.entries[i]; e.EntityType == entityType && e.EntityID == entityID {
+			result = append(result, e)
+		}
+	}
+	r.mu.RUnlock()
+
+	return paginate(result, limit, offset), int64(len(result)), nil
+}
+
+// forgetUsers clears the actor of entries recorded by purged users.
+func (r *MemoryAuditRepository) forgetUsers(userIDs []string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	purged := idSet(userIDs)
+	for i, e := range r.entries {
+		if e.ActorID != nil && purged[*e.ActorID] {
+			r.entries[i].ActorID = nil
+		}
+	}
+}
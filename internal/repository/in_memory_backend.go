@@ -0,0 +1,62 @@
+package repository
+
+// MemoryBackend is a complete set of in-memory repositories, for running
+// without a database. They are wired to each other the way the Postgres
+// schema ties its tables together: visits and reviews resolve against users
+// and places, reviews keep place ratings current, and purging a user or
+// place removes or detaches the rows that referred to it.
+type MemoryBackend struct {
+	Users         *MemoryUserRepository
+	Places        *MemoryPlaceRepository
+	UserPlaces    *MemoryUserPlaceRepository
+	Sessions      *MemorySessionRepository
+	RefreshTokens *MemoryRefreshTokenRepository
+	APIKeys       *MemoryAPIKeyRepository
+	Reviews       *MemoryReviewRepository
+	Tags          *MemoryTagRepository
+	Photos        *MemoryPhotoRepository
+	Audit         *MemoryAuditRepository
+	UnitOfWork    *MemoryUnitOfWork
+}
+
+func NewMemoryBackend() *MemoryBackend {
+	b := &MemoryBackend{
+		Users:         NewMemoryUserRepository(),
+		Places:        NewMemoryPlaceRepository(),
+		Sessions:      NewMemorySessionRepository(),
+		RefreshTokens: NewMemoryRefreshTokenRepository(),
+		APIKeys:       NewMemoryAPIKeyRepository(),
+		Photos:        NewMemoryPhotoRepository(),
+		Audit:         NewMemoryAuditRepository(),
+	}
+	b.UserPlaces = NewMemoryUserPlaceRepository(b.Users, b.Places)
+	b.Reviews = NewMemoryReviewRepository(b.Places)
+	b.Tags = NewMemoryTagRepository(b.Places)
+	b.UnitOfWork = NewMemoryUnitOfWork(b.Users, b.Places, b.UserPlaces, b.Reviews)
+
+	// ON DELETE CASCADE and ON DELETE SET NULL of the foreign keys to users.
+	b.Users.onPurge(b.UserPlaces.forgetUsers)
+	b.Users.onPurge(b.Reviews.forgetUsers)
+	b.Users.onPurge(b.Sessions.forgetUsers)
+	b.Users.onPurge(b.RefreshTokens.forgetUsers)
+	b.Users.onPurge(b.APIKeys.forgetUsers)
+	b.Users.onPurge(b.Places.forgetCreators)
+	b.Users.onPurge(b.Photos.forgetUsers)
+	b.Users.onPurge(b.Audit.forgetUsers)
+
+	// And of the foreign keys to places.
+	b.Places.onPurge(b.UserPlaces.forgetPlaces)
+	b.Places.onPurge(b.Reviews.forgetPlaces)
+	b.Places.onPurge(b.Photos.forgetPlaces)
+
+	return b
+}
+
+// idSet turns a list of ids into a set.
+func idSet(ids []string) map[string]bool {
+	set := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		set[id] = true
+	}
+	return set
+}
@@ -0,0 +1,119 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	er "deu/internal/errors"
+	"deu/internal/models"
+)
+
+type MemoryPhotoRepository struct {
+	mu     sync.RWMutex
+	photos map[string]models.Photo
+}
+
+func NewMemoryPhotoRepository() *MemoryPhotoRepository {
+	return &MemoryPhotoRepository{
+		photos: make(map[string]models.Photo),
+	}
+}
+
+func (r *MemoryPhotoRepository) Create(ctx context.Context, p *models.Photo) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.photos[p.Id] = *p
+	return nil
+}
+
+func (r *MemoryPhotoRepository) GetByID(ctx context.Context, id string) (*models.Photo, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	p, ok := r.photos[id]
+	if !ok {
+		return nil, er.ErrPhotoNotFound
+	}
+	return &p, nil
+}
+
+func (r *MemoryPhotoRepository) ListByPlace(ctx context.Context, placeID string, limit, offset int) ([]models.Photo, int64, error) {
+	r.mu.RLock()
+	result := make([]models.Photo, 0)
+	for _, p := range r.photos {
+		if p.PlaceID == placeID {
+			result = append(result, p)
+		}
+	}
+	r.mu.RUnlock()
+
+	sort.Slice(result, func(i, j int) bool {
+		if !result[i].CreatedAt.Equal(result[j].CreatedAt) {
+			return result[i].CreatedAt.After(result[j].CreatedAt)
+		}
+		return result[i].Id < result[j].Id
+	})
+
+	return paginate(result, limit, offset), int64(len(result)), nil
+}
+
+func (r *MemoryPhotoRepository) Delete(ctx context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.photos[id]; !ok {
+		return er.ErrPhotoNotFound
+	}
+
+	delete(r.photos, id)
+	return nil
+}
+
+func (r *MemoryPhotoRepository) DeleteByPlace(ctx context.Context, placeID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for id, p := range r.photos {
+		if p.PlaceID == placeID {
+			delete(r.photos, id)
+		}
+	}
+	return nil
+}
+
+func (r *MemoryPhotoRepository) DeleteAll(ctx context.Context) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.photos = make(map[string]models.Photo)
+	return nil
+}
+
+// forgetUsers clears the uploader of photos uploaded by purged users.
+func (r *MemoryPhotoRepository) forgetUsers(userIDs []string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	purged := idSet(userIDs)
+	for id, p := range r.photos {
+		if p.UploadedBy != nil && purged[*p.UploadedBy] {
+			p.UploadedBy = nil
+			r.photos[id] = p
+		}
+	}
+}
+
+// forgetPlaces removes the photos of purged places.
+func (r *MemoryPhotoRepository) forgetPlaces(placeIDs []string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	purged := idSet(placeIDs)
+	for id, p := range r.photos {
+		if purged[p.PlaceID] {
+			delete(r.photos, id)
+		}
+	}
+}
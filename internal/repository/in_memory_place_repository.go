@@ -1,101 +1,449 @@
-package repository
-
-import (
-	"deu/internal/models"
-	"context"
-    "sync"
-
-    er "deu/internal/errors"
-)
-
-type MemoryPlaceRepository struct {
-    mu      sync.RWMutex
-    places  map[string]models.Place
-}
-
-func NewMemoryPlaceRepository() *MemoryPlaceRepository {
-    return &MemoryPlaceRepository{
-        places: make(map[string]models.Place),
-    }
-}
-
-func (r *MemoryPlaceRepository) GetAll(ctx context.Context) ([]models.Place, error) {
-    r.mu.RLock()
-    defer r.mu.RUnlock()
-
-    result := make([]models.Place, 0, len(r.places))
-    for _, p := range r.places {
-        result = append(result, p)
-    }
-    return result, nil
-}
-
-func (r *MemoryPlaceRepository) GetByID(ctx context.Context, id string) (*models.Place, error) {
-    r.mu.RLock()
-    defer r.mu.RUnlock()
-
-    result, ok := r.places[id]
-    if ok {
-        return &result, nil
-    }
-
-    return nil, er.ErrPlaceNotFound
-}
-
-func (r *MemoryPlaceRepository) Create(ctx context.Context, p *models.Place) error {
-    r.mu.Lock()
-    defer r.mu.Unlock()
-
-    r.places[p.Id] = *p
-    return nil
-}
-
-func (r *MemoryPlaceRepository) Update(ctx context.Context, id string, p *models.PlaceUpdateRequest) error {
-    r.mu.Lock()
-    defer r.mu.Unlock()
-
-    value, ok := r.places[id]
-    if !ok {
-        return er.ErrPlaceNotFound
-    }
-
-    if p.Name != nil {
-        value.Name = *p.Name
-    }
-    if p.Description != nil {
-        value.Description = *p.Description
-    }
-	if p.Location != nil {
-        value.Location = *p.Location
-    }
-	if p.Address != nil {
-        value.Address = *p.Address
-    }
-	if p.Rating != nil {
-        value.Rating = *p.Rating
-    }
-
-    r.places[id] = value
-
-    return nil
-}
-
-func (r *MemoryPlaceRepository) Delete(ctx context.Context, id string) error {
-    r.mu.Lock()
-    defer r.mu.Unlock()
-
-    if _, ok := r.places[id]; !ok {
-        return er.ErrPlaceNotFound
-    }
-
-    delete(r.places, id)
-    return nil
-}
-
-func (r *MemoryPlaceRepository) DeleteAll(ctx context.Context) error {
-    r.mu.Lock()
-    defer r.mu.Unlock()
-
-    r.places = make(map[string]models.Place)
-    return nil
+package repository
+
+import (
+	"deu/internal/models"
+	"context"
+    "maps"
+    "sort"
+    "strings"
+    "sync"
+    "time"
+
+    er "deu/internal/errors"
+
+    "gorm.io/gorm"
+)
+
+type MemoryPlaceRepository struct {
+    mu      sync.RWMutex
+    places  map[string]models.Place
+    index   *searchIndex
+    // cascades are called with the ids of purged places, as the foreign
+    // keys to places cascade in Postgres.
+    cascades []func(ids []string)
+}
+
+func NewMemoryPlaceRepository() *MemoryPlaceRepository {
+    return &MemoryPlaceRepository{
+        places: make(map[string]models.Place),
+        index:  newSearchIndex(),
+    }
+}
+
+// deletedNow marks a record as soft-deleted at the current time.
+func deletedNow() gorm.DeletedAt {
+    return gorm.DeletedAt{Time: time.Now(), Valid: true}
+}
+
+// hasTags reports whether the place carries every one of the slugs.
+func hasTags(p models.Place, slugs []string) bool {
+    for _, slug := range slugs {
+        found := false
+        for _, t := range p.Tags {
+            if t.Slug == slug {
+                found = true
+                break
+            }
+        }
+        if !found {
+            return false
+        }
+    }
+    return true
+}
+
+// matching returns the places that pass the query's filters, unordered.
+func (r *MemoryPlaceRepository) matching(q models.PlaceQuery) []models.Place {
+    r.mu.RLock()
+    defer r.mu.RUnlock()
+
+    prefix := strings.ToLower(q.NamePrefix)
+    result := make([]models.Place, 0, len(r.places))
+    for _, p := range r.places {
+        if p.DeletedAt.Valid {
+            continue
+        }
+        if prefix != "" && !strings.HasPrefix(strings.ToLower(p.Name), prefix) {
+            continue
+        }
+        if q.MinRating != nil && p.AverageRating < *q.MinRating {
+            continue
+        }
+        if q.MaxRating != nil && p.AverageRating > *q.MaxRating {
+            continue
+        }
+        if q.CreatedAfter != nil && !p.CreatedAt.After(*q.CreatedAfter) {
+            continue
+        }
+        if !hasTags(p, q.Tags) {
+            continue
+        }
+        result = append(result, p)
+    }
+    return result
+}
+
+func (r *MemoryPlaceRepository) List(ctx context.Context, q models.PlaceQuery) ([]models.Place, error) {
+    if _, ok := placeSortColumns[q.Sort]; !ok {
+        return nil, er.ErrInvalidQuery
+    }
+
+    return keysetPage(r.matching(q),
+        func(p models.Place) string { return p.SortValue(q.Sort) },
+        func(p models.Place) string { return p.Id },
+        q.Sort, q.Desc, q.Cursor, q.Limit, q.Offset)
+}
+
+func (r *MemoryPlaceRepository) Count(ctx context.Context, q models.PlaceQuery) (int64, error) {
+    return int64(len(r.matching(q))), nil
+}
+
+func (r *MemoryPlaceRepository) Facets(ctx context.Context, q models.PlaceQuery) ([]models.TagFacet, error) {
+    counts := make(map[string]*models.TagFacet)
+    for _, p := range r.matching(q) {
+        for _, t := range p.Tags {
+            if counts[t.Id] == nil {
+                counts[t.Id] = &models.TagFacet{Id: t.Id, Name: t.Name, Slug: t.Slug}
+            }
+            counts[t.Id].Count++
+        }
+    }
+
+    result := make([]models.TagFacet, 0, len(counts))
+    for _, f := range counts {
+        result = append(result, *f)
+    }
+    sort.Slice(result, func(i, j int) bool {
+        if result[i].Count != result[j].Count {
+            return result[i].Count > result[j].Count
+        }
+        return result[i].Name < result[j].Name
+    })
+    return result, nil
+}
+
+// Nearby scans every place and measures it with the haversine formula.
+func (r *MemoryPlaceRepository) Nearby(ctx context.Context, q models.GeoQuery) ([]models.PlaceWithDistance, int64, error) {
+    r.mu.RLock()
+    result := make([]models.PlaceWithDistance, 0)
+    for _, p := range r.places {
+        if p.DeletedAt.Valid {
+            continue
+        }
+        d := haversineMeters(q.Latitude, q.Longitude, p.Location.Latitude, p.Location.Longitude)
+        if q.RadiusMeters > 0 && d > q.RadiusMeters {
+            continue
+        }
+        if q.BBox != nil && !inBoundingBox(*q.BBox, p.Location) {
+            continue
+        }
+        result = append(result, models.PlaceWithDistance{Place: p, DistanceMeters: d})
+    }
+    r.mu.RUnlock()
+
+    sort.Slice(result, func(i, j int) bool {
+        if result[i].DistanceMeters != result[j].DistanceMeters {
+            return result[i].DistanceMeters < result[j].DistanceMeters
+        }
+        return result[i].Id < result[j].Id
+    })
+
+    return paginate(result, q.Limit, q.Offset), int64(len(result)), nil
+}
+
+// Search answers from the inverted index kept current by Create, Update and
+// Delete, so it ranks the same fields the Postgres search vector does.
+func (r *MemoryPlaceRepository) Search(ctx context.Context, q models.SearchQuery) ([]models.PlaceSearchResult, int64, error) {
+    r.mu.RLock()
+    ids, scores := r.index.search(q.Text)
+    result := make([]models.PlaceSearchResult, 0, len(ids))
+    for _, id := range ids {
+        result = append(result, models.PlaceSearchResult{Place: r.places[id], Score: scores[id]})
+    }
+    r.mu.RUnlock()
+
+    return paginate(result, q.Limit, q.Offset), int64(len(result)), nil
+}
+
+func (r *MemoryPlaceRepository) GetByID(ctx context.Context, id string) (*models.Place, error) {
+    r.mu.RLock()
+    defer r.mu.RUnlock()
+
+    result, ok := r.places[id]
+    if ok && !result.DeletedAt.Valid {
+        return &result, nil
+    }
+
+    return nil, er.ErrPlaceNotFound
+}
+
+func (r *MemoryPlaceRepository) Create(ctx context.Context, p *models.Place) error {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+
+    value := *p
+    value.Tags = append(make([]models.Tag, 0, len(p.Tags)), p.Tags...)
+    r.places[p.Id] = value
+    r.index.add(value)
+    return nil
+}
+
+func (r *MemoryPlaceRepository) Update(ctx context.Context, id string, p *models.PlaceUpdateRequest, version int64) error {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+
+    value, ok := r.places[id]
+    if !ok || value.DeletedAt.Valid {
+        return er.ErrPlaceNotFound
+    }
+    if version != 0 && value.Version != version {
+        return er.ErrVersionMismatch
+    }
+
+    if p.Name != nil {
+        value.Name = *p.Name
+    }
+    if p.Description != nil {
+        value.Description = *p.Description
+    }
+	if p.Location != nil {
+        value.Location = *p.Location
+    }
+	if p.Address != nil {
+        value.Address = *p.Address
+    }
+    value.Version++
+    r.places[id] = value
+    r.index.add(value)
+
+    return nil
+}
+
+// SetTags replaces the place's tags. A fresh slice is stored so places
+// already handed out keep their old tags.
+func (r *MemoryPlaceRepository) SetTags(ctx context.Context, placeID string, tags []models.Tag) error {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+
+    value, ok := r.places[placeID]
+    if !ok || value.DeletedAt.Valid {
+        return er.ErrPlaceNotFound
+    }
+
+    value.Tags = append(make([]models.Tag, 0, len(tags)), tags...)
+    r.places[placeID] = value
+    return nil
+}
+
+// replaceTag updates a renamed tag on every place, for MemoryTagRepository.
+func (r *MemoryPlaceRepository) replaceTag(tag models.Tag) {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+
+    for id, p := range r.places {
+        for i, t := range p.Tags {
+            if t.Id == tag.Id {
+                p.Tags = append([]models.Tag(nil), p.Tags...)
+                p.Tags[i] = tag
+                p.Version++
+                r.places[id] = p
+                break
+            }
+        }
+    }
+}
+
+// removeTag detaches a deleted tag from every place, for MemoryTagRepository.
+func (r *MemoryPlaceRepository) removeTag(tagID string) {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+
+    for id, p := range r.places {
+        tags := make([]models.Tag, 0, len(p.Tags))
+        for _, t := range p.Tags {
+            if t.Id != tagID {
+                tags = append(tags, t)
+            }
+        }
+        if len(tags) != len(p.Tags) {
+            p.Tags = tags
+            p.Version++
+            r.places[id] = p
+        }
+    }
+}
+
+// setRating stores the review aggregates maintained by MemoryReviewRepository.
+func (r *MemoryPlaceRepository) setRating(id string, avg float64, count int) {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+
+    value, ok := r.places[id]
+    if !ok {
+        return
+    }
+
+    value.AverageRating = avg
+    value.ReviewCount = count
+    value.Version++
+    r.places[id] = value
+}
+
+func (r *MemoryPlaceRepository) Delete(ctx context.Context, id string, version int64) error {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+
+    value, ok := r.places[id]
+    if !ok || value.DeletedAt.Valid {
+        return er.ErrPlaceNotFound
+    }
+    if version != 0 && value.Version != version {
+        return er.ErrVersionMismatch
+    }
+
+    value.DeletedAt = deletedNow()
+    r.places[id] = value
+    r.index.remove(id)
+    return nil
+}
+
+func (r *MemoryPlaceRepository) DeleteAll(ctx context.Context) ([]string, error) {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+
+    ids := make([]string, 0)
+    for id, p := range r.places {
+        if !p.DeletedAt.Valid {
+            p.DeletedAt = deletedNow()
+            r.places[id] = p
+            ids = append(ids, id)
+        }
+    }
+    r.index = newSearchIndex()
+    return ids, nil
+}
+
+func (r *MemoryPlaceRepository) ListDeleted(ctx context.Context, limit, offset int) ([]models.Place, int64, error) {
+    r.mu.RLock()
+    result := make([]models.Place, 0)
+    for _, p := range r.places {
+        if p.DeletedAt.Valid {
+            result = append(result, p)
+        }
+    }
+    r.mu.RUnlock()
+
+    sort.Slice(result, func(i, j int) bool {
+        if !result[i].DeletedAt.Time.Equal(result[j].DeletedAt.Time) {
+            return result[i].DeletedAt.Time.After(result[j].DeletedAt.Time)
+        }
+        return result[i].Id < result[j].Id
+    })
+
+    return paginate(result, limit, offset), int64(len(result)), nil
+}
+
+func (r *MemoryPlaceRepository) Restore(ctx context.Context, id string) error {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+
+    value, ok := r.places[id]
+    if !ok || !value.DeletedAt.Valid {
+        return er.ErrPlaceNotFound
+    }
+
+    value.DeletedAt = gorm.DeletedAt{}
+    value.Version++
+    r.places[id] = value
+    r.index.add(value)
+    return nil
+}
+
+// Purge also removes what belonged to the purged places; see
+// NewMemoryBackend.
+func (r *MemoryPlaceRepository) Purge(ctx context.Context, before time.Time) ([]string, error) {
+    r.mu.Lock()
+    ids := make([]string, 0)
+    for id, p := range r.places {
+        if p.DeletedAt.Valid && p.DeletedAt.Time.Before(before) {
+            delete(r.places, id)
+            ids = append(ids, id)
+        }
+    }
+    cascades := r.cascades
+    r.mu.Unlock()
+
+    for _, fn := range cascades {
+        fn(ids)
+    }
+    return ids, nil
+}
+
+// onPurge registers fn to be called with the ids of purged places.
+func (r *MemoryPlaceRepository) onPurge(fn func(ids []string)) {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+    r.cascades = append(r.cascades, fn)
+}
+
+// forgetCreators clears created_by on the places of purged users, as
+// ON DELETE SET NULL does in Postgres.
+func (r *MemoryPlaceRepository) forgetCreators(userIDs []string) {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+
+    purged := idSet(userIDs)
+    for id, p := range r.places {
+        if p.CreatedBy != nil && purged[*p.CreatedBy] {
+            p.CreatedBy = nil
+            r.places[id] = p
+        }
+    }
+}
+
+// keep returns a function that puts the places with the given ids back as
+// they are now, or all places when no ids are given, for rolling back a
+// MemoryUnitOfWork.
+func (r *MemoryPlaceRepository) keep(ids ...string) func() {
+    r.mu.RLock()
+    defer r.mu.RUnlock()
+
+    if len(ids) == 0 {
+        saved := maps.Clone(r.places)
+        return func() {
+            r.mu.Lock()
+            defer r.mu.Unlock()
+            r.places = saved
+            r.index = newSearchIndex()
+            for _, p := range saved {
+                if !p.DeletedAt.Valid {
+                    r.index.add(p)
+                }
+            }
+        }
+    }
+
+    saved := make(map[string]models.Place, len(ids))
+    for _, id := range ids {
+        if p, ok := r.places[id]; ok {
+            saved[id] = p
+        }
+    }
+    return func() {
+        r.mu.Lock()
+        defer r.mu.Unlock()
+        for _, id := range ids {
+            p, ok := saved[id]
+            if ok {
+                r.places[id] = p
+            } else {
+                delete(r.places, id)
+            }
+            if ok && !p.DeletedAt.Valid {
+                r.index.add(p)
+            } else {
+                r.index.remove(id)
+            }
+        }
+    }
 }
\ No newline at end of file
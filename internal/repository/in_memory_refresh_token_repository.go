@@ -0,0 +1,86 @@
+package repository
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	er "deu/internal/errors"
+	"deu/internal/models"
+)
+
+type MemoryRefreshTokenRepository struct {
+	mu     sync.RWMutex
+	tokens map[string]models.RefreshToken
+}
+
+func NewMemoryRefreshTokenRepository() *MemoryRefreshTokenRepository {
+	return &MemoryRefreshTokenRepository{
+		tokens: make(map[string]models.RefreshToken),
+	}
+}
+
+func (r *MemoryRefreshTokenRepository) Create(ctx context.Context, t *models.RefreshToken) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.tokens[t.Id] = *t
+	return nil
+}
+
+func (r *MemoryRefreshTokenRepository) GetByID(ctx context.Context, id string) (*models.RefreshToken, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	result, ok := r.tokens[id]
+	if ok {
+		return &result, nil
+	}
+
+	return nil, er.ErrTokenNotFound
+}
+
+func (r *MemoryRefreshTokenRepository) Revoke(ctx context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	token, ok := r.tokens[id]
+	if !ok {
+		return er.ErrTokenNotFound
+	}
+	if token.RevokedAt != nil {
+		return er.ErrTokenRevoked
+	}
+
+	now := time.Now()
+	token.RevokedAt = &now
+	r.tokens[id] = token
+	return nil
+}
+
+func (r *MemoryRefreshTokenRepository) RevokeFamily(ctx context.Context, familyID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	for id, token := range r.tokens {
+		if token.FamilyID == familyID && token.RevokedAt == nil {
+			token.RevokedAt = &now
+			r.tokens[id] = token
+		}
+	}
+	return nil
+}
+
+// forgetUsers removes the refresh tokens of purged users.
+func (r *MemoryRefreshTokenRepository) forgetUsers(userIDs []string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	purged := idSet(userIDs)
+	for id, t := range r.tokens {
+		if purged[t.UserID] {
+			delete(r.tokens, id)
+		}
+	}
+}
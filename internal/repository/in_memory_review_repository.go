@@ -0,0 +1,206 @@
+package repository
+
+import (
+	"context"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	er "deu/internal/errors"
+	"deu/internal/models"
+)
+
+type MemoryReviewRepository struct {
+	mu      sync.RWMutex
+	reviews map[string]models.Review
+	places  *MemoryPlaceRepository
+}
+
+// NewMemoryReviewRepository writes rating aggregates back into the given
+// place repository whenever a review changes.
+func NewMemoryReviewRepository(places *MemoryPlaceRepository) *MemoryReviewRepository {
+	return &MemoryReviewRepository{
+		reviews: make(map[string]models.Review),
+		places:  places,
+	}
+}
+
+// refreshPlaceRating must be called with r.mu held.
+func (r *MemoryReviewRepository) refreshPlaceRating(placeID string) {
+	sum, count := 0, 0
+	for _, rv := range r.reviews {
+		if rv.PlaceID == placeID && !rv.Hidden {
+			sum += rv.Rating
+			count++
+		}
+	}
+
+	avg := 0.0
+	if count > 0 {
+		avg = math.Round(float64(sum)/float64(count)*100) / 100
+	}
+	r.places.setRating(placeID, avg, count)
+}
+
+func (r *MemoryReviewRepository) Save(ctx context.Context, rv *models.Review) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for id, existing := range r.reviews {
+		if existing.PlaceID == rv.PlaceID && existing.UserID == rv.UserID {
+			existing.Rating = rv.Rating
+			existing.Comment = rv.Comment
+			existing.UpdatedAt = rv.UpdatedAt
+			r.reviews[id] = existing
+			*rv = existing
+			r.refreshPlaceRating(rv.PlaceID)
+			return nil
+		}
+	}
+
+	r.reviews[rv.Id] = *rv
+	r.refreshPlaceRating(rv.PlaceID)
+	return nil
+}
+
+func (r *MemoryReviewRepository) GetByID(ctx context.Context, id string) (*models.Review, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	rv, ok := r.reviews[id]
+	if !ok {
+		return nil, er.ErrReviewNotFound
+	}
+	return &rv, nil
+}
+
+func (r *MemoryReviewRepository) GetByUserAndPlace(ctx context.Context, userID, placeID string) (*models.Review, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, rv := range r.reviews {
+		if rv.UserID == userID && rv.PlaceID == placeID {
+			return &rv, nil
+		}
+	}
+	return nil, er.ErrReviewNotFound
+}
+
+func (r *MemoryReviewRepository) ListByPlace(ctx context.Context, placeID string, includeHidden bool, limit, offset int) ([]models.Review, int64, error) {
+	r.mu.RLock()
+	result := make([]models.Review, 0)
+	for _, rv := range r.reviews {
+		if rv.PlaceID == placeID && (includeHidden || !rv.Hidden) {
+			result = append(result, rv)
+		}
+	}
+	r.mu.RUnlock()
+
+	sort.Slice(result, func(i, j int) bool {
+		if !result[i].UpdatedAt.Equal(result[j].UpdatedAt) {
+			return result[i].UpdatedAt.After(result[j].UpdatedAt)
+		}
+		return result[i].Id < result[j].Id
+	})
+
+	return paginate(result, limit, offset), int64(len(result)), nil
+}
+
+func (r *MemoryReviewRepository) SetHidden(ctx context.Context, id string, hidden bool, moderatorID string, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	rv, ok := r.reviews[id]
+	if !ok {
+		return er.ErrReviewNotFound
+	}
+
+	rv.Hidden = hidden
+	rv.HiddenBy = nil
+	rv.HiddenAt = nil
+	if hidden {
+		rv.HiddenBy = &moderatorID
+		rv.HiddenAt = &at
+	}
+	r.reviews[id] = rv
+	r.refreshPlaceRating(rv.PlaceID)
+	return nil
+}
+
+func (r *MemoryReviewRepository) Delete(ctx context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	rv, ok := r.reviews[id]
+	if !ok {
+		return er.ErrReviewNotFound
+	}
+
+	delete(r.reviews, id)
+	r.refreshPlaceRating(rv.PlaceID)
+	return nil
+}
+
+// forgetUsers removes the reviews of purged users and clears them as the
+// moderator of others, then refreshes the ratings of the places concerned.
+func (r *MemoryReviewRepository) forgetUsers(userIDs []string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	purged := idSet(userIDs)
+	changed := make(map[string]bool)
+	for id, rv := range r.reviews {
+		if purged[rv.UserID] {
+			delete(r.reviews, id)
+			changed[rv.PlaceID] = true
+			continue
+		}
+		if rv.HiddenBy != nil && purged[*rv.HiddenBy] {
+			rv.HiddenBy = nil
+			r.reviews[id] = rv
+		}
+	}
+	for placeID := range changed {
+		r.refreshPlaceRating(placeID)
+	}
+}
+
+// forgetPlaces removes the reviews of purged places.
+func (r *MemoryReviewRepository) forgetPlaces(placeIDs []string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	purged := idSet(placeIDs)
+	for id, rv := range r.reviews {
+		if purged[rv.PlaceID] {
+			delete(r.reviews, id)
+		}
+	}
+}
+
+// keep saves the reviews of a place and returns a function that puts them
+// back.
+func (r *MemoryReviewRepository) keep(placeID string) func() {
+	r.mu.RLock()
+	saved := make(map[string]models.Review)
+	for id, rv := range r.reviews {
+		if rv.PlaceID == placeID {
+			saved[id] = rv
+		}
+	}
+	r.mu.RUnlock()
+
+	return func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		for id, rv := range r.reviews {
+			if rv.PlaceID == placeID {
+				delete(r.reviews, id)
+			}
+		}
+		for id, rv := range saved {
+			r.reviews[id] = rv
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package repository
+
+import (
+	"context"
+	"sync"
+
+	er "deu/internal/errors"
+	"deu/internal/models"
+)
+
+type MemorySessionRepository struct {
+	mu       sync.RWMutex
+	sessions map[string]models.Session
+}
+
+func NewMemorySessionRepository() *MemorySessionRepository {
+	return &MemorySessionRepository{
+		sessions: make(map[string]models.Session),
+	}
+}
+
+func (r *MemorySessionRepository) Create(ctx context.Context, s *models.Session) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.sessions[s.Id] = *s
+	return nil
+}
+
+func (r *MemorySessionRepository) GetByID(ctx context.Context, id string) (*models.Session, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	result, ok := r.sessions[id]
+	if ok {
+		return &result, nil
+	}
+
+	return nil, er.ErrSessionNotFound
+}
+
+func (r *MemorySessionRepository) Delete(ctx context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.sessions, id)
+	return nil
+}
+
+func (r *MemorySessionRepository) DeleteByUser(ctx context.Context, userID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for id, s := range r.sessions {
+		if s.UserID == userID {
+			delete(r.sessions, id)
+		}
+	}
+	return nil
+}
+
+// forgetUsers removes the sessions of purged users.
+func (r *MemorySessionRepository) forgetUsers(userIDs []string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	purged := idSet(userIDs)
+	for id, s := range r.sessions {
+		if purged[s.UserID] {
+			delete(r.sessions, id)
+		}
+	}
+}
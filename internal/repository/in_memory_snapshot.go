@@ -0,0 +1,256 @@
+package repository
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"deu/internal/models"
+)
+
+// memorySnapshot is the JSON form of a MemoryBackend. The records carry the
+// fields the models keep out of API responses, such as password hashes.
+type memorySnapshot struct {
+	Users         []userRecord         `json:"users"`
+	Places        []models.Place       `json:"places"`
+	Visits        []models.UserPlace   `json:"visits"`
+	Sessions      []sessionRecord      `json:"sessions"`
+	RefreshTokens []refreshTokenRecord `json:"refreshTokens"`
+	APIKeys       []apiKeyRecord       `json:"apiKeys"`
+	Reviews       []models.Review      `json:"reviews"`
+	Tags          []models.Tag         `json:"tags"`
+	Photos        []photoRecord        `json:"photos"`
+	Audit         []models.AuditEntry  `json:"audit"`
+}
+
+type userRecord struct {
+	models.User
+	PasswordHash string `json:"passwordHash"`
+}
+
+type sessionRecord struct {
+	models.Session
+	Id string `json:"id"`
+}
+
+type refreshTokenRecord struct {
+	models.RefreshToken
+	Id string `json:"id"`
+}
+
+type apiKeyRecord struct {
+	models.APIKey
+	KeyHash string `json:"keyHash"`
+}
+
+type photoRecord struct {
+	models.Photo
+	ThumbnailType string `json:"thumbnailType"`
+	Checksum      string `json:"checksum"`
+}
+
+// Save writes the contents of every repository to path as JSON. The file
+// is replaced atomically, so a crash while saving leaves the previous
+// snapshot intact.
+func (b *MemoryBackend) Save(path string) error {
+	data, err := json.Marshal(b.snapshot())
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Load replaces the contents of every repository with the snapshot at path.
+// A missing file is not an error; the repositories are left empty.
+func (b *MemoryBackend) Load(path string) error {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var s memorySnapshot
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	b.restore(s)
+	return nil
+}
+
+func (b *MemoryBackend) snapshot() memorySnapshot {
+	var s memorySnapshot
+
+	b.Users.mu.RLock()
+	for _, u := range b.Users.users {
+		s.Users = append(s.Users, userRecord{User: u, PasswordHash: u.PasswordHash})
+	}
+	b.Users.mu.RUnlock()
+
+	b.Places.mu.RLock()
+	for _, p := range b.Places.places {
+		s.Places = append(s.Places, p)
+	}
+	b.Places.mu.RUnlock()
+
+	b.UserPlaces.mu.RLock()
+	for _, places := range b.UserPlaces.visitedMap {
+		for _, visits := range places {
+			s.Visits = append(s.Visits, visits...)
+		}
+	}
+	b.UserPlaces.mu.RUnlock()
+
+	b.Sessions.mu.RLock()
+	for _, session := range b.Sessions.sessions {
+		s.Sessions = append(s.Sessions, sessionRecord{Session: session, Id: session.Id})
+	}
+	b.Sessions.mu.RUnlock()
+
+	b.RefreshTokens.mu.RLock()
+	for _, t := range b.RefreshTokens.tokens {
+		s.RefreshTokens = append(s.RefreshTokens, refreshTokenRecord{RefreshToken: t, Id: t.Id})
+	}
+	b.RefreshTokens.mu.RUnlock()
+
+	b.APIKeys.mu.RLock()
+	for _, k := range b.APIKeys.keys {
+		s.APIKeys = append(s.APIKeys, apiKeyRecord{APIKey: k, KeyHash: k.KeyHash})
+	}
+	b.APIKeys.mu.RUnlock()
+
+	b.Reviews.mu.RLock()
+	for _, rv := range b.Reviews.reviews {
+		s.Reviews = append(s.Reviews, rv)
+	}
+	b.Reviews.mu.RUnlock()
+
+	b.Tags.mu.RLock()
+	for _, t := range b.Tags.tags {
+		s.Tags = append(s.Tags, t)
+	}
+	b.Tags.mu.RUnlock()
+
+	b.Photos.mu.RLock()
+	for _, p := range b.Photos.photos {
+		s.Photos = append(s.Photos, photoRecord{Photo: p, ThumbnailType: p.ThumbnailType, Checksum: p.Checksum})
+	}
+	b.Photos.mu.RUnlock()
+
+	b.Audit.mu.RLock()
+	s.Audit = append(s.Audit, b.Audit.entries...)
+	b.Audit.mu.RUnlock()
+
+	return s
+}
+
+func (b *MemoryBackend) restore(s memorySnapshot) {
+	b.Users.mu.Lock()
+	b.Users.users = make(map[string]models.User, len(s.Users))
+	for _, rec := range s.Users {
+		u := rec.User
+		u.PasswordHash = rec.PasswordHash
+		b.Users.users[u.Id] = u
+	}
+	b.Users.mu.Unlock()
+
+	b.Places.mu.Lock()
+	b.Places.places = make(map[string]models.Place, len(s.Places))
+	b.Places.index = newSearchIndex()
+	for _, p := range s.Places {
+		b.Places.places[p.Id] = p
+		if !p.DeletedAt.Valid {
+			b.Places.index.add(p)
+		}
+	}
+	b.Places.mu.Unlock()
+
+	b.UserPlaces.mu.Lock()
+	b.UserPlaces.visitedMap = make(map[string]map[string][]models.UserPlace)
+	for _, v := range s.Visits {
+		if b.UserPlaces.visitedMap[v.UserID] == nil {
+			b.UserPlaces.visitedMap[v.UserID] = make(map[string][]models.UserPlace)
+		}
+		b.UserPlaces.visitedMap[v.UserID][v.PlaceID] = append(b.UserPlaces.visitedMap[v.UserID][v.PlaceID], v)
+	}
+	b.UserPlaces.mu.Unlock()
+
+	b.Sessions.mu.Lock()
+	b.Sessions.sessions = make(map[string]models.Session, len(s.Sessions))
+	for _, rec := range s.Sessions {
+		session := rec.Session
+		session.Id = rec.Id
+		b.Sessions.sessions[session.Id] = session
+	}
+	b.Sessions.mu.Unlock()
+
+	b.RefreshTokens.mu.Lock()
+	b.RefreshTokens.tokens = make(map[string]models.RefreshToken, len(s.RefreshTokens))
+	for _, rec := range s.RefreshTokens {
+		t := rec.RefreshToken
+		t.Id = rec.Id
+		b.RefreshTokens.tokens[t.Id] = t
+	}
+	b.RefreshTokens.mu.Unlock()
+
+	b.APIKeys.mu.Lock()
+	b.APIKeys.keys = make(map[string]models.APIKey, len(s.APIKeys))
+	for _, rec := range s.APIKeys {
+		k := rec.APIKey
+		k.KeyHash = rec.KeyHash
+		b.APIKeys.keys[k.Id] = k
+	}
+	b.APIKeys.mu.Unlock()
+
+	b.Reviews.mu.Lock()
+	b.Reviews.reviews = make(map[string]models.Review, len(s.Reviews))
+	for _, rv := range s.Reviews {
+		b.Reviews.reviews[rv.Id] = rv
+	}
+	b.Reviews.mu.Unlock()
+
+	b.Tags.mu.Lock()
+	b.Tags.tags = make(map[string]models.Tag, len(s.Tags))
+	for _, t := range s.Tags {
+		b.Tags.tags[t.Id] = t
+	}
+	b.Tags.mu.Unlock()
+
+	b.Photos.mu.Lock()
+	b.Photos.photos = make(map[string]models.Photo, len(s.Photos))
+	for _, rec := range s.Photos {
+		p := rec.Photo
+		p.ThumbnailType = rec.ThumbnailType
+		p.Checksum = rec.Checksum
+		b.Photos.photos[p.Id] = p
+	}
+	b.Photos.mu.Unlock()
+
+	b.Audit.mu.Lock()
+	b.Audit.entries = s.Audit
+	b.Audit.mu.Unlock()
+}
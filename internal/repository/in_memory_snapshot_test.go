@@ -0,0 +1,93 @@
+package repository
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"deu/internal/models"
+)
+
+// fillBackend stores a user who visited a place and is signed in.
+func fillBackend(t *testing.T, b *MemoryBackend) {
+	t.Helper()
+	ctx := context.Background()
+	user := models.User{Id: "u1", Name: "Ada", Email: "ada@example.com", PasswordHash: "hash", Role: models.RoleMember, Version: 1}
+	if err := b.Users.Create(ctx, &user); err != nil {
+		t.Fatal(err)
+	}
+	place := models.Place{Id: "p1", Name: "Louvre Museum", Address: "Rue de Rivoli", Version: 1}
+	if err := b.Places.Create(ctx, &place); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.UserPlaces.AddVisitedPlace(ctx, &models.UserPlace{Id: "v1", UserID: "u1", PlaceID: "p1", VisitedAt: time.Now()}); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Sessions.Create(ctx, &models.Session{Id: "s1", UserID: "u1", ExpiresAt: time.Now().Add(time.Hour)}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMemorySnapshotRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	saved := NewMemoryBackend()
+	fillBackend(t, saved)
+
+	path := filepath.Join(t.TempDir(), "data", "snapshot.json")
+	if err := saved.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	loaded := NewMemoryBackend()
+	if err := loaded.Load(path); err != nil {
+		t.Fatal(err)
+	}
+
+	user, err := loaded.Users.GetByEmail(ctx, "ada@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.Id != "u1" || user.PasswordHash != "hash" {
+		t.Errorf("loaded user %s with password hash %q, want u1 with %q", user.Id, user.PasswordHash, "hash")
+	}
+	if place, err := loaded.Places.GetByID(ctx, "p1"); err != nil || place.Name != "Louvre Museum" {
+		t.Errorf("GetByID(p1) = %+v, %v, want the Louvre", place, err)
+	}
+	expectSearch(t, loaded.Places, "louvre", "p1")
+	if visits, err := loaded.UserPlaces.ListVisits(ctx, "u1", "p1"); err != nil || len(visits) != 1 {
+		t.Errorf("ListVisits = %v, %v, want the one visit", visits, err)
+	}
+	if _, err := loaded.Sessions.GetByID(ctx, "s1"); err != nil {
+		t.Errorf("session: %v", err)
+	}
+}
+
+func TestMemoryLoadWithoutSnapshot(t *testing.T) {
+	b := NewMemoryBackend()
+	if err := b.Load(filepath.Join(t.TempDir(), "missing.json")); err != nil {
+		t.Fatalf("Load of a missing file returned %v, want nil", err)
+	}
+}
+
+func TestMemoryPurgeCascades(t *testing.T) {
+	ctx := context.Background()
+	b := NewMemoryBackend()
+	fillBackend(t, b)
+
+	if err := b.Users.Delete(ctx, "u1", 0); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := b.Users.Purge(ctx, time.Now().Add(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+
+	if visits, _ := b.UserPlaces.ListVisits(ctx, "u1", "p1"); len(visits) != 0 {
+		t.Errorf("%d visits left after purging their user", len(visits))
+	}
+	if _, err := b.Sessions.GetByID(ctx, "s1"); err == nil {
+		t.Error("session left after purging its user")
+	}
+	if _, err := b.Places.GetByID(ctx, "p1"); err != nil {
+		t.Errorf("purging a user removed the place they visited: %v", err)
+	}
+}
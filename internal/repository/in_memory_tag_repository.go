@@ -0,0 +1,131 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	er "deu/internal/errors"
+	"deu/internal/models"
+)
+
+type MemoryTagRepository struct {
+	mu     sync.RWMutex
+	tags   map[string]models.Tag
+	places *MemoryPlaceRepository
+}
+
+// NewMemoryTagRepository propagates renamed and deleted tags to the places
+// of the given place repository.
+func NewMemoryTagRepository(places *MemoryPlaceRepository) *MemoryTagRepository {
+	return &MemoryTagRepository{
+		tags:   make(map[string]models.Tag),
+		places: places,
+	}
+}
+
+func sortTags(tags []models.Tag) {
+	sort.Slice(tags, func(i, j int) bool {
+		if tags[i].Name != tags[j].Name {
+			return tags[i].Name < tags[j].Name
+		}
+		return tags[i].Id < tags[j].Id
+	})
+}
+
+func (r *MemoryTagRepository) List(ctx context.Context) ([]models.Tag, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	result := make([]models.Tag, 0, len(r.tags))
+	for _, t := range r.tags {
+		result = append(result, t)
+	}
+	sortTags(result)
+	return result, nil
+}
+
+func (r *MemoryTagRepository) GetByID(ctx context.Context, id string) (*models.Tag, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	t, ok := r.tags[id]
+	if !ok {
+		return nil, er.ErrTagNotFound
+	}
+	return &t, nil
+}
+
+func (r *MemoryTagRepository) GetBySlugs(ctx context.Context, slugs []string) ([]models.Tag, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	wanted := make(map[string]bool, len(slugs))
+	for _, s := range slugs {
+		wanted[s] = true
+	}
+
+	result := make([]models.Tag, 0, len(slugs))
+	for _, t := range r.tags {
+		if wanted[t.Slug] {
+			result = append(result, t)
+		}
+	}
+	sortTags(result)
+	return result, nil
+}
+
+// slugTaken reports whether a tag other than exceptID has the slug, like
+// the unique constraint in Postgres. r.mu must be held.
+func (r *MemoryTagRepository) slugTaken(slug, exceptID string) bool {
+	for id, t := range r.tags {
+		if id != exceptID && t.Slug == slug {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *MemoryTagRepository) Create(ctx context.Context, t *models.Tag) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.slugTaken(t.Slug, t.Id) {
+		return er.ErrTagExists
+	}
+
+	r.tags[t.Id] = *t
+	return nil
+}
+
+func (r *MemoryTagRepository) Update(ctx context.Context, t *models.Tag) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	value, ok := r.tags[t.Id]
+	if !ok {
+		return er.ErrTagNotFound
+	}
+	if r.slugTaken(t.Slug, t.Id) {
+		return er.ErrTagExists
+	}
+
+	value.Name = t.Name
+	value.Slug = t.Slug
+	r.tags[t.Id] = value
+	r.places.replaceTag(value)
+	return nil
+}
+
+func (r *MemoryTagRepository) Delete(ctx context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.tags[id]; !ok {
+		return er.ErrTagNotFound
+	}
+
+	delete(r.tags, id)
+	r.places.removeTag(id)
+	return nil
+}
@@ -0,0 +1,200 @@
+package repository
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"deu/internal/models"
+)
+
+// MemoryUnitOfWork runs transactions over the in-memory repositories one at
+// a time. Every write made through a transaction's repositories is undone if
+// it fails. Calls made outside WithTx are not held back by a transaction, so
+// services run every multi-step write, and every delete of a user or place,
+// through WithTx.
+type MemoryUnitOfWork struct {
+	mu      sync.Mutex
+	users   *MemoryUserRepository
+	places  *MemoryPlaceRepository
+	visits  *MemoryUserPlaceRepository
+	reviews *MemoryReviewRepository
+}
+
+func NewMemoryUnitOfWork(users *MemoryUserRepository, places *MemoryPlaceRepository, visits *MemoryUserPlaceRepository, reviews *MemoryReviewRepository) *MemoryUnitOfWork {
+	return &MemoryUnitOfWork{users: users, places: places, visits: visits, reviews: reviews}
+}
+
+func (u *MemoryUnitOfWork) WithTx(ctx context.Context, fn func(tx Repos) error) error {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	tx := &memoryTx{}
+	defer func() {
+		if p := recover(); p != nil {
+			tx.rollback()
+			panic(p)
+		}
+	}()
+
+	err := fn(Repos{
+		Users:      memoryTxUsers{u.users, tx},
+		Places:     memoryTxPlaces{u.places, tx},
+		UserPlaces: memoryTxUserPlaces{u.visits, tx},
+		Reviews:    memoryTxReviews{u.reviews, tx},
+	})
+	if err != nil {
+		tx.rollback()
+	}
+	return err
+}
+
+// memoryTx collects what is needed to undo a transaction's writes.
+type memoryTx struct {
+	undo []func()
+}
+
+func (tx *memoryTx) keep(restore func()) {
+	tx.undo = append(tx.undo, restore)
+}
+
+func (tx *memoryTx) rollback() {
+	for i := len(tx.undo) - 1; i >= 0; i-- {
+		tx.undo[i]()
+	}
+}
+
+// memoryTxUsers saves the users each write is about to change.
+type memoryTxUsers struct {
+	*MemoryUserRepository
+	tx *memoryTx
+}
+
+func (r memoryTxUsers) Create(ctx context.Context, u *models.User) error {
+	r.tx.keep(r.keep(u.Id))
+	return r.MemoryUserRepository.Create(ctx, u)
+}
+
+func (r memoryTxUsers) Update(ctx context.Context, id string, u *models.UserUpdateRequest, version int64) error {
+	r.tx.keep(r.keep(id))
+	return r.MemoryUserRepository.Update(ctx, id, u, version)
+}
+
+func (r memoryTxUsers) SetRole(ctx context.Context, id string, role string) error {
+	r.tx.keep(r.keep(id))
+	return r.MemoryUserRepository.SetRole(ctx, id, role)
+}
+
+func (r memoryTxUsers) Delete(ctx context.Context, id string, version int64) error {
+	r.tx.keep(r.keep(id))
+	return r.MemoryUserRepository.Delete(ctx, id, version)
+}
+
+func (r memoryTxUsers) DeleteAll(ctx context.Context) ([]string, error) {
+	r.tx.keep(r.keep())
+	return r.MemoryUserRepository.DeleteAll(ctx)
+}
+
+func (r memoryTxUsers) Restore(ctx context.Context, id string) error {
+	r.tx.keep(r.keep(id))
+	return r.MemoryUserRepository.Restore(ctx, id)
+}
+
+func (r memoryTxUsers) Purge(ctx context.Context, before time.Time) ([]string, error) {
+	r.tx.keep(r.keep())
+	return r.MemoryUserRepository.Purge(ctx, before)
+}
+
+// memoryTxPlaces saves the places each write is about to change.
+type memoryTxPlaces struct {
+	*MemoryPlaceRepository
+	tx *memoryTx
+}
+
+func (r memoryTxPlaces) Create(ctx context.Context, p *models.Place) error {
+	r.tx.keep(r.keep(p.Id))
+	return r.MemoryPlaceRepository.Create(ctx, p)
+}
+
+func (r memoryTxPlaces) Update(ctx context.Context, id string, p *models.PlaceUpdateRequest, version int64) error {
+	r.tx.keep(r.keep(id))
+	return r.MemoryPlaceRepository.Update(ctx, id, p, version)
+}
+
+func (r memoryTxPlaces) SetTags(ctx context.Context, placeID string, tags []models.Tag) error {
+	r.tx.keep(r.keep(placeID))
+	return r.MemoryPlaceRepository.SetTags(ctx, placeID, tags)
+}
+
+func (r memoryTxPlaces) Delete(ctx context.Context, id string, version int64) error {
+	r.tx.keep(r.keep(id))
+	return r.MemoryPlaceRepository.Delete(ctx, id, version)
+}
+
+func (r memoryTxPlaces) DeleteAll(ctx context.Context) ([]string, error) {
+	r.tx.keep(r.keep())
+	return r.MemoryPlaceRepository.DeleteAll(ctx)
+}
+
+func (r memoryTxPlaces) Restore(ctx context.Context, id string) error {
+	r.tx.keep(r.keep(id))
+	return r.MemoryPlaceRepository.Restore(ctx, id)
+}
+
+func (r memoryTxPlaces) Purge(ctx context.Context, before time.Time) ([]string, error) {
+	r.tx.keep(r.keep())
+	return r.MemoryPlaceRepository.Purge(ctx, before)
+}
+
+// memoryTxUserPlaces saves the visits of the user each write is about to
+// change.
+type memoryTxUserPlaces struct {
+	*MemoryUserPlaceRepository
+	tx *memoryTx
+}
+
+func (r memoryTxUserPlaces) AddVisitedPlace(ctx context.Context, visit *models.UserPlace) error {
+	r.tx.keep(r.keep(visit.UserID))
+	return r.MemoryUserPlaceRepository.AddVisitedPlace(ctx, visit)
+}
+
+func (r memoryTxUserPlaces) RemoveVisitedPlace(ctx context.Context, userID, placeID string) error {
+	r.tx.keep(r.keep(userID))
+	return r.MemoryUserPlaceRepository.RemoveVisitedPlace(ctx, userID, placeID)
+}
+
+func (r memoryTxUserPlaces) RemoveVisit(ctx context.Context, userID, visitID string) error {
+	r.tx.keep(r.keep(userID))
+	return r.MemoryUserPlaceRepository.RemoveVisit(ctx, userID, visitID)
+}
+
+// memoryTxReviews saves the reviews and rating of the place each write is
+// about to change.
+type memoryTxReviews struct {
+	*MemoryReviewRepository
+	tx *memoryTx
+}
+
+func (r memoryTxReviews) keepPlace(placeID string) {
+	r.tx.keep(r.places.keep(placeID))
+	r.tx.keep(r.keep(placeID))
+}
+
+func (r memoryTxReviews) Save(ctx context.Context, rv *models.Review) error {
+	r.keepPlace(rv.PlaceID)
+	return r.MemoryReviewRepository.Save(ctx, rv)
+}
+
+func (r memoryTxReviews) SetHidden(ctx context.Context, id string, hidden bool, moderatorID string, at time.Time) error {
+	if rv, err := r.GetByID(ctx, id); err == nil {
+		r.keepPlace(rv.PlaceID)
+	}
+	return r.MemoryReviewRepository.SetHidden(ctx, id, hidden, moderatorID, at)
+}
+
+func (r memoryTxReviews) Delete(ctx context.Context, id string) error {
+	if rv, err := r.GetByID(ctx, id); err == nil {
+		r.keepPlace(rv.PlaceID)
+	}
+	return r.MemoryReviewRepository.Delete(ctx, id)
+}
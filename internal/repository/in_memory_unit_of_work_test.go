@@ -0,0 +1,68 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"deu/internal/models"
+)
+
+func TestMemoryUnitOfWorkRollbackRestoresReviews(t *testing.T) {
+	ctx := context.Background()
+	review := func(id, userID string, rating int) *models.Review {
+		return &models.Review{Id: id, PlaceID: "p1", UserID: userID, Rating: rating, UpdatedAt: time.Now()}
+	}
+
+	tests := []struct {
+		name string
+		fn   func(tx Repos) error
+	}{
+		{"save", func(tx Repos) error { return tx.Reviews.Save(ctx, review("r2", "u2", 1)) }},
+		{"edit", func(tx Repos) error { return tx.Reviews.Save(ctx, review("r3", "u1", 1)) }},
+		{"hide", func(tx Repos) error { return tx.Reviews.SetHidden(ctx, "r1", true, "u2", time.Now()) }},
+		{"delete", func(tx Repos) error { return tx.Reviews.Delete(ctx, "r1") }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := NewMemoryBackend()
+			if err := b.Places.Create(ctx, &models.Place{Id: "p1", Name: "Louvre Museum", Version: 1}); err != nil {
+				t.Fatal(err)
+			}
+			if err := b.Reviews.Save(ctx, review("r1", "u1", 5)); err != nil {
+				t.Fatal(err)
+			}
+			before, err := b.Places.GetByID(ctx, "p1")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = b.UnitOfWork.WithTx(ctx, func(tx Repos) error {
+				if err := tt.fn(tx); err != nil {
+					t.Fatal(err)
+				}
+				return errRollback
+			})
+			if !errors.Is(err, errRollback) {
+				t.Fatalf("WithTx returned %v, want the callback's error", err)
+			}
+
+			reviews, _, err := b.Reviews.ListByPlace(ctx, "p1", true, 10, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(reviews) != 1 || reviews[0].Id != "r1" || reviews[0].Rating != 5 || reviews[0].Hidden {
+				t.Errorf("reviews after rollback: %+v, want r1 as it was", reviews)
+			}
+			after, err := b.Places.GetByID(ctx, "p1")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if after.AverageRating != before.AverageRating || after.ReviewCount != before.ReviewCount || after.Version != before.Version {
+				t.Errorf("place after rollback: rating %v of %d at version %d, want %v of %d at %d",
+					after.AverageRating, after.ReviewCount, after.Version, before.AverageRating, before.ReviewCount, before.Version)
+			}
+		})
+	}
+}
@@ -1,45 +1,194 @@
-package repository
-
-import (
-	"context"
-	"sync"
-)
-
-
-type MemoryUserPlaceRepository struct {
-	visitedMap map[string]map[string]bool
-	mu         sync.RWMutex
-}
-
-func NewMemoryUserPlaceRepository() *MemoryUserPlaceRepository {
-	return &MemoryUserPlaceRepository{
-		visitedMap: make(map[string]map[string]bool),
-	}
-}
-
-func (r *MemoryUserPlaceRepository) AddVisitedPlace(ctx context.Context, userID, placeID string) error {
-	r.mu.Lock()
-	defer r.mu.Unlock()
-
-	if r.visitedMap[userID] == nil {
-		r.visitedMap[userID] = make(map[string]bool)
-	}
-
-	r.visitedMap[userID][placeID] = true
-	
-	return nil
-}
-
-func (r *MemoryUserPlaceRepository) HasVisitedPlace(ctx context.Context, userID, placeID string) (bool, error) {
-	r.mu.RLock()
-	defer r.mu.RUnlock()
-
-	places, ok := r.visitedMap[userID]
-	if !ok {
-		return false, nil
-	}
-
-	_, visited := places[placeID]
-	
-	return visited, nil
+package repository
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	er "deu/internal/errors"
+	"deu/internal/models"
+)
+
+
+type MemoryUserPlaceRepository struct {
+	visitedMap map[string]map[string][]models.UserPlace
+	mu         sync.RWMutex
+	users      *MemoryUserRepository
+	places     *MemoryPlaceRepository
+}
+
+// NewMemoryUserPlaceRepository keeps visits keyed by user and place id and
+// resolves them against the given user and place repositories when listing.
+func NewMemoryUserPlaceRepository(users *MemoryUserRepository, places *MemoryPlaceRepository) *MemoryUserPlaceRepository {
+	return &MemoryUserPlaceRepository{
+		visitedMap: make(map[string]map[string][]models.UserPlace),
+		users:      users,
+		places:     places,
+	}
+}
+
+func (r *MemoryUserPlaceRepository) AddVisitedPlace(ctx context.Context, visit *models.UserPlace) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.visitedMap[visit.UserID] == nil {
+		r.visitedMap[visit.UserID] = make(map[string][]models.UserPlace)
+	}
+
+	r.visitedMap[visit.UserID][visit.PlaceID] = append(r.visitedMap[visit.UserID][visit.PlaceID], *visit)
+	
+	return nil
+}
+
+func (r *MemoryUserPlaceRepository) ListVisits(ctx context.Context, userID, placeID string) ([]models.UserPlace, error) {
+	r.mu.RLock()
+	visits := append([]models.UserPlace{}, r.visitedMap[userID][placeID]...)
+	r.mu.RUnlock()
+
+	sort.Slice(visits, func(i, j int) bool {
+		if !visits[i].VisitedAt.Equal(visits[j].VisitedAt) {
+			return visits[i].VisitedAt.After(visits[j].VisitedAt)
+		}
+		return visits[i].Id < visits[j].Id
+	})
+	
+	return visits, nil
+}
+
+// RemoveVisitedPlace forgets every visit of the user to the place.
+func (r *MemoryUserPlaceRepository) RemoveVisitedPlace(ctx context.Context, userID, placeID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.visitedMap[userID], placeID)
+	return nil
+}
+
+func (r *MemoryUserPlaceRepository) RemoveVisit(ctx context.Context, userID, visitID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for placeID, visits := range r.visitedMap[userID] {
+		for i, visit := range visits {
+			if visit.Id != visitID {
+				continue
+			}
+			visits = append(visits[:i], visits[i+1:]...)
+			if len(visits) == 0 {
+				delete(r.visitedMap[userID], placeID)
+			} else {
+				r.visitedMap[userID][placeID] = visits
+			}
+			return nil
+		}
+	}
+
+	return er.ErrVisitNotFound
+}
+
+func (r *MemoryUserPlaceRepository) ListVisitedPlaces(ctx context.Context, userID string, limit, offset int) ([]models.Place, int64, error) {
+	r.mu.RLock()
+	placeIDs := make([]string, 0, len(r.visitedMap[userID]))
+	for placeID := range r.visitedMap[userID] {
+		placeIDs = append(placeIDs, placeID)
+	}
+	r.mu.RUnlock()
+
+	result := make([]models.Place, 0, len(placeIDs))
+	for _, placeID := range placeIDs {
+		place, err := r.places.GetByID(ctx, placeID)
+		if err != nil {
+			continue
+		}
+		result = append(result, *place)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Name != result[j].Name {
+			return result[i].Name < result[j].Name
+		}
+		return result[i].Id < result[j].Id
+	})
+
+	return paginate(result, limit, offset), int64(len(result)), nil
+}
+
+func (r *MemoryUserPlaceRepository) ListVisitors(ctx context.Context, placeID string, limit, offset int) ([]models.User, int64, error) {
+	r.mu.RLock()
+	userIDs := make([]string, 0)
+	for userID, places := range r.visitedMap {
+		if len(places[placeID]) > 0 {
+			userIDs = append(userIDs, userID)
+		}
+	}
+	r.mu.RUnlock()
+
+	result := make([]models.User, 0, len(userIDs))
+	for _, userID := range userIDs {
+		user, err := r.users.GetByID(ctx, userID)
+		if err != nil {
+			continue
+		}
+		result = append(result, *user)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Name != result[j].Name {
+			return result[i].Name < result[j].Name
+		}
+		return result[i].Id < result[j].Id
+	})
+
+	return paginate(result, limit, offset), int64(len(result)), nil
+}
+
+// keep returns a function that puts the user's visits back as they are now,
+// for rolling back a MemoryUnitOfWork.
+func (r *MemoryUserPlaceRepository) keep(userID string) func() {
+	r.mu.RLock()
+	saved := make(map[string][]models.UserPlace, len(r.visitedMap[userID]))
+	for placeID, visits := range r.visitedMap[userID] {
+		saved[placeID] = append([]models.UserPlace(nil), visits...)
+	}
+	r.mu.RUnlock()
+
+	return func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.visitedMap[userID] = saved
+	}
+}
+
+// paginate returns the limit-sized window of items starting at offset.
+func paginate[T any](items []T, limit, offset int) []T {
+	if offset >= len(items) {
+		return []T{}
+	}
+	end := len(items)
+	if limit > 0 && offset+limit < end {
+		end = offset + limit
+	}
+	return items[offset:end]
+}
+
+// forgetUsers removes the visits of purged users.
+func (r *MemoryUserPlaceRepository) forgetUsers(userIDs []string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, id := range userIDs {
+		delete(r.visitedMap, id)
+	}
+}
+
+// forgetPlaces removes the visits to purged places.
+func (r *MemoryUserPlaceRepository) forgetPlaces(placeIDs []string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, places := range r.visitedMap {
+		for _, id := range placeIDs {
+			delete(places, id)
+		}
+	}
 }
\ No newline at end of file
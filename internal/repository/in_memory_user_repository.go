@@ -1,92 +1,292 @@
-package repository
-
-import (
-	"deu/internal/models"
-	"context"
-    "sync"
-
-    er "deu/internal/errors"
-)
-
-type MemoryUserRepository struct {
-    mu      sync.RWMutex
-    users  map[string]models.User
-}
-
-func NewMemoryUserRepository() *MemoryUserRepository {
-    return &MemoryUserRepository{
-        users: make(map[string]models.User),
-    }
-}
-
-func (r *MemoryUserRepository) GetAll(ctx context.Context) ([]models.User, error) {
-    r.mu.RLock()
-    defer r.mu.RUnlock()
-
-    result := make([]models.User, 0, len(r.users))
-    for _, u := range r.users {
-        result = append(result, u)
-    }
-    return result, nil
-}
-
-func (r *MemoryUserRepository) GetByID(ctx context.Context, id string) (*models.User, error) {
-    r.mu.RLock()
-    defer r.mu.RUnlock()
-
-    result, ok := r.users[id]
-    if ok {
-        return &result, nil
-    }
-
-    return nil, er.ErrUserNotFound
-}
-
-func (r *MemoryUserRepository) Create(ctx context.Context, u *models.User) error {
-    r.mu.Lock()
-    defer r.mu.Unlock()
-
-    r.users[u.Id] = *u
-    return nil
-}
-
-func (r *MemoryUserRepository) Update(ctx context.Context, id string, u *models.UserUpdateRequest) error { //impove
-    r.mu.Lock()
-    defer r.mu.Unlock()
-
-    value, ok := r.users[id]
-    if !ok {
-        return er.ErrUserNotFound
-    }
-
-    if u.Name != nil {
-        value.Name = *u.Name
-    }
-    if u.Email != nil {
-        value.Email = *u.Email
-    }
-
-    r.users[id] = value
-
-    return nil
-}
-
-func (r *MemoryUserRepository) Delete(ctx context.Context, id string) error {
-    r.mu.Lock()
-    defer r.mu.Unlock()
-
-    if _, ok := r.users[id]; !ok {
-        return er.ErrUserNotFound
-    }
-
-    delete(r.users, id)
-    return nil
-}
-
-func (r *MemoryUserRepository) DeleteAll(ctx context.Context) error {
-    r.mu.Lock()
-    defer r.mu.Unlock()
-
-    r.users = make(map[string]models.User)
-    return nil
+package repository
+
+import (
+	"deu/internal/models"
+	"context"
+    "maps"
+    "sort"
+    "strings"
+    "sync"
+    "time"
+
+    er "deu/internal/errors"
+
+    "gorm.io/gorm"
+)
+
+type MemoryUserRepository struct {
+    mu      sync.RWMutex
+    users  map[string]models.User
+    // cascades are called with the ids of purged users, as the foreign
+    // keys to users cascade in Postgres.
+    cascades []func(ids []string)
+}
+
+func NewMemoryUserRepository() *MemoryUserRepository {
+    return &MemoryUserRepository{
+        users: make(map[string]models.User),
+    }
+}
+
+// matching returns the users that pass the query's filters, unordered.
+func (r *MemoryUserRepository) matching(q models.UserQuery) []models.User {
+    r.mu.RLock()
+    defer r.mu.RUnlock()
+
+    name, email := strings.ToLower(q.Name), strings.ToLower(q.Email)
+    result := make([]models.User, 0, len(r.users))
+    for _, u := range r.users {
+        if u.DeletedAt.Valid {
+            continue
+        }
+        if name != "" && !strings.Contains(strings.ToLower(u.Name), name) {
+            continue
+        }
+        if email != "" && !strings.Contains(strings.ToLower(u.Email), email) {
+            continue
+        }
+        result = append(result, u)
+    }
+    return result
+}
+
+func (r *MemoryUserRepository) List(ctx context.Context, q models.UserQuery) ([]models.User, error) {
+    if _, ok := userSortColumns[q.Sort]; !ok {
+        return nil, er.ErrInvalidQuery
+    }
+
+    return keysetPage(r.matching(q),
+        func(u models.User) string { return u.SortValue(q.Sort) },
+        func(u models.User) string { return u.Id },
+        q.Sort, q.Desc, q.Cursor, q.Limit, q.Offset)
+}
+
+func (r *MemoryUserRepository) Count(ctx context.Context, q models.UserQuery) (int64, error) {
+    return int64(len(r.matching(q))), nil
+}
+
+func (r *MemoryUserRepository) GetByID(ctx context.Context, id string) (*models.User, error) {
+    r.mu.RLock()
+    defer r.mu.RUnlock()
+
+    result, ok := r.users[id]
+    if ok && !result.DeletedAt.Valid {
+        return &result, nil
+    }
+
+    return nil, er.ErrUserNotFound
+}
+
+func (r *MemoryUserRepository) GetByEmail(ctx context.Context, email string) (*models.User, error) {
+    r.mu.RLock()
+    defer r.mu.RUnlock()
+
+    for _, u := range r.users {
+        if strings.EqualFold(u.Email, email) && !u.DeletedAt.Valid {
+            return &u, nil
+        }
+    }
+
+    return nil, er.ErrUserNotFound
+}
+
+// emailTaken reports whether a live user other than exceptID has the email,
+// ignoring case like the unique index in Postgres. r.mu must be held.
+func (r *MemoryUserRepository) emailTaken(email, exceptID string) bool {
+    for id, u := range r.users {
+        if id != exceptID && !u.DeletedAt.Valid && strings.EqualFold(u.Email, email) {
+            return true
+        }
+    }
+    return false
+}
+
+func (r *MemoryUserRepository) Create(ctx context.Context, u *models.User) error {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+
+    if r.emailTaken(u.Email, u.Id) {
+        return er.ErrConflict
+    }
+
+    r.users[u.Id] = *u
+    return nil
+}
+
+func (r *MemoryUserRepository) Update(ctx context.Context, id string, u *models.UserUpdateRequest, version int64) error { //impove
+    r.mu.Lock()
+    defer r.mu.Unlock()
+
+    value, ok := r.users[id]
+    if !ok || value.DeletedAt.Valid {
+        return er.ErrUserNotFound
+    }
+    if version != 0 && value.Version != version {
+        return er.ErrVersionMismatch
+    }
+
+    if u.Name != nil {
+        value.Name = *u.Name
+    }
+    if u.Email != nil {
+        if r.emailTaken(*u.Email, id) {
+            return er.ErrConflict
+        }
+        value.Email = *u.Email
+    }
+    value.Version++
+
+    r.users[id] = value
+
+    return nil
+}
+
+func (r *MemoryUserRepository) SetRole(ctx context.Context, id string, role string) error {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+
+    value, ok := r.users[id]
+    if !ok || value.DeletedAt.Valid {
+        return er.ErrUserNotFound
+    }
+
+    value.Role = role
+    value.Version++
+    r.users[id] = value
+
+    return nil
+}
+
+func (r *MemoryUserRepository) Delete(ctx context.Context, id string, version int64) error {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+
+    value, ok := r.users[id]
+    if !ok || value.DeletedAt.Valid {
+        return er.ErrUserNotFound
+    }
+    if version != 0 && value.Version != version {
+        return er.ErrVersionMismatch
+    }
+
+    value.DeletedAt = deletedNow()
+    r.users[id] = value
+    return nil
+}
+
+func (r *MemoryUserRepository) DeleteAll(ctx context.Context) ([]string, error) {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+
+    ids := make([]string, 0)
+    for id, u := range r.users {
+        if !u.DeletedAt.Valid {
+            u.DeletedAt = deletedNow()
+            r.users[id] = u
+            ids = append(ids, id)
+        }
+    }
+    return ids, nil
+}
+
+func (r *MemoryUserRepository) ListDeleted(ctx context.Context, limit, offset int) ([]models.User, int64, error) {
+    r.mu.RLock()
+    result := make([]models.User, 0)
+    for _, u := range r.users {
+        if u.DeletedAt.Valid {
+            result = append(result, u)
+        }
+    }
+    r.mu.RUnlock()
+
+    sort.Slice(result, func(i, j int) bool {
+        if !result[i].DeletedAt.Time.Equal(result[j].DeletedAt.Time) {
+            return result[i].DeletedAt.Time.After(result[j].DeletedAt.Time)
+        }
+        return result[i].Id < result[j].Id
+    })
+
+    return paginate(result, limit, offset), int64(len(result)), nil
+}
+
+// Restore fails with ErrConflict when a live user has taken the email in
+// the meantime, matching the partial unique index in Postgres.
+func (r *MemoryUserRepository) Restore(ctx context.Context, id string) error {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+
+    value, ok := r.users[id]
+    if !ok || !value.DeletedAt.Valid {
+        return er.ErrUserNotFound
+    }
+    if r.emailTaken(value.Email, id) {
+        return er.ErrConflict
+    }
+
+    value.DeletedAt = gorm.DeletedAt{}
+    value.Version++
+    r.users[id] = value
+    return nil
+}
+
+// Purge also removes or detaches what belonged to the purged users; see
+// NewMemoryBackend.
+func (r *MemoryUserRepository) Purge(ctx context.Context, before time.Time) ([]string, error) {
+    r.mu.Lock()
+    ids := make([]string, 0)
+    for id, u := range r.users {
+        if u.DeletedAt.Valid && u.DeletedAt.Time.Before(before) {
+            delete(r.users, id)
+            ids = append(ids, id)
+        }
+    }
+    cascades := r.cascades
+    r.mu.Unlock()
+
+    for _, fn := range cascades {
+        fn(ids)
+    }
+    return ids, nil
+}
+
+// onPurge registers fn to be called with the ids of purged users.
+func (r *MemoryUserRepository) onPurge(fn func(ids []string)) {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+    r.cascades = append(r.cascades, fn)
+}
+
+// keep returns a function that puts the users with the given ids back as
+// they are now, or all users when no ids are given, for rolling back a
+// MemoryUnitOfWork.
+func (r *MemoryUserRepository) keep(ids ...string) func() {
+    r.mu.RLock()
+    defer r.mu.RUnlock()
+
+    if len(ids) == 0 {
+        saved := maps.Clone(r.users)
+        return func() {
+            r.mu.Lock()
+            defer r.mu.Unlock()
+            r.users = saved
+        }
+    }
+
+    saved := make(map[string]models.User, len(ids))
+    for _, id := range ids {
+        if u, ok := r.users[id]; ok {
+            saved[id] = u
+        }
+    }
+    return func() {
+        r.mu.Lock()
+        defer r.mu.Unlock()
+        for _, id := range ids {
+            if u, ok := saved[id]; ok {
+                r.users[id] = u
+            } else {
+                delete(r.users, id)
+            }
+        }
+    }
 }
\ No newline at end of file
@@ -1,239 +1,583 @@
-package repository
-
-import (
-	"context"
-	"deu/internal/models"
-	"log/slog"
-	"time"
-)
-
-type LoggingUserRepository struct {
-	Repo   UserRepository
-	Logger *slog.Logger
-}
-
-func NewLoggingUserRepository(repo UserRepository, logger *slog.Logger) *LoggingUserRepository {
-	return &LoggingUserRepository{
-		Repo:   repo,
-		Logger: logger,
-	}
-}
-
-func (r *LoggingUserRepository) GetAll(ctx context.Context) ([]models.User, error) {
-	r.Logger.Info("Calling GetAll Users")
-	start := time.Now()
-	users, err := r.Repo.GetAll(ctx)
-	duration := time.Since(start)
-	if err != nil {
-		r.Logger.Error("GetAll Users failed", "error", err, "duration", duration)
-		return nil, err
-	}
-	r.Logger.Info("GetAll Users success", "count", len(users), "duration", duration)
-	return users, nil
-}
-
-func (r *LoggingUserRepository) GetByID(ctx context.Context, id string) (*models.User, error) {
-	r.Logger.Info("Calling GetByID User", "id", id)
-	start := time.Now()
-	user, err := r.Repo.GetByID(ctx, id)
-	duration := time.Since(start)
-	if err != nil {
-		r.Logger.Error("GetByID User failed", "id", id, "error", err, "duration", duration)
-		return nil, err
-	}
-	r.Logger.Info("GetByID User success", "id", id, "duration", duration)
-	return user, nil
-}
-
-func (r *LoggingUserRepository) Create(ctx context.Context, u *models.User) error {
-	r.Logger.Info("Calling Create User", "email", u.Email)
-	start := time.Now()
-	err := r.Repo.Create(ctx, u)
-	duration := time.Since(start)
-	if err != nil {
-		r.Logger.Error("Create User failed", "email", u.Email, "error", err, "duration", duration)
-		return err
-	}
-	r.Logger.Info("Create User success", "id", u.Id, "duration", duration)
-	return nil
-}
-
-func (r *LoggingUserRepository) Update(ctx context.Context, id string, u *models.UserUpdateRequest) error {
-	r.Logger.Info("Calling Update User", "id", id)
-	start := time.Now()
-	err := r.Repo.Update(ctx, id, u)
-	duration := time.Since(start)
-	if err != nil {
-		r.Logger.Error("Update User failed", "id", id, "error", err, "duration", duration)
-		return err
-	}
-	r.Logger.Info("Update User success", "id", id, "duration", duration)
-	return nil
-}
-
-func (r *LoggingUserRepository) Delete(ctx context.Context, id string) error {
-	r.Logger.Info("Calling Delete User", "id", id)
-	start := time.Now()
-	err := r.Repo.Delete(ctx, id)
-	duration := time.Since(start)
-	if err != nil {
-		r.Logger.Error("Delete User failed", "id", id, "error", err, "duration", duration)
-		return err
-	}
-	r.Logger.Info("Delete User success", "id", id, "duration", duration)
-	return nil
-}
-
-func (r *LoggingUserRepository) DeleteAll(ctx context.Context) error {
-	r.Logger.Info("Calling DeleteAll Users")
-	start := time.Now()
-	err := r.Repo.DeleteAll(ctx)
-	duration := time.Since(start)
-	if err != nil {
-		r.Logger.Error("DeleteAll Users failed", "error", err, "duration", duration)
-		return err
-	}
-	r.Logger.Info("DeleteAll Users success", "duration", duration)
-	return nil
-}
-
-type LoggingPlaceRepository struct {
-	Repo   PlaceRepository
-	Logger *slog.Logger
-}
-
-func NewLoggingPlaceRepository(repo PlaceRepository, logger *slog.Logger) *LoggingPlaceRepository {
-	return &LoggingPlaceRepository{
-		Repo:   repo,
-		Logger: logger,
-	}
-}
-
-func (r *LoggingPlaceRepository) GetAll(ctx context.Context) ([]models.Place, error) {
-	r.Logger.Info("Calling GetAll Places")
-	start := time.Now()
-	places, err := r.Repo.GetAll(ctx)
-	duration := time.Since(start)
-	if err != nil {
-		r.Logger.Error("GetAll Places failed", "error", err, "duration", duration)
-		return nil, err
-	}
-	r.Logger.Info("GetAll Places success", "count", len(places), "duration", duration)
-	return places, nil
-}
-
-func (r *LoggingPlaceRepository) GetByID(ctx context.Context, id string) (*models.Place, error) {
-	r.Logger.Info("Calling GetByID Place", "id", id)
-	start := time.Now()
-	place, err := r.Repo.GetByID(ctx, id)
-	duration := time.Since(start)
-	if err != nil {
-		r.Logger.Error("GetByID Place failed", "id", id, "error", err, "duration", duration)
-		return nil, err
-	}
-	r.Logger.Info("GetByID Place success", "id", id, "duration", duration)
-	return place, nil
-}
-
-func (r *LoggingPlaceRepository) Create(ctx context.Context, p *models.Place) error {
-	r.Logger.Info("Calling Create Place", "name", p.Name)
-	start := time.Now()
-	err := r.Repo.Create(ctx, p)
-	duration := time.Since(start)
-	if err != nil {
-		r.Logger.Error("Create Place failed", "name", p.Name, "error", err, "duration", duration)
-		return err
-	}
-	r.Logger.Info("Create Place success", "id", p.Id, "duration", duration)
-	return nil
-}
-
-func (r *LoggingPlaceRepository) Update(ctx context.Context, id string, p *models.PlaceUpdateRequest) error {
-	r.Logger.Info("Calling Update Place", "id", id)
-	start := time.Now()
-	err := r.Repo.Update(ctx, id, p)
-	duration := time.Since(start)
-	if err != nil {
-		r.Logger.Error("Update Place failed", "id", id, "error", err, "duration", duration)
-		return err
-	}
-	r.Logger.Info("Update Place success", "id", id, "duration", duration)
-	return nil
-}
-
-func (r *LoggingPlaceRepository) Delete(ctx context.Context, id string) error {
-	r.Logger.Info("Calling Delete Place", "id", id)
-	start := time.Now()
-	err := r.Repo.Delete(ctx, id)
-	duration := time.Since(start)
-	if err != nil {
-		r.Logger.Error("Delete Place failed", "id", id, "error", err, "duration", duration)
-		return err
-	}
-	r.Logger.Info("Delete Place success", "id", id, "duration", duration)
-	return nil
-}
-
-func (r *LoggingPlaceRepository) DeleteAll(ctx context.Context) error {
-	r.Logger.Info("Calling DeleteAll Places")
-	start := time.Now()
-	err := r.Repo.DeleteAll(ctx)
-	duration := time.Since(start)
-	if err != nil {
-		r.Logger.Error("DeleteAll Places failed", "error", err, "duration", duration)
-		return err
-	}
-	r.Logger.Info("DeleteAll Places success", "duration", duration)
-	return nil
-}
-
-type LoggingUserPlaceRepository struct {
-	Repo   UserPlaceRepository
-	Logger *slog.Logger
-}
-
-func NewLoggingUserPlaceRepository(repo UserPlaceRepository, logger *slog.Logger) *LoggingUserPlaceRepository {
-	return &LoggingUserPlaceRepository{
-		Repo:   repo,
-		Logger: logger,
-	}
-}
-
-func (r *LoggingUserPlaceRepository) AddVisitedPlace(ctx context.Context, userID, placeID string) error {
-	r.Logger.Info("Calling AddVisitedPlace", "userID", userID, "placeID", placeID)
-	start := time.Now()
-	err := r.Repo.AddVisitedPlace(ctx, userID, placeID)
-	duration := time.Since(start)
-	if err != nil {
-		r.Logger.Error("AddVisitedPlace failed", "userID", userID, "placeID", placeID, "error", err, "duration", duration)
-		return err
-	}
-	r.Logger.Info("AddVisitedPlace success", "userID", userID, "placeID", placeID, "duration", duration)
-	return nil
-}
-
-func (r *LoggingUserPlaceRepository) RemoveVisitedPlace(ctx context.Context, userID, placeID string) error {
-	r.Logger.Info("Calling RemoveVisitedPlace", "userID", userID, "placeID", placeID)
-	start := time.Now()
-	err := r.Repo.RemoveVisitedPlace(ctx, userID, placeID)
-	duration := time.Since(start)
-	if err != nil {
-		r.Logger.Error("RemoveVisitedPlace failed", "userID", userID, "placeID", placeID, "error", err, "duration", duration)
-		return err
-	}
-	r.Logger.Info("RemoveVisitedPlace success", "userID", userID, "placeID", placeID, "duration", duration)
-	return nil
-}
-
-func (r *LoggingUserPlaceRepository) HasVisitedPlace(ctx context.Context, userID, placeID string) (bool, error) {
-	r.Logger.Info("Calling HasVisitedPlace", "userID", userID, "placeID", placeID)
-	start := time.Now()
-	visited, err := r.Repo.HasVisitedPlace(ctx, userID, placeID)
-	duration := time.Since(start)
-	if err != nil {
-		r.Logger.Error("HasVisitedPlace failed", "userID", userID, "placeID", placeID, "error", err, "duration", duration)
-		return false, err
-	}
-	r.Logger.Info("HasVisitedPlace success", "userID", userID, "placeID", placeID, "visited", visited, "duration", duration)
-	return visited, nil
-}
+package repository
+
+import (
+	"context"
+	"deu/internal/models"
+	"log/slog"
+	"time"
+)
+
+type LoggingUserRepository struct {
+	Repo   UserRepository
+	Logger *slog.Logger
+}
+
+func NewLoggingUserRepository(repo UserRepository, logger *slog.Logger) *LoggingUserRepository {
+	return &LoggingUserRepository{
+		Repo:   repo,
+		Logger: logger,
+	}
+}
+
+func (r *LoggingUserRepository) List(ctx context.Context, q models.UserQuery) ([]models.User, error) {
+	r.Logger.Info("Calling List Users", "sort", q.Sort, "desc", q.Desc, "limit", q.Limit, "offset", q.Offset, "cursor", q.Cursor != nil)
+	start := time.Now()
+	users, err := r.Repo.List(ctx, q)
+	duration := time.Since(start)
+	if err != nil {
+		r.Logger.Error("List Users failed", "error", err, "duration", duration)
+		return nil, err
+	}
+	r.Logger.Info("List Users success", "count", len(users), "duration", duration)
+	return users, nil
+}
+
+func (r *LoggingUserRepository) Count(ctx context.Context, q models.UserQuery) (int64, error) {
+	r.Logger.Info("Calling Count Users")
+	start := time.Now()
+	total, err := r.Repo.Count(ctx, q)
+	duration := time.Since(start)
+	if err != nil {
+		r.Logger.Error("Count Users failed", "error", err, "duration", duration)
+		return 0, err
+	}
+	r.Logger.Info("Count Users success", "total", total, "duration", duration)
+	return total, nil
+}
+
+func (r *LoggingUserRepository) GetByID(ctx context.Context, id string) (*models.User, error) {
+	r.Logger.Info("Calling GetByID User", "id", id)
+	start := time.Now()
+	user, err := r.Repo.GetByID(ctx, id)
+	duration := time.Since(start)
+	if err != nil {
+		r.Logger.Error("GetByID User failed", "id", id, "error", err, "duration", duration)
+		return nil, err
+	}
+	r.Logger.Info("GetByID User success", "id", id, "duration", duration)
+	return user, nil
+}
+
+func (r *LoggingUserRepository) GetByEmail(ctx context.Context, email string) (*models.User, error) {
+	r.Logger.Info("Calling GetByEmail User", "email", email)
+	start := time.Now()
+	user, err := r.Repo.GetByEmail(ctx, email)
+	duration := time.Since(start)
+	if err != nil {
+		r.Logger.Error("GetByEmail User failed", "email", email, "error", err, "duration", duration)
+		return nil, err
+	}
+	r.Logger.Info("GetByEmail User success", "id", user.Id, "duration", duration)
+	return user, nil
+}
+
+func (r *LoggingUserRepository) Create(ctx context.Context, u *models.User) error {
+	r.Logger.Info("Calling Create User", "email", u.Email)
+	start := time.Now()
+	err := r.Repo.Create(ctx, u)
+	duration := time.Since(start)
+	if err != nil {
+		r.Logger.Error("Create User failed", "email", u.Email, "error", err, "duration", duration)
+		return err
+	}
+	r.Logger.Info("Create User success", "id", u.Id, "duration", duration)
+	return nil
+}
+
+func (r *LoggingUserRepository) Update(ctx context.Context, id string, u *models.UserUpdateRequest, version int64) error {
+	r.Logger.Info("Calling Update User", "id", id, "version", version)
+	start := time.Now()
+	err := r.Repo.Update(ctx, id, u, version)
+	duration := time.Since(start)
+	if err != nil {
+		r.Logger.Error("Update User failed", "id", id, "error", err, "duration", duration)
+		return err
+	}
+	r.Logger.Info("Update User success", "id", id, "duration", duration)
+	return nil
+}
+
+func (r *LoggingUserRepository) SetRole(ctx context.Context, id string, role string) error {
+	r.Logger.Info("Calling SetRole User", "id", id, "role", role)
+	start := time.Now()
+	err := r.Repo.SetRole(ctx, id, role)
+	duration := time.Since(start)
+	if err != nil {
+		r.Logger.Error("SetRole User failed", "id", id, "role", role, "error", err, "duration", duration)
+		return err
+	}
+	r.Logger.Info("SetRole User success", "id", id, "role", role, "duration", duration)
+	return nil
+}
+
+func (r *LoggingUserRepository) Delete(ctx context.Context, id string, version int64) error {
+	r.Logger.Info("Calling Delete User", "id", id, "version", version)
+	start := time.Now()
+	err := r.Repo.Delete(ctx, id, version)
+	duration := time.Since(start)
+	if err != nil {
+		r.Logger.Error("Delete User failed", "id", id, "error", err, "duration", duration)
+		return err
+	}
+	r.Logger.Info("Delete User success", "id", id, "duration", duration)
+	return nil
+}
+
+func (r *LoggingUserRepository) DeleteAll(ctx context.Context) ([]string, error) {
+	r.Logger.Info("Calling DeleteAll Users")
+	start := time.Now()
+	ids, err := r.Repo.DeleteAll(ctx)
+	duration := time.Since(start)
+	if err != nil {
+		r.Logger.Error("DeleteAll Users failed", "error", err, "duration", duration)
+		return nil, err
+	}
+	r.Logger.Info("DeleteAll Users success", "count", len(ids), "duration", duration)
+	return ids, nil
+}
+
+func (r *LoggingUserRepository) ListDeleted(ctx context.Context, limit, offset int) ([]models.User, int64, error) {
+	r.Logger.Info("Calling ListDeleted Users", "limit", limit, "offset", offset)
+	start := time.Now()
+	items, total, err := r.Repo.ListDeleted(ctx, limit, offset)
+	duration := time.Since(start)
+	if err != nil {
+		r.Logger.Error("ListDeleted Users failed", "error", err, "duration", duration)
+		return nil, 0, err
+	}
+	r.Logger.Info("ListDeleted Users success", "count", len(items), "total", total, "duration", duration)
+	return items, total, nil
+}
+
+func (r *LoggingUserRepository) Restore(ctx context.Context, id string) error {
+	r.Logger.Info("Calling Restore User", "id", id)
+	start := time.Now()
+	err := r.Repo.Restore(ctx, id)
+	duration := time.Since(start)
+	if err != nil {
+		r.Logger.Error("Restore User failed", "id", id, "error", err, "duration", duration)
+		return err
+	}
+	r.Logger.Info("Restore User success", "id", id, "duration", duration)
+	return nil
+}
+
+func (r *LoggingUserRepository) Purge(ctx context.Context, before time.Time) ([]string, error) {
+	r.Logger.Info("Calling Purge Users", "before", before)
+	start := time.Now()
+	ids, err := r.Repo.Purge(ctx, before)
+	duration := time.Since(start)
+	if err != nil {
+		r.Logger.Error("Purge Users failed", "error", err, "duration", duration)
+		return nil, err
+	}
+	r.Logger.Info("Purge Users success", "count", len(ids), "duration", duration)
+	return ids, nil
+}
+
+type LoggingPlaceRepository struct {
+	Repo   PlaceRepository
+	Logger *slog.Logger
+}
+
+func NewLoggingPlaceRepository(repo PlaceRepository, logger *slog.Logger) *LoggingPlaceRepository {
+	return &LoggingPlaceRepository{
+		Repo:   repo,
+		Logger: logger,
+	}
+}
+
+func (r *LoggingPlaceRepository) List(ctx context.Context, q models.PlaceQuery) ([]models.Place, error) {
+	r.Logger.Info("Calling List Places", "sort", q.Sort, "desc", q.Desc, "limit", q.Limit, "offset", q.Offset, "cursor", q.Cursor != nil)
+	start := time.Now()
+	places, err := r.Repo.List(ctx, q)
+	duration := time.Since(start)
+	if err != nil {
+		r.Logger.Error("List Places failed", "error", err, "duration", duration)
+		return nil, err
+	}
+	r.Logger.Info("List Places success", "count", len(places), "duration", duration)
+	return places, nil
+}
+
+func (r *LoggingPlaceRepository) Nearby(ctx context.Context, q models.GeoQuery) ([]models.PlaceWithDistance, int64, error) {
+	r.Logger.Info("Calling Nearby Places", "lat", q.Latitude, "lng", q.Longitude, "radius", q.RadiusMeters, "bbox", q.BBox != nil, "limit", q.Limit, "offset", q.Offset)
+	start := time.Now()
+	places, total, err := r.Repo.Nearby(ctx, q)
+	duration := time.Since(start)
+	if err != nil {
+		r.Logger.Error("Nearby Places failed", "error", err, "duration", duration)
+		return nil, 0, err
+	}
+	r.Logger.Info("Nearby Places success", "count", len(places), "total", total, "duration", duration)
+	return places, total, nil
+}
+
+func (r *LoggingPlaceRepository) Search(ctx context.Context, q models.SearchQuery) ([]models.PlaceSearchResult, int64, error) {
+	r.Logger.Info("Calling Search Places", "q", q.Text, "limit", q.Limit, "offset", q.Offset)
+	start := time.Now()
+	places, total, err := r.Repo.Search(ctx, q)
+	duration := time.Since(start)
+	if err != nil {
+		r.Logger.Error("Search Places failed", "error", err, "duration", duration)
+		return nil, 0, err
+	}
+	r.Logger.Info("Search Places success", "count", len(places), "total", total, "duration", duration)
+	return places, total, nil
+}
+
+func (r *LoggingPlaceRepository) Count(ctx context.Context, q models.PlaceQuery) (int64, error) {
+	r.Logger.Info("Calling Count Places")
+	start := time.Now()
+	total, err := r.Repo.Count(ctx, q)
+	duration := time.Since(start)
+	if err != nil {
+		r.Logger.Error("Count Places failed", "error", err, "duration", duration)
+		return 0, err
+	}
+	r.Logger.Info("Count Places success", "total", total, "duration", duration)
+	return total, nil
+}
+
+func (r *LoggingPlaceRepository) Facets(ctx context.Context, q models.PlaceQuery) ([]models.TagFacet, error) {
+	r.Logger.Info("Calling Facets Places", "tags", q.Tags)
+	start := time.Now()
+	facets, err := r.Repo.Facets(ctx, q)
+	duration := time.Since(start)
+	if err != nil {
+		r.Logger.Error("Facets Places failed", "error", err, "duration", duration)
+		return nil, err
+	}
+	r.Logger.Info("Facets Places success", "count", len(facets), "duration", duration)
+	return facets, nil
+}
+
+func (r *LoggingPlaceRepository) GetByID(ctx context.Context, id string) (*models.Place, error) {
+	r.Logger.Info("Calling GetByID Place", "id", id)
+	start := time.Now()
+	place, err := r.Repo.GetByID(ctx, id)
+	duration := time.Since(start)
+	if err != nil {
+		r.Logger.Error("GetByID Place failed", "id", id, "error", err, "duration", duration)
+		return nil, err
+	}
+	r.Logger.Info("GetByID Place success", "id", id, "duration", duration)
+	return place, nil
+}
+
+func (r *LoggingPlaceRepository) Create(ctx context.Context, p *models.Place) error {
+	r.Logger.Info("Calling Create Place", "name", p.Name)
+	start := time.Now()
+	err := r.Repo.Create(ctx, p)
+	duration := time.Since(start)
+	if err != nil {
+		r.Logger.Error("Create Place failed", "name", p.Name, "error", err, "duration", duration)
+		return err
+	}
+	r.Logger.Info("Create Place success", "id", p.Id, "duration", duration)
+	return nil
+}
+
+func (r *LoggingPlaceRepository) Update(ctx context.Context, id string, p *models.PlaceUpdateRequest, version int64) error {
+	r.Logger.Info("Calling Update Place", "id", id, "version", version)
+	start := time.Now()
+	err := r.Repo.Update(ctx, id, p, version)
+	duration := time.Since(start)
+	if err != nil {
+		r.Logger.Error("Update Place failed", "id", id, "error", err, "duration", duration)
+		return err
+	}
+	r.Logger.Info("Update Place success", "id", id, "duration", duration)
+	return nil
+}
+
+func (r *LoggingPlaceRepository) SetTags(ctx context.Context, placeID string, tags []models.Tag) error {
+	r.Logger.Info("Calling SetTags Place", "id", placeID, "count", len(tags))
+	start := time.Now()
+	err := r.Repo.SetTags(ctx, placeID, tags)
+	duration := time.Since(start)
+	if err != nil {
+		r.Logger.Error("SetTags Place failed", "id", placeID, "error", err, "duration", duration)
+		return err
+	}
+	r.Logger.Info("SetTags Place success", "id", placeID, "duration", duration)
+	return nil
+}
+
+func (r *LoggingPlaceRepository) Delete(ctx context.Context, id string, version int64) error {
+	r.Logger.Info("Calling Delete Place", "id", id, "version", version)
+	start := time.Now()
+	err := r.Repo.Delete(ctx, id, version)
+	duration := time.Since(start)
+	if err != nil {
+		r.Logger.Error("Delete Place failed", "id", id, "error", err, "duration", duration)
+		return err
+	}
+	r.Logger.Info("Delete Place success", "id", id, "duration", duration)
+	return nil
+}
+
+func (r *LoggingPlaceRepository) DeleteAll(ctx context.Context) ([]string, error) {
+	r.Logger.Info("Calling DeleteAll Places")
+	start := time.Now()
+	ids, err := r.Repo.DeleteAll(ctx)
+	duration := time.Since(start)
+	if err != nil {
+		r.Logger.Error("DeleteAll Places failed", "error", err, "duration", duration)
+		return nil, err
+	}
+	r.Logger.Info("DeleteAll Places success", "count", len(ids), "duration", duration)
+	return ids, nil
+}
+
+func (r *LoggingPlaceRepository) ListDeleted(ctx context.Context, limit, offset int) ([]models.Place, int64, error) {
+	r.Logger.Info("Calling ListDeleted Places", "limit", limit, "offset", offset)
+	start := time.Now()
+	items, total, err := r.Repo.ListDeleted(ctx, limit, offset)
+	duration := time.Since(start)
+	if err != nil {
+		r.Logger.Error("ListDeleted Places failed", "error", err, "duration", duration)
+		return nil, 0, err
+	}
+	r.Logger.Info("ListDeleted Places success", "count", len(items), "total", total, "duration", duration)
+	return items, total, nil
+}
+
+func (r *LoggingPlaceRepository) Restore(ctx context.Context, id string) error {
+	r.Logger.Info("Calling Restore Place", "id", id)
+	start := time.Now()
+	err := r.Repo.Restore(ctx, id)
+	duration := time.Since(start)
+	if err != nil {
+		r.Logger.Error("Restore Place failed", "id", id, "error", err, "duration", duration)
+		return err
+	}
+	r.Logger.Info("Restore Place success", "id", id, "duration", duration)
+	return nil
+}
+
+func (r *LoggingPlaceRepository) Purge(ctx context.Context, before time.Time) ([]string, error) {
+	r.Logger.Info("Calling Purge Places", "before", before)
+	start := time.Now()
+	ids, err := r.Repo.Purge(ctx, before)
+	duration := time.Since(start)
+	if err != nil {
+		r.Logger.Error("Purge Places failed", "error", err, "duration", duration)
+		return nil, err
+	}
+	r.Logger.Info("Purge Places success", "count", len(ids), "duration", duration)
+	return ids, nil
+}
+
+type LoggingUserPlaceRepository struct {
+	Repo   UserPlaceRepository
+	Logger *slog.Logger
+}
+
+func NewLoggingUserPlaceRepository(repo UserPlaceRepository, logger *slog.Logger) *LoggingUserPlaceRepository {
+	return &LoggingUserPlaceRepository{
+		Repo:   repo,
+		Logger: logger,
+	}
+}
+
+func (r *LoggingUserPlaceRepository) AddVisitedPlace(ctx context.Context, visit *models.UserPlace) error {
+	r.Logger.Info("Calling AddVisitedPlace", "userID", visit.UserID, "placeID", visit.PlaceID)
+	start := time.Now()
+	err := r.Repo.AddVisitedPlace(ctx, visit)
+	duration := time.Since(start)
+	if err != nil {
+		r.Logger.Error("AddVisitedPlace failed", "userID", visit.UserID, "placeID", visit.PlaceID, "error", err, "duration", duration)
+		return err
+	}
+	r.Logger.Info("AddVisitedPlace success", "userID", visit.UserID, "placeID", visit.PlaceID, "visitID", visit.Id, "duration", duration)
+	return nil
+}
+
+func (r *LoggingUserPlaceRepository) RemoveVisitedPlace(ctx context.Context, userID, placeID string) error {
+	r.Logger.Info("Calling RemoveVisitedPlace", "userID", userID, "placeID", placeID)
+	start := time.Now()
+	err := r.Repo.RemoveVisitedPlace(ctx, userID, placeID)
+	duration := time.Since(start)
+	if err != nil {
+		r.Logger.Error("RemoveVisitedPlace failed", "userID", userID, "placeID", placeID, "error", err, "duration", duration)
+		return err
+	}
+	r.Logger.Info("RemoveVisitedPlace success", "userID", userID, "placeID", placeID, "duration", duration)
+	return nil
+}
+
+func (r *LoggingUserPlaceRepository) RemoveVisit(ctx context.Context, userID, visitID string) error {
+	r.Logger.Info("Calling RemoveVisit", "userID", userID, "visitID", visitID)
+	start := time.Now()
+	err := r.Repo.RemoveVisit(ctx, userID, visitID)
+	duration := time.Since(start)
+	if err != nil {
+		r.Logger.Error("RemoveVisit failed", "userID", userID, "visitID", visitID, "error", err, "duration", duration)
+		return err
+	}
+	r.Logger.Info("RemoveVisit success", "userID", userID, "visitID", visitID, "duration", duration)
+	return nil
+}
+
+func (r *LoggingUserPlaceRepository) ListVisits(ctx context.Context, userID, placeID string) ([]models.UserPlace, error) {
+	r.Logger.Info("Calling ListVisits", "userID", userID, "placeID", placeID)
+	start := time.Now()
+	visits, err := r.Repo.ListVisits(ctx, userID, placeID)
+	duration := time.Since(start)
+	if err != nil {
+		r.Logger.Error("ListVisits failed", "userID", userID, "placeID", placeID, "error", err, "duration", duration)
+		return nil, err
+	}
+	r.Logger.Info("ListVisits success", "userID", userID, "placeID", placeID, "count", len(visits), "duration", duration)
+	return visits, nil
+}
+
+func (r *LoggingUserPlaceRepository) ListVisitedPlaces(ctx context.Context, userID string, limit, offset int) ([]models.Place, int64, error) {
+	r.Logger.Info("Calling ListVisitedPlaces", "userID", userID, "limit", limit, "offset", offset)
+	start := time.Now()
+	places, total, err := r.Repo.ListVisitedPlaces(ctx, userID, limit, offset)
+	duration := time.Since(start)
+	if err != nil {
+		r.Logger.Error("ListVisitedPlaces failed", "userID", userID, "error", err, "duration", duration)
+		return nil, 0, err
+	}
+	r.Logger.Info("ListVisitedPlaces success", "userID", userID, "count", len(places), "total", total, "duration", duration)
+	return places, total, nil
+}
+
+func (r *LoggingUserPlaceRepository) ListVisitors(ctx context.Context, placeID string, limit, offset int) ([]models.User, int64, error) {
+	r.Logger.Info("Calling ListVisitors", "placeID", placeID, "limit", limit, "offset", offset)
+	start := time.Now()
+	users, total, err := r.Repo.ListVisitors(ctx, placeID, limit, offset)
+	duration := time.Since(start)
+	if err != nil {
+		r.Logger.Error("ListVisitors failed", "placeID", placeID, "error", err, "duration", duration)
+		return nil, 0, err
+	}
+	r.Logger.Info("ListVisitors success", "placeID", placeID, "count", len(users), "total", total, "duration", duration)
+	return users, total, nil
+}
+
+type LoggingTagRepository struct {
+	Repo   TagRepository
+	Logger *slog.Logger
+}
+
+func NewLoggingTagRepository(repo TagRepository, logger *slog.Logger) *LoggingTagRepository {
+	return &LoggingTagRepository{
+		Repo:   repo,
+		Logger: logger,
+	}
+}
+
+func (r *LoggingTagRepository) List(ctx context.Context) ([]models.Tag, error) {
+	r.Logger.Info("Calling List Tags")
+	start := time.Now()
+	tags, err := r.Repo.List(ctx)
+	duration := time.Since(start)
+	if err != nil {
+		r.Logger.Error("List Tags failed", "error", err, "duration", duration)
+		return nil, err
+	}
+	r.Logger.Info("List Tags success", "count", len(tags), "duration", duration)
+	return tags, nil
+}
+
+func (r *LoggingTagRepository) GetByID(ctx context.Context, id string) (*models.Tag, error) {
+	r.Logger.Info("Calling GetByID Tag", "id", id)
+	start := time.Now()
+	tag, err := r.Repo.GetByID(ctx, id)
+	duration := time.Since(start)
+	if err != nil {
+		r.Logger.Error("GetByID Tag failed", "id", id, "error", err, "duration", duration)
+		return nil, err
+	}
+	r.Logger.Info("GetByID Tag success", "id", id, "duration", duration)
+	return tag, nil
+}
+
+func (r *LoggingTagRepository) GetBySlugs(ctx context.Context, slugs []string) ([]models.Tag, error) {
+	r.Logger.Info("Calling GetBySlugs Tags", "slugs", slugs)
+	start := time.Now()
+	tags, err := r.Repo.GetBySlugs(ctx, slugs)
+	duration := time.Since(start)
+	if err != nil {
+		r.Logger.Error("GetBySlugs Tags failed", "slugs", slugs, "error", err, "duration", duration)
+		return nil, err
+	}
+	r.Logger.Info("GetBySlugs Tags success", "count", len(tags), "duration", duration)
+	return tags, nil
+}
+
+func (r *LoggingTagRepository) Create(ctx context.Context, t *models.Tag) error {
+	r.Logger.Info("Calling Create Tag", "slug", t.Slug)
+	start := time.Now()
+	err := r.Repo.Create(ctx, t)
+	duration := time.Since(start)
+	if err != nil {
+		r.Logger.Error("Create Tag failed", "slug", t.Slug, "error", err, "duration", duration)
+		return err
+	}
+	r.Logger.Info("Create Tag success", "id", t.Id, "duration", duration)
+	return nil
+}
+
+func (r *LoggingTagRepository) Update(ctx context.Context, t *models.Tag) error {
+	r.Logger.Info("Calling Update Tag", "id", t.Id)
+	start := time.Now()
+	err := r.Repo.Update(ctx, t)
+	duration := time.Since(start)
+	if err != nil {
+		r.Logger.Error("Update Tag failed", "id", t.Id, "error", err, "duration", duration)
+		return err
+	}
+	r.Logger.Info("Update Tag success", "id", t.Id, "duration", duration)
+	return nil
+}
+
+func (r *LoggingTagRepository) Delete(ctx context.Context, id string) error {
+	r.Logger.Info("Calling Delete Tag", "id", id)
+	start := time.Now()
+	err := r.Repo.Delete(ctx, id)
+	duration := time.Since(start)
+	if err != nil {
+		r.Logger.Error("Delete Tag failed", "id", id, "error", err, "duration", duration)
+		return err
+	}
+	r.Logger.Info("Delete Tag success", "id", id, "duration", duration)
+	return nil
+}
+
+// LoggingUnitOfWork logs each transaction and hands fn logging repositories.
+type LoggingUnitOfWork struct {
+	UnitOfWork UnitOfWork
+	Logger     *slog.Logger
+}
+
+func NewLoggingUnitOfWork(uow UnitOfWork, logger *slog.Logger) *LoggingUnitOfWork {
+	return &LoggingUnitOfWork{
+		UnitOfWork: uow,
+		Logger:     logger,
+	}
+}
+
+func (u *LoggingUnitOfWork) WithTx(ctx context.Context, fn func(tx Repos) error) error {
+	u.Logger.Info("Calling WithTx")
+	start := time.Now()
+	err := u.UnitOfWork.WithTx(ctx, func(tx Repos) error {
+		return fn(Repos{
+			Users:      NewLoggingUserRepository(tx.Users, u.Logger),
+			Places:     NewLoggingPlaceRepository(tx.Places, u.Logger),
+			UserPlaces: NewLoggingUserPlaceRepository(tx.UserPlaces, u.Logger),
+			Reviews:    tx.Reviews,
+		})
+	})
+	duration := time.Since(start)
+	if err != nil {
+		u.Logger.Error("WithTx rolled back", "error", err, "duration", duration)
+		return err
+	}
+	u.Logger.Info("WithTx committed", "duration", duration)
+	return nil
+}
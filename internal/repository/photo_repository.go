@@ -0,0 +1,18 @@
+package repository
+
+import (
+	"context"
+
+	"deu/internal/models"
+)
+
+// PhotoRepository stores photo metadata; the image bytes are kept in a
+// blob.Store by the caller.
+type PhotoRepository interface {
+	Create(ctx context.Context, p *models.Photo) error
+	GetByID(ctx context.Context, id string) (*models.Photo, error)
+	ListByPlace(ctx context.Context, placeID string, limit, offset int) ([]models.Photo, int64, error)
+	Delete(ctx context.Context, id string) error
+	DeleteByPlace(ctx context.Context, placeID string) error
+	DeleteAll(ctx context.Context) error
+}
@@ -1,15 +1,29 @@
-package repository
-
-import (
-	"deu/internal/models"
-	"context"
-)
-
-type PlaceRepository interface {
-    GetAll(ctx context.Context) ([]models.Place, error)
-    GetByID(ctx context.Context, id string) (*models.Place, error)
-    Create(ctx context.Context, p *models.Place) error
-    Update(ctx context.Context, id string, p *models.PlaceUpdateRequest) error
-    Delete(ctx context.Context, id string) error
-    DeleteAll(ctx context.Context) error
+package repository
+
+import (
+	"deu/internal/models"
+	"context"
+	"time"
+)
+
+type PlaceRepository interface {
+    List(ctx context.Context, q models.PlaceQuery) ([]models.Place, error)
+    Count(ctx context.Context, q models.PlaceQuery) (int64, error)
+    Facets(ctx context.Context, q models.PlaceQuery) ([]models.TagFacet, error)
+    Nearby(ctx context.Context, q models.GeoQuery) ([]models.PlaceWithDistance, int64, error)
+    Search(ctx context.Context, q models.SearchQuery) ([]models.PlaceSearchResult, int64, error)
+    GetByID(ctx context.Context, id string) (*models.Place, error)
+    Create(ctx context.Context, p *models.Place) error
+    // Update and Delete only apply while the place is still at version; a
+    // version of 0 skips the check.
+    Update(ctx context.Context, id string, p *models.PlaceUpdateRequest, version int64) error
+    // SetTags leaves the version alone: it is only called together with
+    // Update, in one unit of work, and a write bumps the version once. A tag
+    // that no longer exists is reported as ErrUnknownTag.
+    SetTags(ctx context.Context, placeID string, tags []models.Tag) error
+    Delete(ctx context.Context, id string, version int64) error
+    DeleteAll(ctx context.Context) ([]string, error)
+    ListDeleted(ctx context.Context, limit, offset int) ([]models.Place, int64, error)
+    Restore(ctx context.Context, id string) error
+    Purge(ctx context.Context, before time.Time) ([]string, error)
 }
\ No newline at end of file
@@ -0,0 +1,59 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	er "deu/internal/errors"
+	"deu/internal/models"
+
+	"gorm.io/gorm"
+)
+
+type PostgresAPIKeyRepository struct {
+	DB *gorm.DB
+}
+
+func NewPostgresAPIKeyRepository(db *gorm.DB) *PostgresAPIKeyRepository {
+	return &PostgresAPIKeyRepository{DB: db}
+}
+
+func (r *PostgresAPIKeyRepository) Create(ctx context.Context, k *models.APIKey) error {
+	return translateDuplicate(r.DB.WithContext(ctx).Create(k).Error, er.ErrDuplicate)
+}
+
+func (r *PostgresAPIKeyRepository) ListByUser(ctx context.Context, userID string) ([]models.APIKey, error) {
+	var keys []models.APIKey
+	if err := r.DB.WithContext(ctx).Where("user_id = ?", userID).Order("created_at DESC").Find(&keys).Error; err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+func (r *PostgresAPIKeyRepository) GetByHash(ctx context.Context, keyHash string) (*models.APIKey, error) {
+	var key models.APIKey
+	if err := r.DB.WithContext(ctx).Where("key_hash = ?", keyHash).First(&key).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, er.ErrAPIKeyNotFound
+		}
+		return nil, err
+	}
+	return &key, nil
+}
+
+func (r *PostgresAPIKeyRepository) Delete(ctx context.Context, userID, id string) error {
+	result := r.DB.WithContext(ctx).Where("id = ? AND user_id = ?", id, userID).Delete(&models.APIKey{})
+
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return er.ErrAPIKeyNotFound
+	}
+	return nil
+}
+
+func (r *PostgresAPIKeyRepository) TouchLastUsed(ctx context.Context, id string, at time.Time) error {
+	return r.DB.WithContext(ctx).Model(&models.APIKey{}).Where("id = ?", id).Update("last_used_at", at).Error
+}
@@ -0,0 +1,51 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	er "deu/internal/errors"
+	"deu/internal/models"
+
+	"gorm.io/gorm"
+)
+
+type PostgresAuditRepository struct {
+	DB *gorm.DB
+}
+
+func NewPostgresAuditRepository(db *gorm.DB) *PostgresAuditRepository {
+	return &PostgresAuditRepository{DB: db}
+}
+
+func (r *PostgresAuditRepository) Create(ctx context.Context, e *models.AuditEntry) error {
+	return r.DB.WithContext(ctx).Create(e).Error
+}
+
+func (r *PostgresAuditRepository) GetByID(ctx context.Context, id string) (*models.AuditEntry, error) {
+	var entry models.AuditEntry
+	if err := r.DB.WithContext(ctx).Where("id = ?", id).First(&entry).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, er.ErrAuditEntryNotFound
+		}
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// ListByEntity returns an entity's history, newest first.
+func (r *PostgresAuditRepository) ListByEntity(ctx context.Context, entityType, entityID string, limit, offset int) ([]models.AuditEntry, int64, error) {
+	db := r.DB.WithContext(ctx).Model(&models.AuditEntry{}).
+		Where("entity_type = ? AND entity_id = ?", entityType, entityID)
+
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var entries []models.AuditEntry
+	if err := db.Order("created_at DESC, id").Limit(limit).Offset(offset).Find(&entries).Error; err != nil {
+		return nil, 0, err
+	}
+	return entries, total, nil
+}
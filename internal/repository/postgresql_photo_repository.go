@@ -0,0 +1,68 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	er "deu/internal/errors"
+	"deu/internal/models"
+
+	"gorm.io/gorm"
+)
+
+type PostgresPhotoRepository struct {
+	DB *gorm.DB
+}
+
+func NewPostgresPhotoRepository(db *gorm.DB) *PostgresPhotoRepository {
+	return &PostgresPhotoRepository{DB: db}
+}
+
+func (r *PostgresPhotoRepository) Create(ctx context.Context, p *models.Photo) error {
+	return translateDuplicate(r.DB.WithContext(ctx).Create(p).Error, er.ErrPhotoExists)
+}
+
+func (r *PostgresPhotoRepository) GetByID(ctx context.Context, id string) (*models.Photo, error) {
+	var photo models.Photo
+	if err := r.DB.WithContext(ctx).Where("id = ?", id).First(&photo).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, er.ErrPhotoNotFound
+		}
+		return nil, err
+	}
+	return &photo, nil
+}
+
+func (r *PostgresPhotoRepository) ListByPlace(ctx context.Context, placeID string, limit, offset int) ([]models.Photo, int64, error) {
+	db := r.DB.WithContext(ctx).Model(&models.Photo{}).Where("place_id = ?", placeID)
+
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var photos []models.Photo
+	if err := db.Order("created_at DESC, id").Limit(limit).Offset(offset).Find(&photos).Error; err != nil {
+		return nil, 0, err
+	}
+	return photos, total, nil
+}
+
+func (r *PostgresPhotoRepository) Delete(ctx context.Context, id string) error {
+	result := r.DB.WithContext(ctx).Where("id = ?", id).Delete(&models.Photo{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return er.ErrPhotoNotFound
+	}
+	return nil
+}
+
+func (r *PostgresPhotoRepository) DeleteByPlace(ctx context.Context, placeID string) error {
+	return r.DB.WithContext(ctx).Where("place_id = ?", placeID).Delete(&models.Photo{}).Error
+}
+
+func (r *PostgresPhotoRepository) DeleteAll(ctx context.Context) error {
+	return r.DB.WithContext(ctx).Where("1 = 1").Delete(&models.Photo{}).Error
+}
@@ -1,89 +1,342 @@
-package repository
-
-import (
-	"context"
-	"time"
-
-	er "deu/internal/errors"
-	"deu/internal/models"
-
-	"gorm.io/gorm"
-)
-
-type PostgresPlaceRepository struct {
-	DB *gorm.DB
-}
-
-func NewPostgresPlaceRepository(db *gorm.DB) *PostgresPlaceRepository {
-	return &PostgresPlaceRepository{DB: db}
-}
-
-func (r *PostgresPlaceRepository) GetAll(ctx context.Context) ([]models.Place, error) {
-	var places []models.Place
-	if err := r.DB.WithContext(ctx).Find(&places).Error; err != nil {
-		return nil, err
-	}
-	return places, nil
-}
-
-func (r *PostgresPlaceRepository) GetByID(ctx context.Context, id string) (*models.Place, error) {
-	var place models.Place
-	if err := r.DB.WithContext(ctx).Where("id = ?", id).First(&place).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
-			return nil, er.ErrPlaceNotFound
-		}
-		return nil, err
-	}
-	return &place, nil
-}
-
-func (r *PostgresPlaceRepository) Create(ctx context.Context, p *models.Place) error {
-	return r.DB.WithContext(ctx).Create(p).Error
-}
-
-func (r *PostgresPlaceRepository) Update(ctx context.Context, id string, p *models.PlaceUpdateRequest) error {
-	updates := map[string]interface{}{}
-	if p.Name != nil {
-		updates["name"] = *p.Name
-	}
-	if p.Description != nil {
-		updates["description"] = *p.Description
-	}
-	if p.Location != nil {
-		updates["location"] = *p.Location
-	}
-	if p.Address != nil {
-		updates["address"] = *p.Address
-	}
-	if p.Rating != nil {
-		updates["rating"] = *p.Rating
-	}
-
-	updates["updated_at"] = time.Now()
-
-	result := r.DB.WithContext(ctx).Model(&models.Place{}).Where("id = ?", id).Updates(updates)
-
-	if result.Error != nil {
-		return result.Error
-	}
-	if result.RowsAffected == 0 {
-		return er.ErrPlaceNotFound
-	}
-	return nil
-}
-
-func (r *PostgresPlaceRepository) Delete(ctx context.Context, id string) error {
-	result := r.DB.WithContext(ctx).Where("id = ?", id).Delete(&models.Place{})
-	
-	if result.Error != nil {
-		return result.Error
-	}
-	if result.RowsAffected == 0 {
-		return er.ErrPlaceNotFound
-	}
-	return nil
-}
-
-func (r *PostgresPlaceRepository) DeleteAll(ctx context.Context) error {
-	return r.DB.WithContext(ctx).Unscoped().Where("1 = 1").Delete(&models.Place{}).Error
+package repository
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	er "deu/internal/errors"
+	"deu/internal/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type PostgresPlaceRepository struct {
+	DB *gorm.DB
+	// lockRows makes GetByID lock the row it reads; it is set inside a
+	// unit of work.
+	lockRows bool
+}
+
+func NewPostgresPlaceRepository(db *gorm.DB) *PostgresPlaceRepository {
+	return &PostgresPlaceRepository{DB: db}
+}
+
+// filtered applies the query's filters but not its sorting or paging.
+func (r *PostgresPlaceRepository) filtered(ctx context.Context, q models.PlaceQuery) *gorm.DB {
+	db := r.DB.WithContext(ctx).Model(&models.Place{})
+	if q.NamePrefix != "" {
+		db = db.Where("name ILIKE ?", escapeLike(q.NamePrefix)+"%")
+	}
+	if q.MinRating != nil {
+		db = db.Where("average_rating >= ?", *q.MinRating)
+	}
+	if q.MaxRating != nil {
+		db = db.Where("average_rating <= ?", *q.MaxRating)
+	}
+	if q.CreatedAfter != nil {
+		db = db.Where("created_at > ?", *q.CreatedAfter)
+	}
+	if len(q.Tags) > 0 {
+		tagged := r.DB.Table("place_tags").
+			Select("place_tags.place_id").
+			Joins("JOIN tags ON tags.id = place_tags.tag_id").
+			Where("tags.slug IN ?", q.Tags).
+			Group("place_tags.place_id").
+			Having("COUNT(*) = ?", len(q.Tags))
+		db = db.Where("places.id IN (?)", tagged)
+	}
+	return db
+}
+
+func (r *PostgresPlaceRepository) List(ctx context.Context, q models.PlaceQuery) ([]models.Place, error) {
+	column, ok := placeSortColumns[q.Sort]
+	if !ok {
+		return nil, er.ErrInvalidQuery
+	}
+
+	db := r.filtered(ctx, q)
+	if q.Cursor != nil {
+		value, err := sortValue(q.Sort, q.Cursor.Value)
+		if err != nil {
+			return nil, err
+		}
+		db = db.Where(keysetCondition(column, q.Desc), value, q.Cursor.Id)
+	}
+
+	var places []models.Place
+	err := db.
+		Preload("Tags").
+		Order(orderClause(column, q.Desc)).
+		Limit(q.Limit).
+		Offset(q.Offset).
+		Find(&places).Error
+	if err != nil {
+		return nil, err
+	}
+	return places, nil
+}
+
+func (r *PostgresPlaceRepository) Count(ctx context.Context, q models.PlaceQuery) (int64, error) {
+	var total int64
+	if err := r.filtered(ctx, q).Count(&total).Error; err != nil {
+		return 0, err
+	}
+	return total, nil
+}
+
+// Facets counts the tags of all places matching the query's filters, most
+// common first.
+func (r *PostgresPlaceRepository) Facets(ctx context.Context, q models.PlaceQuery) ([]models.TagFacet, error) {
+	var facets []models.TagFacet
+	err := r.DB.WithContext(ctx).Table("tags").
+		Select("tags.id, tags.name, tags.slug, COUNT(*) AS count").
+		Joins("JOIN place_tags ON place_tags.tag_id = tags.id").
+		Where("place_tags.place_id IN (?)", r.filtered(ctx, q).Select("places.id")).
+		Group("tags.id, tags.name, tags.slug").
+		Order("count DESC, tags.name").
+		Scan(&facets).Error
+	if err != nil {
+		return nil, err
+	}
+	return facets, nil
+}
+
+// Nearby relies on the cube and earthdistance extensions. The radius filter
+// is answered from the GiST index on ll_to_earth(latitude, longitude); the
+// earth_box check is approximate, so the exact distance is checked as well.
+func (r *PostgresPlaceRepository) Nearby(ctx context.Context, q models.GeoQuery) ([]models.PlaceWithDistance, int64, error) {
+	distance := gorm.Expr("earth_distance(ll_to_earth(?, ?), ll_to_earth(latitude, longitude))", q.Latitude, q.Longitude)
+
+	db := r.DB.WithContext(ctx).Model(&models.Place{})
+	if q.RadiusMeters > 0 {
+		db = db.
+			Where("earth_box(ll_to_earth(?, ?), ?) @> ll_to_earth(latitude, longitude)", q.Latitude, q.Longitude, q.RadiusMeters).
+			Where("? <= ?", distance, q.RadiusMeters)
+	}
+	if b := q.BBox; b != nil {
+		db = db.Where("latitude BETWEEN ? AND ?", b.MinLat, b.MaxLat)
+		if b.MinLng <= b.MaxLng {
+			db = db.Where("longitude BETWEEN ? AND ?", b.MinLng, b.MaxLng)
+		} else {
+			db = db.Where("(longitude >= ? OR longitude <= ?)", b.MinLng, b.MaxLng)
+		}
+	}
+
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var places []models.PlaceWithDistance
+	err := db.
+		Preload("Tags").
+		Select("places.*, ? AS distance_m", distance).
+		Order("distance_m, id").
+		Limit(q.Limit).
+		Offset(q.Offset).
+		Find(&places).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	return places, total, nil
+}
+
+// Search matches the weighted search_vector column with every query word
+// as a prefix, and falls back to pg_trgm word similarity on the name and
+// address so misspelt words still find their place. Both are served by the
+// GIN indexes created by the initial schema migration.
+func (r *PostgresPlaceRepository) Search(ctx context.Context, q models.SearchQuery) ([]models.PlaceSearchResult, int64, error) {
+	terms := tokenize(q.Text)
+	if len(terms) == 0 {
+		return []models.PlaceSearchResult{}, 0, nil
+	}
+	text := strings.Join(terms, " ")
+	prefixes := make([]string, len(terms))
+	for i, term := range terms {
+		prefixes[i] = term + ":*"
+	}
+	tsQuery := gorm.Expr("to_tsquery('simple', ?)", strings.Join(prefixes, " & "))
+
+	db := r.DB.WithContext(ctx).Model(&models.Place{}).
+		Where("search_vector @@ ? OR ? <% name OR ? <% address", tsQuery, text, text)
+
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	score := gorm.Expr("ts_rank(search_vector, ?) + word_similarity(?, name) + 0.4 * word_similarity(?, address)", tsQuery, text, text)
+
+	var places []models.PlaceSearchResult
+	err := db.
+		Preload("Tags").
+		Select("places.*, ? AS score", score).
+		Order("score DESC, id").
+		Limit(q.Limit).
+		Offset(q.Offset).
+		Find(&places).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	return places, total, nil
+}
+
+func (r *PostgresPlaceRepository) GetByID(ctx context.Context, id string) (*models.Place, error) {
+	db := r.DB.WithContext(ctx)
+	if r.lockRows {
+		db = db.Clauses(clause.Locking{Strength: "SHARE"})
+	}
+
+	var place models.Place
+	if err := db.Preload("Tags").Where("id = ?", id).First(&place).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, er.ErrPlaceNotFound
+		}
+		return nil, err
+	}
+	return &place, nil
+}
+
+// Create links the place to its existing tags without writing the tags
+// themselves.
+func (r *PostgresPlaceRepository) Create(ctx context.Context, p *models.Place) error {
+	return r.DB.WithContext(ctx).Omit("Tags.*").Create(p).Error
+}
+
+// Update is a compare-and-swap on the place's version, which it bumps.
+func (r *PostgresPlaceRepository) Update(ctx context.Context, id string, p *models.PlaceUpdateRequest, version int64) error {
+	updates := map[string]interface{}{}
+	if p.Name != nil {
+		updates["name"] = *p.Name
+	}
+	if p.Description != nil {
+		updates["description"] = *p.Description
+	}
+	if p.Location != nil {
+		updates["latitude"] = p.Location.Latitude
+		updates["longitude"] = p.Location.Longitude
+	}
+	if p.Address != nil {
+		updates["address"] = *p.Address
+	}
+
+	updates["updated_at"] = time.Now()
+	updates["version"] = gorm.Expr("version + 1")
+
+	db := r.DB.WithContext(ctx)
+	result := atVersion(db.Model(&models.Place{}), id, version).Updates(updates)
+
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return versionMissed(db, &models.Place{}, id, er.ErrPlaceNotFound)
+	}
+	return nil
+}
+
+func (r *PostgresPlaceRepository) SetTags(ctx context.Context, placeID string, tags []models.Tag) error {
+	return r.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Model(&models.Place{}).Where("id = ?", placeID).Count(&count).Error; err != nil {
+			return err
+		}
+		if count == 0 {
+			return er.ErrPlaceNotFound
+		}
+		// The place is there, so a missing row can only be a tag deleted
+		// since the caller looked it up.
+		err := tx.Model(&models.Place{Id: placeID}).Omit("Tags.*").Association("Tags").Replace(tags)
+		return translateForeignKey(err, er.ErrUnknownTag)
+	})
+}
+
+func (r *PostgresPlaceRepository) Delete(ctx context.Context, id string, version int64) error {
+	db := r.DB.WithContext(ctx)
+	result := atVersion(db, id, version).Delete(&models.Place{})
+	
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return versionMissed(db, &models.Place{}, id, er.ErrPlaceNotFound)
+	}
+	return nil
+}
+
+// DeleteAll soft-deletes every place and returns their ids.
+func (r *PostgresPlaceRepository) DeleteAll(ctx context.Context) ([]string, error) {
+	var deleted []models.Place
+	err := r.DB.WithContext(ctx).
+		Clauses(clause.Returning{Columns: []clause.Column{{Name: "id"}}}).
+		Where("1 = 1").
+		Delete(&deleted).Error
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]string, len(deleted))
+	for i, d := range deleted {
+		ids[i] = d.Id
+	}
+	return ids, nil
+}
+
+// ListDeleted returns soft-deleted places, most recently deleted first.
+func (r *PostgresPlaceRepository) ListDeleted(ctx context.Context, limit, offset int) ([]models.Place, int64, error) {
+	db := r.DB.WithContext(ctx).Unscoped().Model(&models.Place{}).Where("deleted_at IS NOT NULL")
+
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var places []models.Place
+	err := db.
+		Preload("Tags").
+		Order("deleted_at DESC, id").
+		Limit(limit).
+		Offset(offset).
+		Find(&places).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	return places, total, nil
+}
+
+func (r *PostgresPlaceRepository) Restore(ctx context.Context, id string) error {
+	result := r.DB.WithContext(ctx).Unscoped().Model(&models.Place{}).
+		Where("id = ? AND deleted_at IS NOT NULL", id).
+		Updates(map[string]interface{}{"deleted_at": nil, "updated_at": time.Now(), "version": gorm.Expr("version + 1")})
+
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return er.ErrPlaceNotFound
+	}
+	return nil
+}
+
+// Purge permanently removes places soft-deleted before the cutoff and
+// returns their ids. Reviews, visits, photos and tag links go with them
+// through the foreign keys.
+func (r *PostgresPlaceRepository) Purge(ctx context.Context, before time.Time) ([]string, error) {
+	var purged []models.Place
+	err := r.DB.WithContext(ctx).Unscoped().
+		Clauses(clause.Returning{Columns: []clause.Column{{Name: "id"}}}).
+		Where("deleted_at < ?", before).
+		Delete(&purged).Error
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]string, len(purged))
+	for i, p := range purged {
+		ids[i] = p.Id
+	}
+	return ids, nil
 }
\ No newline at end of file
@@ -0,0 +1,57 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	er "deu/internal/errors"
+	"deu/internal/models"
+
+	"gorm.io/gorm"
+)
+
+type PostgresRefreshTokenRepository struct {
+	DB *gorm.DB
+}
+
+func NewPostgresRefreshTokenRepository(db *gorm.DB) *PostgresRefreshTokenRepository {
+	return &PostgresRefreshTokenRepository{DB: db}
+}
+
+func (r *PostgresRefreshTokenRepository) Create(ctx context.Context, t *models.RefreshToken) error {
+	return translateDuplicate(r.DB.WithContext(ctx).Create(t).Error, er.ErrTokenExists)
+}
+
+func (r *PostgresRefreshTokenRepository) GetByID(ctx context.Context, id string) (*models.RefreshToken, error) {
+	var token models.RefreshToken
+	if err := r.DB.WithContext(ctx).Where("id = ?", id).First(&token).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, er.ErrTokenNotFound
+		}
+		return nil, err
+	}
+	return &token, nil
+}
+
+func (r *PostgresRefreshTokenRepository) Revoke(ctx context.Context, id string) error {
+	result := r.DB.WithContext(ctx).
+		Model(&models.RefreshToken{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Update("revoked_at", time.Now())
+
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return er.ErrTokenRevoked
+	}
+	return nil
+}
+
+func (r *PostgresRefreshTokenRepository) RevokeFamily(ctx context.Context, familyID string) error {
+	return r.DB.WithContext(ctx).
+		Model(&models.RefreshToken{}).
+		Where("family_id = ? AND revoked_at IS NULL", familyID).
+		Update("revoked_at", time.Now()).Error
+}
@@ -0,0 +1,141 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	er "deu/internal/errors"
+	"deu/internal/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type PostgresReviewRepository struct {
+	DB *gorm.DB
+}
+
+func NewPostgresReviewRepository(db *gorm.DB) *PostgresReviewRepository {
+	return &PostgresReviewRepository{DB: db}
+}
+
+// refreshPlaceRating recomputes the place's aggregates from its visible
+// reviews and bumps its version. It runs in the same transaction as the
+// change that triggered it.
+func refreshPlaceRating(tx *gorm.DB, placeID string) error {
+	return tx.Exec(`
+		UPDATE places SET
+			average_rating = COALESCE((SELECT ROUND(AVG(rating), 2) FROM reviews WHERE place_id = @place AND NOT hidden), 0),
+			review_count = (SELECT COUNT(*) FROM reviews WHERE place_id = @place AND NOT hidden),
+			version = version + 1
+		WHERE id = @place`,
+		map[string]interface{}{"place": placeID},
+	).Error
+}
+
+func (r *PostgresReviewRepository) Save(ctx context.Context, rv *models.Review) error {
+	return r.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "place_id"}, {Name: "user_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"rating", "comment", "updated_at"}),
+		}).Create(rv).Error
+		if err != nil {
+			return translateDuplicate(err, er.ErrReviewExists)
+		}
+		// After a conflict the row keeps its own id, so read it back.
+		var stored models.Review
+		err = tx.Where("user_id = ?", rv.UserID).Where("place_id = ?", rv.PlaceID).First(&stored).Error
+		if err != nil {
+			return err
+		}
+		*rv = stored
+		return refreshPlaceRating(tx, rv.PlaceID)
+	})
+}
+
+func (r *PostgresReviewRepository) GetByID(ctx context.Context, id string) (*models.Review, error) {
+	var rv models.Review
+	if err := r.DB.WithContext(ctx).Where("id = ?", id).First(&rv).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, er.ErrReviewNotFound
+		}
+		return nil, err
+	}
+	return &rv, nil
+}
+
+func (r *PostgresReviewRepository) GetByUserAndPlace(ctx context.Context, userID, placeID string) (*models.Review, error) {
+	var rv models.Review
+	err := r.DB.WithContext(ctx).
+		Where("user_id = ?", userID).
+		Where("place_id = ?", placeID).
+		First(&rv).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, er.ErrReviewNotFound
+		}
+		return nil, err
+	}
+	return &rv, nil
+}
+
+func (r *PostgresReviewRepository) ListByPlace(ctx context.Context, placeID string, includeHidden bool, limit, offset int) ([]models.Review, int64, error) {
+	query := r.DB.WithContext(ctx).
+		Model(&models.Review{}).
+		Where("place_id = ?", placeID)
+	if !includeHidden {
+		query = query.Where("NOT hidden")
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var reviews []models.Review
+	err := query.
+		Order("updated_at DESC, id").
+		Limit(limit).
+		Offset(offset).
+		Find(&reviews).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	return reviews, total, nil
+}
+
+func (r *PostgresReviewRepository) SetHidden(ctx context.Context, id string, hidden bool, moderatorID string, at time.Time) error {
+	return r.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var rv models.Review
+		if err := tx.Where("id = ?", id).First(&rv).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return er.ErrReviewNotFound
+			}
+			return err
+		}
+
+		updates := map[string]interface{}{"hidden": hidden, "hidden_by": nil, "hidden_at": nil}
+		if hidden {
+			updates["hidden_by"] = moderatorID
+			updates["hidden_at"] = at
+		}
+		if err := tx.Model(&models.Review{}).Where("id = ?", id).Updates(updates).Error; err != nil {
+			return err
+		}
+		return refreshPlaceRating(tx, rv.PlaceID)
+	})
+}
+
+func (r *PostgresReviewRepository) Delete(ctx context.Context, id string) error {
+	return r.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var rv models.Review
+		if err := tx.Clauses(clause.Returning{}).Where("id = ?", id).Delete(&rv).Error; err != nil {
+			return err
+		}
+		if rv.Id == "" {
+			return er.ErrReviewNotFound
+		}
+		return refreshPlaceRating(tx, rv.PlaceID)
+	})
+}
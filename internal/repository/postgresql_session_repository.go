@@ -0,0 +1,42 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	er "deu/internal/errors"
+	"deu/internal/models"
+
+	"gorm.io/gorm"
+)
+
+type PostgresSessionRepository struct {
+	DB *gorm.DB
+}
+
+func NewPostgresSessionRepository(db *gorm.DB) *PostgresSessionRepository {
+	return &PostgresSessionRepository{DB: db}
+}
+
+func (r *PostgresSessionRepository) Create(ctx context.Context, s *models.Session) error {
+	return translateDuplicate(r.DB.WithContext(ctx).Create(s).Error, er.ErrSessionExists)
+}
+
+func (r *PostgresSessionRepository) GetByID(ctx context.Context, id string) (*models.Session, error) {
+	var session models.Session
+	if err := r.DB.WithContext(ctx).Where("id = ?", id).First(&session).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, er.ErrSessionNotFound
+		}
+		return nil, err
+	}
+	return &session, nil
+}
+
+func (r *PostgresSessionRepository) Delete(ctx context.Context, id string) error {
+	return r.DB.WithContext(ctx).Where("id = ?", id).Delete(&models.Session{}).Error
+}
+
+func (r *PostgresSessionRepository) DeleteByUser(ctx context.Context, userID string) error {
+	return r.DB.WithContext(ctx).Where("user_id = ?", userID).Delete(&models.Session{}).Error
+}
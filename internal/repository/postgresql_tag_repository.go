@@ -0,0 +1,90 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	er "deu/internal/errors"
+	"deu/internal/models"
+
+	"gorm.io/gorm"
+)
+
+type PostgresTagRepository struct {
+	DB *gorm.DB
+}
+
+func NewPostgresTagRepository(db *gorm.DB) *PostgresTagRepository {
+	return &PostgresTagRepository{DB: db}
+}
+
+func (r *PostgresTagRepository) List(ctx context.Context) ([]models.Tag, error) {
+	var tags []models.Tag
+	if err := r.DB.WithContext(ctx).Order("name, id").Find(&tags).Error; err != nil {
+		return nil, err
+	}
+	return tags, nil
+}
+
+func (r *PostgresTagRepository) GetByID(ctx context.Context, id string) (*models.Tag, error) {
+	var tag models.Tag
+	if err := r.DB.WithContext(ctx).Where("id = ?", id).First(&tag).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, er.ErrTagNotFound
+		}
+		return nil, err
+	}
+	return &tag, nil
+}
+
+func (r *PostgresTagRepository) GetBySlugs(ctx context.Context, slugs []string) ([]models.Tag, error) {
+	var tags []models.Tag
+	if err := r.DB.WithContext(ctx).Where("slug IN ?", slugs).Order("name, id").Find(&tags).Error; err != nil {
+		return nil, err
+	}
+	return tags, nil
+}
+
+func (r *PostgresTagRepository) Create(ctx context.Context, t *models.Tag) error {
+	return translateDuplicate(r.DB.WithContext(ctx).Create(t).Error, er.ErrTagExists)
+}
+
+// touchTagged bumps the version of every place carrying the tag, since the
+// tag is part of their representation.
+func touchTagged(tx *gorm.DB, tagID string) error {
+	tagged := tx.Table("place_tags").Select("place_id").Where("tag_id = ?", tagID)
+	return tx.Model(&models.Place{}).
+		Where("id IN (?)", tagged).
+		Update("version", gorm.Expr("version + 1")).Error
+}
+
+func (r *PostgresTagRepository) Update(ctx context.Context, t *models.Tag) error {
+	return r.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.Tag{}).Where("id = ?", t.Id).
+			Updates(map[string]interface{}{"name": t.Name, "slug": t.Slug})
+		if result.Error != nil {
+			return translateDuplicate(result.Error, er.ErrTagExists)
+		}
+		if result.RowsAffected == 0 {
+			return er.ErrTagNotFound
+		}
+		return touchTagged(tx, t.Id)
+	})
+}
+
+// Delete relies on ON DELETE CASCADE to remove the tag's place_tags rows.
+func (r *PostgresTagRepository) Delete(ctx context.Context, id string) error {
+	return r.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := touchTagged(tx, id); err != nil {
+			return err
+		}
+		result := tx.Where("id = ?", id).Delete(&models.Tag{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return er.ErrTagNotFound
+		}
+		return nil
+	})
+}
@@ -0,0 +1,29 @@
+package repository
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+)
+
+type PostgresUnitOfWork struct {
+	DB *gorm.DB
+}
+
+func NewPostgresUnitOfWork(db *gorm.DB) *PostgresUnitOfWork {
+	return &PostgresUnitOfWork{DB: db}
+}
+
+// WithTx runs fn in a database transaction. GetByID on the users and places
+// of tx reads with FOR SHARE, so concurrent updates and deletes of those rows
+// wait until the transaction ends.
+func (u *PostgresUnitOfWork) WithTx(ctx context.Context, fn func(tx Repos) error) error {
+	return u.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return fn(Repos{
+			Users:      &PostgresUserRepository{DB: tx, lockRows: true},
+			Places:     &PostgresPlaceRepository{DB: tx, lockRows: true},
+			UserPlaces: &PostgresUserPlaceRepository{DB: tx},
+			Reviews:    &PostgresReviewRepository{DB: tx},
+		})
+	})
+}
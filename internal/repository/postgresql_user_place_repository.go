@@ -1,55 +1,108 @@
-package repository
-
-import (
-	"context"
-
-	"deu/internal/models"
-
-	"gorm.io/gorm"
-)
-
-type PostgresUserPlaceRepository struct {
-	DB *gorm.DB
-}
-
-func NewPostgresUserPlaceRepository(db *gorm.DB) *PostgresUserPlaceRepository {
-	return &PostgresUserPlaceRepository{DB: db}
-}
-
-func (r *PostgresUserPlaceRepository) AddVisitedPlace(ctx context.Context, userID, placeID string) error {
-	userPlace := models.UserPlace{
-		UserID: userID,
-		PlaceID: placeID,
-	}
-	result := r.DB.WithContext(ctx).FirstOrCreate(&userPlace, userPlace)
-	
-	return result.Error
-}
-
-func (r *PostgresUserPlaceRepository) HasVisitedPlace(ctx context.Context, userID, placeID string) (bool, error) {
-	var userPlace models.UserPlace
-	
-	err := r.DB.WithContext(ctx).
-		Where("user_id = ?", userID).
-		Where("place_id = ?", placeID).
-		First(&userPlace).Error
-
-	if err == nil {
-		return true, nil
-	}
-
-	if err == gorm.ErrRecordNotFound {
-		return false, nil
-	}
-
-	return false, err
-}
-
-func (r *PostgresUserPlaceRepository) RemoveVisitedPlace(ctx context.Context, userID, placeID string) error {
-	result := r.DB.WithContext(ctx).
-		Where("user_id = ?", userID).
-		Where("place_id = ?", placeID).
-		Delete(&models.UserPlace{})
-	
-	return result.Error
+package repository
+
+import (
+	"context"
+
+	er "deu/internal/errors"
+	"deu/internal/models"
+
+	"gorm.io/gorm"
+)
+
+type PostgresUserPlaceRepository struct {
+	DB *gorm.DB
+}
+
+func NewPostgresUserPlaceRepository(db *gorm.DB) *PostgresUserPlaceRepository {
+	return &PostgresUserPlaceRepository{DB: db}
+}
+
+func (r *PostgresUserPlaceRepository) AddVisitedPlace(ctx context.Context, visit *models.UserPlace) error {
+	result := r.DB.WithContext(ctx).Create(visit)
+	
+	return translateDuplicate(result.Error, er.ErrVisitExists)
+}
+
+func (r *PostgresUserPlaceRepository) ListVisits(ctx context.Context, userID, placeID string) ([]models.UserPlace, error) {
+	var visits []models.UserPlace
+	
+	err := r.DB.WithContext(ctx).
+		Where("user_id = ?", userID).
+		Where("place_id = ?", placeID).
+		Order("visited_at DESC, id").
+		Find(&visits).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return visits, nil
+}
+
+func (r *PostgresUserPlaceRepository) RemoveVisitedPlace(ctx context.Context, userID, placeID string) error {
+	result := r.DB.WithContext(ctx).
+		Where("user_id = ?", userID).
+		Where("place_id = ?", placeID).
+		Delete(&models.UserPlace{})
+	
+	return result.Error
+}
+
+func (r *PostgresUserPlaceRepository) RemoveVisit(ctx context.Context, userID, visitID string) error {
+	result := r.DB.WithContext(ctx).
+		Where("id = ?", visitID).
+		Where("user_id = ?", userID).
+		Delete(&models.UserPlace{})
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return er.ErrVisitNotFound
+	}
+
+	return nil
+}
+
+func (r *PostgresUserPlaceRepository) ListVisitedPlaces(ctx context.Context, userID string, limit, offset int) ([]models.Place, int64, error) {
+	query := r.DB.WithContext(ctx).
+		Model(&models.Place{}).
+		Where("places.id IN (?)", r.DB.Model(&models.UserPlace{}).Select("place_id").Where("user_id = ?", userID))
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var places []models.Place
+	err := query.
+		Order("places.name, places.id").
+		Limit(limit).
+		Offset(offset).
+		Find(&places).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	return places, total, nil
+}
+
+func (r *PostgresUserPlaceRepository) ListVisitors(ctx context.Context, placeID string, limit, offset int) ([]models.User, int64, error) {
+	query := r.DB.WithContext(ctx).
+		Model(&models.User{}).
+		Where("users.id IN (?)", r.DB.Model(&models.UserPlace{}).Select("user_id").Where("place_id = ?", placeID))
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var users []models.User
+	err := query.
+		Order("users.name, users.id").
+		Limit(limit).
+		Offset(offset).
+		Find(&users).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	return users, total, nil
 }
\ No newline at end of file
@@ -0,0 +1,162 @@
+package repository
+
+import (
+	"cmp"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	er "deu/internal/errors"
+	"deu/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// atVersion limits a write to the row with id while it is still at version.
+// A version of 0 matches any version.
+func atVersion(db *gorm.DB, id string, version int64) *gorm.DB {
+	db = db.Where("id = ?", id)
+	if version != 0 {
+		db = db.Where("version = ?", version)
+	}
+	return db
+}
+
+// versionMissed explains a versioned write that matched no row: notFound when
+// the row is gone, ErrVersionMismatch when someone else changed it first.
+func versionMissed(db *gorm.DB, model interface{}, id string, notFound error) error {
+	var count int64
+	if err := db.Model(model).Where("id = ?", id).Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		return notFound
+	}
+	return er.ErrVersionMismatch
+}
+
+var placeSortColumns = map[string]string{
+	models.PlaceSortName:      "name",
+	models.PlaceSortCreatedAt: "created_at",
+	models.PlaceSortRating:    "average_rating",
+}
+
+var userSortColumns = map[string]string{
+	models.UserSortName:      "name",
+	models.UserSortEmail:     "email",
+	models.UserSortCreatedAt: "created_at",
+}
+
+// sortValue converts a cursor value back into the type of its sort field so
+// it compares the same way the column does. Places and users share the
+// createdAt field name.
+func sortValue(field, value string) (interface{}, error) {
+	switch field {
+	case models.PlaceSortCreatedAt:
+		t, err := time.Parse(time.RFC3339Nano, value)
+		if err != nil {
+			return nil, er.ErrInvalidQuery
+		}
+		return t, nil
+	case models.PlaceSortRating:
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, er.ErrInvalidQuery
+		}
+		return f, nil
+	default:
+		return value, nil
+	}
+}
+
+// compareSortValues orders two values returned by sortValue.
+func compareSortValues(a, b interface{}) int {
+	switch av := a.(type) {
+	case time.Time:
+		return av.Compare(b.(time.Time))
+	case float64:
+		return cmp.Compare(av, b.(float64))
+	default:
+		return strings.Compare(a.(string), b.(string))
+	}
+}
+
+// keysetCondition returns the WHERE clause that continues a listing after
+// the row the cursor points at.
+func keysetCondition(column string, desc bool) string {
+	if desc {
+		return fmt.Sprintf("(%s, id) < (?, ?)", column)
+	}
+	return fmt.Sprintf("(%s, id) > (?, ?)", column)
+}
+
+func orderClause(column string, desc bool) string {
+	if desc {
+		return column + " DESC, id DESC"
+	}
+	return column + " ASC, id ASC"
+}
+
+// escapeLike escapes the LIKE wildcards in user input.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+// keysetPage sorts items by the given field and id, skips everything up to
+// the cursor and returns the requested window. It is the in-memory
+// counterpart of the keyset queries the Postgres repositories run.
+func keysetPage[T any](items []T, sortValueOf func(T) string, idOf func(T) string, field string, desc bool, cursor *models.Cursor, limit, offset int) ([]T, error) {
+	type keyed struct {
+		item  T
+		value interface{}
+		id    string
+	}
+
+	compare := func(av interface{}, aID string, bv interface{}, bID string) int {
+		if c := compareSortValues(av, bv); c != 0 {
+			return c
+		}
+		return strings.Compare(aID, bID)
+	}
+
+	rows := make([]keyed, 0, len(items))
+	for _, item := range items {
+		v, err := sortValue(field, sortValueOf(item))
+		if err != nil {
+			return nil, err
+		}
+		rows = append(rows, keyed{item: item, value: v, id: idOf(item)})
+	}
+
+	sort.Slice(rows, func(i, j int) bool {
+		c := compare(rows[i].value, rows[i].id, rows[j].value, rows[j].id)
+		if desc {
+			return c > 0
+		}
+		return c < 0
+	})
+
+	if cursor != nil {
+		cv, err := sortValue(field, cursor.Value)
+		if err != nil {
+			return nil, err
+		}
+		start := len(rows)
+		for i, row := range rows {
+			c := compare(row.value, row.id, cv, cursor.Id)
+			if (!desc && c > 0) || (desc && c < 0) {
+				start = i
+				break
+			}
+		}
+		rows = rows[start:]
+	}
+
+	result := make([]T, 0, len(rows))
+	for _, row := range paginate(rows, limit, offset) {
+		result = append(result, row.item)
+	}
+	return result, nil
+}
@@ -0,0 +1,16 @@
+package repository
+
+import (
+	"context"
+	"deu/internal/models"
+)
+
+type RefreshTokenRepository interface {
+	Create(ctx context.Context, t *models.RefreshToken) error
+	GetByID(ctx context.Context, id string) (*models.RefreshToken, error)
+	// Revoke marks a single token as used. It returns er.ErrTokenRevoked if
+	// the token had already been revoked, so concurrent refreshes with the
+	// same token cannot both succeed.
+	Revoke(ctx context.Context, id string) error
+	RevokeFamily(ctx context.Context, familyID string) error
+}
@@ -0,0 +1,22 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"deu/internal/models"
+)
+
+// ReviewRepository stores reviews and keeps each place's averageRating and
+// reviewCount in step with its visible reviews.
+type ReviewRepository interface {
+	// Save creates rv or, if its user has already reviewed the place,
+	// updates the rating and comment of that review. Either way rv is set
+	// to the review as stored, which keeps its original id.
+	Save(ctx context.Context, rv *models.Review) error
+	GetByID(ctx context.Context, id string) (*models.Review, error)
+	GetByUserAndPlace(ctx context.Context, userID, placeID string) (*models.Review, error)
+	ListByPlace(ctx context.Context, placeID string, includeHidden bool, limit, offset int) ([]models.Review, int64, error)
+	SetHidden(ctx context.Context, id string, hidden bool, moderatorID string, at time.Time) error
+	Delete(ctx context.Context, id string) error
+}
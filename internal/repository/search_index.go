@@ -0,0 +1,181 @@
+package repository
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+
+	"deu/internal/models"
+)
+
+// Field weights mirror the A/B/C weights of the Postgres search vector.
+const (
+	nameWeight        = 1.0
+	addressWeight     = 0.4
+	descriptionWeight = 0.2
+
+	// fuzzyPenalty scales matches found through typo tolerance.
+	fuzzyPenalty = 0.5
+)
+
+// tokenize lower-cases text and splits it into letter and digit runs.
+func tokenize(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// maxTypos is how many edits a query term of the given length tolerates.
+func maxTypos(term string) int {
+	switch n := len([]rune(term)); {
+	case n >= 8:
+		return 2
+	case n >= 4:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// editDistance is the Levenshtein distance between a and b, giving up once
+// it exceeds max.
+func editDistance(a, b string, max int) int {
+	ra, rb := []rune(a), []rune(b)
+	if d := len(ra) - len(rb); d > max || -d > max {
+		return max + 1
+	}
+
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		rowMin := curr[0]
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+			rowMin = min(rowMin, curr[j])
+		}
+		if rowMin > max {
+			return max + 1
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(rb)]
+}
+
+// searchIndex is an inverted index from tokens to the places containing
+// them, with the best field weight each place has for the token. It is not
+// safe for concurrent use; MemoryPlaceRepository guards it with its lock.
+type searchIndex struct {
+	postings map[string]map[string]float64
+	docs     map[string][]string
+}
+
+func newSearchIndex() *searchIndex {
+	return &searchIndex{
+		postings: make(map[string]map[string]float64),
+		docs:     make(map[string][]string),
+	}
+}
+
+func (idx *searchIndex) add(p models.Place) {
+	idx.remove(p.Id)
+
+	weights := make(map[string]float64)
+	for _, field := range []struct {
+		text   string
+		weight float64
+	}{{p.Name, nameWeight}, {p.Address, addressWeight}, {p.Description, descriptionWeight}} {
+		for _, token := range tokenize(field.text) {
+			weights[token] = max(weights[token], field.weight)
+		}
+	}
+
+	tokens := make([]string, 0, len(weights))
+	for token, weight := range weights {
+		if idx.postings[token] == nil {
+			idx.postings[token] = make(map[string]float64)
+		}
+		idx.postings[token][p.Id] = weight
+		tokens = append(tokens, token)
+	}
+	idx.docs[p.Id] = tokens
+}
+
+func (idx *searchIndex) remove(id string) {
+	for _, token := range idx.docs[id] {
+		delete(idx.postings[token], id)
+		if len(idx.postings[token]) == 0 {
+			delete(idx.postings, token)
+		}
+	}
+	delete(idx.docs, id)
+}
+
+// termScores scores every place matching one query term. A token matches
+// exactly, as a prefix of a longer token, or within the term's typo budget.
+func (idx *searchIndex) termScores(term string) map[string]float64 {
+	scores := make(map[string]float64)
+	typos := maxTypos(term)
+
+	for token, places := range idx.postings {
+		factor := 0.0
+		switch {
+		case token == term:
+			factor = 1
+		case strings.HasPrefix(token, term):
+			factor = 0.8
+		case typos > 0 && editDistance(term, token, typos) <= typos:
+			factor = fuzzyPenalty
+		default:
+			continue
+		}
+		for id, weight := range places {
+			scores[id] = max(scores[id], weight*factor)
+		}
+	}
+	return scores
+}
+
+// search returns the ids of places matching every term of the query with
+// their scores, best first.
+func (idx *searchIndex) search(text string) ([]string, map[string]float64) {
+	terms := tokenize(text)
+	if len(terms) == 0 {
+		return nil, nil
+	}
+
+	var total map[string]float64
+	for _, term := range terms {
+		scores := idx.termScores(term)
+		if total == nil {
+			total = scores
+			continue
+		}
+		for id := range total {
+			if s, ok := scores[id]; ok {
+				total[id] += s
+			} else {
+				delete(total, id)
+			}
+		}
+	}
+
+	ids := make([]string, 0, len(total))
+	for id := range total {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		if total[ids[i]] != total[ids[j]] {
+			return total[ids[i]] > total[ids[j]]
+		}
+		return ids[i] < ids[j]
+	})
+	return ids, total
+}
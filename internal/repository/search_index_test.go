@@ -0,0 +1,325 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"deu/internal/models"
+)
+
+func TestTokenize(t *testing.T) {
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{"", nil},
+		{"   ", nil},
+		{"Eiffel Tower", []string{"eiffel", "tower"}},
+		{"Champ de Mars, 5 Av. Anatole-France", []string{"champ", "de", "mars", "5", "av", "anatole", "france"}},
+		{"Café Müller", []string{"café", "müller"}},
+		{"route66", []string{"route66"}},
+		{"--!!--", nil},
+	}
+	for _, tt := range tests {
+		got := tokenize(tt.text)
+		if len(got) == 0 && len(tt.want) == 0 {
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("tokenize(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestMaxTypos(t *testing.T) {
+	tests := []struct {
+		term string
+		want int
+	}{
+		{"eif", 0},
+		{"park", 1},
+		{"tower", 1},
+		{"cathedra", 2},
+		{"cathedral", 2},
+		{"müll", 1},
+	}
+	for _, tt := range tests {
+		if got := maxTypos(tt.term); got != tt.want {
+			t.Errorf("maxTypos(%q) = %d, want %d", tt.term, got, tt.want)
+		}
+	}
+}
+
+func TestEditDistance(t *testing.T) {
+	tests := []struct {
+		a, b string
+		max  int
+		want int
+	}{
+		{"tower", "tower", 1, 0},
+		{"towr", "tower", 1, 1},
+		{"towre", "tower", 2, 2},
+		{"tower", "power", 1, 1},
+		{"café", "cafe", 1, 1},
+		{"cathedral", "catedrals", 2, 2},
+		// A transposition is two edits.
+		{"louvre", "lovure", 2, 2},
+		// Past max the exact distance does not matter, only that it is over.
+		{"tower", "bridge", 1, 2},
+		{"a", "abcd", 2, 3},
+	}
+	for _, tt := range tests {
+		if got := editDistance(tt.a, tt.b, tt.max); got != tt.want {
+			t.Errorf("editDistance(%q, %q, %d) = %d, want %d", tt.a, tt.b, tt.max, got, tt.want)
+		}
+	}
+}
+
+// searchPlaces are indexed by the search tests below; their ids sort in the
+// order they are listed.
+var searchPlaces = []models.Place{
+	{Id: "1", Name: "Eiffel Tower", Address: "Champ de Mars, Paris", Description: "Wrought-iron lattice tower"},
+	{Id: "2", Name: "Tower Bridge", Address: "Tower Bridge Road, London", Description: "Bascule and suspension bridge"},
+	{Id: "3", Name: "Louvre Museum", Address: "Rue de Rivoli, Paris", Description: "Home of the Mona Lisa"},
+	{Id: "4", Name: "Notre-Dame Cathedral", Address: "Parvis Notre-Dame, Paris", Description: "Gothic cathedral"},
+	{Id: "5", Name: "Old Harbour", Address: "Quay Street", Description: "Fishing boats next to the old tower"},
+}
+
+func newTestIndex() *searchIndex {
+	idx := newSearchIndex()
+	for _, p := range searchPlaces {
+		idx.add(p)
+	}
+	return idx
+}
+
+func TestSearchIndexMatches(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  []string
+	}{
+		{"empty query", "", nil},
+		{"punctuation only", "?!", nil},
+		{"no match", "colosseum", nil},
+		{"exact name", "louvre", []string{"3"}},
+		{"case insensitive", "LOUVRE", []string{"3"}},
+		{"name ranks above description, ties by id", "tower", []string{"1", "2", "5"}},
+		{"prefix", "cathed", []string{"4"}},
+		{"prefix of a short term", "lo", []string{"3", "2"}},
+		{"every term must match", "tower paris", []string{"1"}},
+		{"terms in any field", "gothic paris", []string{"4"}},
+		{"one typo", "luvre", []string{"3"}},
+		{"two typos in a long term", "catedrals", []string{"4"}},
+		{"one typo is all a medium term gets", "lovure", nil},
+		{"short terms need an exact or prefix match", "tke", nil},
+		{"too many typos", "lvre", nil},
+	}
+	idx := newTestIndex()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, _ := idx.search(tt.query)
+			if len(got) == 0 && len(tt.want) == 0 {
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("search(%q) = %q, want %q", tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSearchIndexScores(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		id    string
+		want  float64
+	}{
+		{"exact in name", "louvre", "3", nameWeight},
+		{"exact in address", "rivoli", "3", addressWeight},
+		{"exact in description", "mona", "3", descriptionWeight},
+		{"prefix in name", "louv", "3", nameWeight * 0.8},
+		{"typo in name", "luvre", "3", nameWeight * fuzzyPenalty},
+		{"best field wins", "tower", "1", nameWeight},
+		{"terms add up", "louvre paris", "3", nameWeight + addressWeight},
+	}
+	idx := newTestIndex()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, scores := idx.search(tt.query)
+			got, ok := scores[tt.id]
+			if !ok {
+				t.Fatalf("search(%q) did not match place %s", tt.query, tt.id)
+			}
+			if diff := got - tt.want; diff > 1e-9 || diff < -1e-9 {
+				t.Errorf("search(%q) scored place %s %v, want %v", tt.query, tt.id, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSearchIndexRankingOrder(t *testing.T) {
+	idx := newSearchIndex()
+	for _, p := range []models.Place{
+		{Id: "a", Name: "Harbor"},                           // typo
+		{Id: "b", Description: "A walk by the harbour"},     // exact, description
+		{Id: "c", Name: "Harbourside"},                      // prefix, name
+		{Id: "d", Address: "1 Harbour Lane"},                // exact, address
+		{Id: "e", Name: "Harbour"},                          // exact, name
+		{Id: "f", Name: "Harbour", Address: "Harbour Lane"}, // exact, ties with e
+	} {
+		idx.add(p)
+	}
+
+	got, _ := idx.search("harbour")
+	want := []string{"e", "f", "c", "a", "d", "b"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("search ranked %q, want %q", got, want)
+	}
+}
+
+func TestSearchIndexAddReplacesAndRemoveForgets(t *testing.T) {
+	idx := newTestIndex()
+
+	idx.add(models.Place{Id: "3", Name: "Musée d'Orsay", Address: "Rue de la Légion d'Honneur, Paris"})
+	if got, _ := idx.search("louvre"); len(got) != 0 {
+		t.Errorf("search found %q under the replaced name", got)
+	}
+	if got, _ := idx.search("orsay"); !reflect.DeepEqual(got, []string{"3"}) {
+		t.Errorf("search(orsay) = %q, want [3]", got)
+	}
+
+	idx.remove("3")
+	if got, _ := idx.search("orsay"); len(got) != 0 {
+		t.Errorf("search found %q after the place was removed", got)
+	}
+	if _, ok := idx.postings["orsay"]; ok {
+		t.Error("removing the only place with a token left its posting list behind")
+	}
+	if _, ok := idx.docs["3"]; ok {
+		t.Error("removing a place left its document behind")
+	}
+
+	// Removing an unknown place is a no-op.
+	idx.remove("missing")
+}
+
+// searchIDs runs a search on the repository and returns the ids it found.
+func searchIDs(t *testing.T, r *MemoryPlaceRepository, text string) []string {
+	t.Helper()
+	results, _, err := r.Search(context.Background(), models.SearchQuery{Text: text, Limit: 100})
+	if err != nil {
+		t.Fatalf("Search(%q): %v", text, err)
+	}
+	ids := make([]string, 0, len(results))
+	for _, p := range results {
+		ids = append(ids, p.Id)
+	}
+	return ids
+}
+
+func expectSearch(t *testing.T, r *MemoryPlaceRepository, text string, want ...string) {
+	t.Helper()
+	got := searchIDs(t, r, text)
+	if len(got) == 0 && len(want) == 0 {
+		return
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Search(%q) = %q, want %q", text, got, want)
+	}
+}
+
+func TestMemoryPlaceRepositoryKeepsIndexCurrent(t *testing.T) {
+	ctx := context.Background()
+	r := NewMemoryPlaceRepository()
+
+	place := models.Place{Id: "p1", Name: "Louvre Museum", Address: "Rue de Rivoli", Version: 1}
+	if err := r.Create(ctx, &place); err != nil {
+		t.Fatal(err)
+	}
+	expectSearch(t, r, "louvre", "p1")
+
+	name := "Orangerie Museum"
+	if err := r.Update(ctx, "p1", &models.PlaceUpdateRequest{Name: &name}, 0); err != nil {
+		t.Fatal(err)
+	}
+	expectSearch(t, r, "louvre")
+	expectSearch(t, r, "orangerie", "p1")
+	expectSearch(t, r, "rivoli", "p1")
+
+	if err := r.Delete(ctx, "p1", 0); err != nil {
+		t.Fatal(err)
+	}
+	expectSearch(t, r, "orangerie")
+
+	if err := r.Restore(ctx, "p1"); err != nil {
+		t.Fatal(err)
+	}
+	expectSearch(t, r, "orangerie", "p1")
+
+	if _, err := r.DeleteAll(ctx); err != nil {
+		t.Fatal(err)
+	}
+	expectSearch(t, r, "orangerie")
+}
+
+var errRollback = errors.New("roll back")
+
+func TestMemoryUnitOfWorkRollbackRestoresIndex(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name string
+		fn   func(tx Repos) error
+	}{
+		{"update", func(tx Repos) error {
+			name := "Orangerie Museum"
+			return tx.Places.Update(ctx, "p1", &models.PlaceUpdateRequest{Name: &name}, 0)
+		}},
+		{"delete", func(tx Repos) error {
+			return tx.Places.Delete(ctx, "p1", 0)
+		}},
+		{"delete all", func(tx Repos) error {
+			_, err := tx.Places.DeleteAll(ctx)
+			return err
+		}},
+		{"create", func(tx Repos) error {
+			return tx.Places.Create(ctx, &models.Place{Id: "p3", Name: "Orangerie Museum", Version: 1})
+		}},
+		{"restore", func(tx Repos) error {
+			return tx.Places.Restore(ctx, "p2")
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := NewMemoryBackend()
+			live := models.Place{Id: "p1", Name: "Louvre Museum", Version: 1}
+			trashed := models.Place{Id: "p2", Name: "Pompidou Centre", Version: 1}
+			for _, p := range []*models.Place{&live, &trashed} {
+				if err := b.Places.Create(ctx, p); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := b.Places.Delete(ctx, "p2", 0); err != nil {
+				t.Fatal(err)
+			}
+
+			err := b.UnitOfWork.WithTx(ctx, func(tx Repos) error {
+				if err := tt.fn(tx); err != nil {
+					t.Fatal(err)
+				}
+				return errRollback
+			})
+			if !errors.Is(err, errRollback) {
+				t.Fatalf("WithTx returned %v, want the callback's error", err)
+			}
+
+			expectSearch(t, b.Places, "louvre", "p1")
+			expectSearch(t, b.Places, "orangerie")
+			expectSearch(t, b.Places, "pompidou")
+		})
+	}
+}
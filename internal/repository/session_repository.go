@@ -0,0 +1,13 @@
+package repository
+
+import (
+	"context"
+	"deu/internal/models"
+)
+
+type SessionRepository interface {
+	Create(ctx context.Context, s *models.Session) error
+	GetByID(ctx context.Context, id string) (*models.Session, error)
+	Delete(ctx context.Context, id string) error
+	DeleteByUser(ctx context.Context, userID string) error
+}
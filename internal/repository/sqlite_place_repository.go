@@ -0,0 +1,377 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sort"
+	"time"
+
+	er "deu/internal/errors"
+	"deu/internal/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// metersPerDegreeLat is the length of one degree of latitude on the sphere
+// haversineMeters uses.
+const metersPerDegreeLat = earthRadiusMeters * math.Pi / 180
+
+// SQLitePlaceRepository keeps places in a database opened with
+// db.InitSQLite. SQLite has neither earthdistance nor full-text ranking
+// like Postgres, so Nearby and Search narrow the candidates in SQL and
+// compute distances and scores the way the in-memory repository does.
+type SQLitePlaceRepository struct {
+	DB *gorm.DB
+}
+
+func NewSQLitePlaceRepository(db *gorm.DB) *SQLitePlaceRepository {
+	return &SQLitePlaceRepository{DB: db}
+}
+
+// filtered applies the query's filters but not its sorting or paging.
+// SQLite's LIKE already ignores case.
+func (r *SQLitePlaceRepository) filtered(ctx context.Context, q models.PlaceQuery) *gorm.DB {
+	db := r.DB.WithContext(ctx).Model(&models.Place{})
+	if q.NamePrefix != "" {
+		db = db.Where(`name LIKE ? ESCAPE '\'`, escapeLike(q.NamePrefix)+"%")
+	}
+	if q.MinRating != nil {
+		db = db.Where("average_rating >= ?", *q.MinRating)
+	}
+	if q.MaxRating != nil {
+		db = db.Where("average_rating <= ?", *q.MaxRating)
+	}
+	if q.CreatedAfter != nil {
+		db = db.Where("created_at > ?", *q.CreatedAfter)
+	}
+	if len(q.Tags) > 0 {
+		tagged := r.DB.Table("place_tags").
+			Select("place_tags.place_id").
+			Joins("JOIN tags ON tags.id = place_tags.tag_id").
+			Where("tags.slug IN ?", q.Tags).
+			Group("place_tags.place_id").
+			Having("COUNT(*) = ?", len(q.Tags))
+		db = db.Where("places.id IN (?)", tagged)
+	}
+	return db
+}
+
+func (r *SQLitePlaceRepository) List(ctx context.Context, q models.PlaceQuery) ([]models.Place, error) {
+	column, ok := placeSortColumns[q.Sort]
+	if !ok {
+		return nil, er.ErrInvalidQuery
+	}
+
+	db := r.filtered(ctx, q)
+	if q.Cursor != nil {
+		value, err := sortValue(q.Sort, q.Cursor.Value)
+		if err != nil {
+			return nil, err
+		}
+		db = db.Where(keysetCondition(column, q.Desc), value, q.Cursor.Id)
+	}
+
+	var places []models.Place
+	err := db.
+		Preload("Tags").
+		Order(orderClause(column, q.Desc)).
+		Limit(q.Limit).
+		Offset(q.Offset).
+		Find(&places).Error
+	if err != nil {
+		return nil, err
+	}
+	return places, nil
+}
+
+func (r *SQLitePlaceRepository) Count(ctx context.Context, q models.PlaceQuery) (int64, error) {
+	var total int64
+	if err := r.filtered(ctx, q).Count(&total).Error; err != nil {
+		return 0, err
+	}
+	return total, nil
+}
+
+// Facets counts the tags of all places matching the query's filters, most
+// common first.
+func (r *SQLitePlaceRepository) Facets(ctx context.Context, q models.PlaceQuery) ([]models.TagFacet, error) {
+	var facets []models.TagFacet
+	err := r.DB.WithContext(ctx).Table("tags").
+		Select("tags.id, tags.name, tags.slug, COUNT(*) AS count").
+		Joins("JOIN place_tags ON place_tags.tag_id = tags.id").
+		Where("place_tags.place_id IN (?)", r.filtered(ctx, q).Select("places.id")).
+		Group("tags.id, tags.name, tags.slug").
+		Order("count DESC, tags.name").
+		Scan(&facets).Error
+	if err != nil {
+		return nil, err
+	}
+	return facets, nil
+}
+
+// Nearby narrows the places to the latitude band the radius can reach and
+// to the bounding box using the index on (latitude, longitude), then
+// measures the exact distance of each candidate.
+func (r *SQLitePlaceRepository) Nearby(ctx context.Context, q models.GeoQuery) ([]models.PlaceWithDistance, int64, error) {
+	db := r.DB.WithContext(ctx).Model(&models.Place{})
+	if q.RadiusMeters > 0 {
+		band := q.RadiusMeters / metersPerDegreeLat
+		db = db.Where("latitude BETWEEN ? AND ?", q.Latitude-band, q.Latitude+band)
+	}
+	if b := q.BBox; b != nil {
+		db = db.Where("latitude BETWEEN ? AND ?", b.MinLat, b.MaxLat)
+		if b.MinLng <= b.MaxLng {
+			db = db.Where("longitude BETWEEN ? AND ?", b.MinLng, b.MaxLng)
+		} else {
+			db = db.Where("(longitude >= ? OR longitude <= ?)", b.MinLng, b.MaxLng)
+		}
+	}
+
+	var candidates []models.Place
+	if err := db.Select("id", "latitude", "longitude").Find(&candidates).Error; err != nil {
+		return nil, 0, err
+	}
+
+	type hit struct {
+		id       string
+		distance float64
+	}
+	hits := make([]hit, 0, len(candidates))
+	for _, p := range candidates {
+		d := haversineMeters(q.Latitude, q.Longitude, p.Location.Latitude, p.Location.Longitude)
+		if q.RadiusMeters > 0 && d > q.RadiusMeters {
+			continue
+		}
+		hits = append(hits, hit{id: p.Id, distance: d})
+	}
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].distance != hits[j].distance {
+			return hits[i].distance < hits[j].distance
+		}
+		return hits[i].id < hits[j].id
+	})
+
+	page := paginate(hits, q.Limit, q.Offset)
+	ids := make([]string, len(page))
+	for i, h := range page {
+		ids[i] = h.id
+	}
+	byID, err := r.byIDs(ctx, ids)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	result := make([]models.PlaceWithDistance, 0, len(page))
+	for _, h := range page {
+		if p, ok := byID[h.id]; ok {
+			result = append(result, models.PlaceWithDistance{Place: p, DistanceMeters: h.distance})
+		}
+	}
+	return result, int64(len(hits)), nil
+}
+
+// Search ranks places with the same inverted index as the in-memory
+// repository, built from the name, address and description of every live
+// place. That is a scan per search, which suits the single-node databases
+// SQLite is meant for.
+func (r *SQLitePlaceRepository) Search(ctx context.Context, q models.SearchQuery) ([]models.PlaceSearchResult, int64, error) {
+	if len(tokenize(q.Text)) == 0 {
+		return []models.PlaceSearchResult{}, 0, nil
+	}
+
+	var docs []models.Place
+	err := r.DB.WithContext(ctx).Model(&models.Place{}).
+		Select("id", "name", "address", "description").
+		Find(&docs).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	index := newSearchIndex()
+	for _, p := range docs {
+		index.add(p)
+	}
+	ids, scores := index.search(q.Text)
+
+	page := paginate(ids, q.Limit, q.Offset)
+	byID, err := r.byIDs(ctx, page)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	result := make([]models.PlaceSearchResult, 0, len(page))
+	for _, id := range page {
+		if p, ok := byID[id]; ok {
+			result = append(result, models.PlaceSearchResult{Place: p, Score: scores[id]})
+		}
+	}
+	return result, int64(len(ids)), nil
+}
+
+// byIDs loads the live places with the given ids and their tags.
+func (r *SQLitePlaceRepository) byIDs(ctx context.Context, ids []string) (map[string]models.Place, error) {
+	byID := make(map[string]models.Place, len(ids))
+	if len(ids) == 0 {
+		return byID, nil
+	}
+
+	var places []models.Place
+	if err := r.DB.WithContext(ctx).Preload("Tags").Where("id IN ?", ids).Find(&places).Error; err != nil {
+		return nil, err
+	}
+	for _, p := range places {
+		byID[p.Id] = p
+	}
+	return byID, nil
+}
+
+func (r *SQLitePlaceRepository) GetByID(ctx context.Context, id string) (*models.Place, error) {
+	var place models.Place
+	if err := r.DB.WithContext(ctx).Preload("Tags").Where("id = ?", id).First(&place).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, er.ErrPlaceNotFound
+		}
+		return nil, err
+	}
+	return &place, nil
+}
+
+// Create assigns an id when the place has none, and links the place to its
+// existing tags without writing the tags themselves.
+func (r *SQLitePlaceRepository) Create(ctx context.Context, p *models.Place) error {
+	if p.Id == "" {
+		p.Id = uuid.New().String()
+	}
+	return r.DB.WithContext(ctx).Omit("Tags.*").Create(p).Error
+}
+
+// Update is a compare-and-swap on the place's version, which it bumps.
+func (r *SQLitePlaceRepository) Update(ctx context.Context, id string, p *models.PlaceUpdateRequest, version int64) error {
+	updates := map[string]interface{}{}
+	if p.Name != nil {
+		updates["name"] = *p.Name
+	}
+	if p.Description != nil {
+		updates["description"] = *p.Description
+	}
+	if p.Location != nil {
+		updates["latitude"] = p.Location.Latitude
+		updates["longitude"] = p.Location.Longitude
+	}
+	if p.Address != nil {
+		updates["address"] = *p.Address
+	}
+
+	updates["updated_at"] = time.Now()
+	updates["version"] = gorm.Expr("version + 1")
+
+	db := r.DB.WithContext(ctx)
+	result := atVersion(db.Model(&models.Place{}), id, version).Updates(updates)
+
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return versionMissed(db, &models.Place{}, id, er.ErrPlaceNotFound)
+	}
+	return nil
+}
+
+func (r *SQLitePlaceRepository) SetTags(ctx context.Context, placeID string, tags []models.Tag) error {
+	return r.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Model(&models.Place{}).Where("id = ?", placeID).Count(&count).Error; err != nil {
+			return err
+		}
+		if count == 0 {
+			return er.ErrPlaceNotFound
+		}
+		// The place is there, so a missing row can only be a tag deleted
+		// since the caller looked it up.
+		err := tx.Model(&models.Place{Id: placeID}).Omit("Tags.*").Association("Tags").Replace(tags)
+		return translateForeignKey(err, er.ErrUnknownTag)
+	})
+}
+
+func (r *SQLitePlaceRepository) Delete(ctx context.Context, id string, version int64) error {
+	db := r.DB.WithContext(ctx)
+	result := atVersion(db, id, version).Delete(&models.Place{})
+
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return versionMissed(db, &models.Place{}, id, er.ErrPlaceNotFound)
+	}
+	return nil
+}
+
+// DeleteAll soft-deletes every place and returns their ids.
+func (r *SQLitePlaceRepository) DeleteAll(ctx context.Context) ([]string, error) {
+	var ids []string
+	err := r.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Place{}).Pluck("id", &ids).Error; err != nil {
+			return err
+		}
+		return tx.Where("1 = 1").Delete(&models.Place{}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+// ListDeleted returns soft-deleted places, most recently deleted first.
+func (r *SQLitePlaceRepository) ListDeleted(ctx context.Context, limit, offset int) ([]models.Place, int64, error) {
+	db := r.DB.WithContext(ctx).Unscoped().Model(&models.Place{}).Where("deleted_at IS NOT NULL")
+
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var places []models.Place
+	err := db.
+		Preload("Tags").
+		Order("deleted_at DESC, id").
+		Limit(limit).
+		Offset(offset).
+		Find(&places).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	return places, total, nil
+}
+
+func (r *SQLitePlaceRepository) Restore(ctx context.Context, id string) error {
+	result := r.DB.WithContext(ctx).Unscoped().Model(&models.Place{}).
+		Where("id = ? AND deleted_at IS NOT NULL", id).
+		Updates(map[string]interface{}{"deleted_at": nil, "updated_at": time.Now(), "version": gorm.Expr("version + 1")})
+
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return er.ErrPlaceNotFound
+	}
+	return nil
+}
+
+// Purge permanently removes places soft-deleted before the cutoff and
+// returns their ids. Reviews, visits, photos and tag links go with them
+// through the foreign keys.
+func (r *SQLitePlaceRepository) Purge(ctx context.Context, before time.Time) ([]string, error) {
+	var ids []string
+	err := r.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Model(&models.Place{}).Where("deleted_at < ?", before).Pluck("id", &ids).Error; err != nil {
+			return err
+		}
+		return tx.Unscoped().Where("deleted_at < ?", before).Delete(&models.Place{}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
@@ -0,0 +1,38 @@
+package repository_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	er "deu/internal/errors"
+	"deu/internal/models"
+	"deu/internal/repository"
+	"deu/pkg/db"
+)
+
+// A tag deleted between looking it up and tagging a place with it breaks
+// place_tags' foreign key; the place repository reports it as unknown.
+func TestSQLiteSetTagsRejectsDeletedTags(t *testing.T) {
+	ctx := context.Background()
+	gormDB := db.InitSQLite(filepath.Join(t.TempDir(), "tags.db"))
+	t.Cleanup(func() { closeDB(t, gormDB) })
+	migrate(t, gormDB, db.SQLiteMigrations())
+
+	places := repository.NewSQLitePlaceRepository(gormDB)
+	tags := repository.NewPostgresTagRepository(gormDB)
+	b := backend{places: places}
+	createPlace(t, b, louvreID, "Louvre Museum", nil)
+
+	museum := &models.Tag{Id: "00000000-0000-0000-0000-0000000000c1", Name: "Museum", Slug: "museum"}
+	if err := tags.Create(ctx, museum); err != nil {
+		t.Fatal(err)
+	}
+	if err := places.SetTags(ctx, louvreID, []models.Tag{*museum}); err != nil {
+		t.Fatalf("tagging with an existing tag: %v", err)
+	}
+	if err := tags.Delete(ctx, museum.Id); err != nil {
+		t.Fatal(err)
+	}
+	expectErr(t, places.SetTags(ctx, louvreID, []models.Tag{*museum}), er.ErrUnknownTag, "tagging with a deleted tag")
+}
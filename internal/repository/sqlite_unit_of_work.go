@@ -0,0 +1,30 @@
+package repository
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+)
+
+type SQLiteUnitOfWork struct {
+	DB *gorm.DB
+}
+
+func NewSQLiteUnitOfWork(db *gorm.DB) *SQLiteUnitOfWork {
+	return &SQLiteUnitOfWork{DB: db}
+}
+
+// WithTx runs fn in a database transaction. db.InitSQLite opens
+// transactions with BEGIN IMMEDIATE, so the whole database is locked for
+// writes from the start and what fn reads stays as read.
+func (u *SQLiteUnitOfWork) WithTx(ctx context.Context, fn func(tx Repos) error) error {
+	return u.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return fn(Repos{
+			Users:      &SQLiteUserRepository{DB: tx},
+			Places:     &SQLitePlaceRepository{DB: tx},
+			UserPlaces: &SQLiteUserPlaceRepository{DB: tx},
+			// The review queries are portable; SQLite shares them.
+			Reviews: &PostgresReviewRepository{DB: tx},
+		})
+	})
+}
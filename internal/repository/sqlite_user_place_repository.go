@@ -0,0 +1,111 @@
+package repository
+
+import (
+	"context"
+
+	er "deu/internal/errors"
+	"deu/internal/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type SQLiteUserPlaceRepository struct {
+	DB *gorm.DB
+}
+
+func NewSQLiteUserPlaceRepository(db *gorm.DB) *SQLiteUserPlaceRepository {
+	return &SQLiteUserPlaceRepository{DB: db}
+}
+
+// AddVisitedPlace assigns an id when the visit has none.
+func (r *SQLiteUserPlaceRepository) AddVisitedPlace(ctx context.Context, visit *models.UserPlace) error {
+	if visit.Id == "" {
+		visit.Id = uuid.New().String()
+	}
+	return translateDuplicate(r.DB.WithContext(ctx).Create(visit).Error, er.ErrVisitExists)
+}
+
+func (r *SQLiteUserPlaceRepository) ListVisits(ctx context.Context, userID, placeID string) ([]models.UserPlace, error) {
+	var visits []models.UserPlace
+
+	err := r.DB.WithContext(ctx).
+		Where("user_id = ?", userID).
+		Where("place_id = ?", placeID).
+		Order("visited_at DESC, id").
+		Find(&visits).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return visits, nil
+}
+
+func (r *SQLiteUserPlaceRepository) RemoveVisitedPlace(ctx context.Context, userID, placeID string) error {
+	result := r.DB.WithContext(ctx).
+		Where("user_id = ?", userID).
+		Where("place_id = ?", placeID).
+		Delete(&models.UserPlace{})
+
+	return result.Error
+}
+
+func (r *SQLiteUserPlaceRepository) RemoveVisit(ctx context.Context, userID, visitID string) error {
+	result := r.DB.WithContext(ctx).
+		Where("id = ?", visitID).
+		Where("user_id = ?", userID).
+		Delete(&models.UserPlace{})
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return er.ErrVisitNotFound
+	}
+
+	return nil
+}
+
+func (r *SQLiteUserPlaceRepository) ListVisitedPlaces(ctx context.Context, userID string, limit, offset int) ([]models.Place, int64, error) {
+	query := r.DB.WithContext(ctx).
+		Model(&models.Place{}).
+		Where("places.id IN (?)", r.DB.Model(&models.UserPlace{}).Select("place_id").Where("user_id = ?", userID))
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var places []models.Place
+	err := query.
+		Order("places.name, places.id").
+		Limit(limit).
+		Offset(offset).
+		Find(&places).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	return places, total, nil
+}
+
+func (r *SQLiteUserPlaceRepository) ListVisitors(ctx context.Context, placeID string, limit, offset int) ([]models.User, int64, error) {
+	query := r.DB.WithContext(ctx).
+		Model(&models.User{}).
+		Where("users.id IN (?)", r.DB.Model(&models.UserPlace{}).Select("user_id").Where("place_id = ?", placeID))
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var users []models.User
+	err := query.
+		Order("users.name, users.id").
+		Limit(limit).
+		Offset(offset).
+		Find(&users).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	return users, total, nil
+}
@@ -0,0 +1,240 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	er "deu/internal/errors"
+	"deu/internal/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// SQLiteUserRepository keeps users in a database opened with
+// db.InitSQLite. Transactions there take the write lock when they begin, so
+// unlike the Postgres repository it needs no row locks.
+type SQLiteUserRepository struct {
+	DB *gorm.DB
+}
+
+func NewSQLiteUserRepository(db *gorm.DB) *SQLiteUserRepository {
+	return &SQLiteUserRepository{DB: db}
+}
+
+// filtered applies the query's filters but not its sorting or paging.
+// SQLite's LIKE already ignores case.
+func (r *SQLiteUserRepository) filtered(ctx context.Context, q models.UserQuery) *gorm.DB {
+	db := r.DB.WithContext(ctx).Model(&models.User{})
+	if q.Name != "" {
+		db = db.Where(`name LIKE ? ESCAPE '\'`, "%"+escapeLike(q.Name)+"%")
+	}
+	if q.Email != "" {
+		db = db.Where(`email LIKE ? ESCAPE '\'`, "%"+escapeLike(q.Email)+"%")
+	}
+	return db
+}
+
+func (r *SQLiteUserRepository) List(ctx context.Context, q models.UserQuery) ([]models.User, error) {
+	column, ok := userSortColumns[q.Sort]
+	if !ok {
+		return nil, er.ErrInvalidQuery
+	}
+
+	db := r.filtered(ctx, q)
+	if q.Cursor != nil {
+		value, err := sortValue(q.Sort, q.Cursor.Value)
+		if err != nil {
+			return nil, err
+		}
+		db = db.Where(keysetCondition(column, q.Desc), value, q.Cursor.Id)
+	}
+
+	var users []models.User
+	err := db.
+		Order(orderClause(column, q.Desc)).
+		Limit(q.Limit).
+		Offset(q.Offset).
+		Find(&users).Error
+	if err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+func (r *SQLiteUserRepository) Count(ctx context.Context, q models.UserQuery) (int64, error) {
+	var total int64
+	if err := r.filtered(ctx, q).Count(&total).Error; err != nil {
+		return 0, err
+	}
+	return total, nil
+}
+
+func (r *SQLiteUserRepository) GetByID(ctx context.Context, id string) (*models.User, error) {
+	var user models.User
+	if err := r.DB.WithContext(ctx).Where("id = ?", id).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, er.ErrUserNotFound
+		}
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (r *SQLiteUserRepository) GetByEmail(ctx context.Context, email string) (*models.User, error) {
+	var user models.User
+	if err := r.DB.WithContext(ctx).Where("lower(email) = lower(?)", email).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, er.ErrUserNotFound
+		}
+		return nil, err
+	}
+	return &user, nil
+}
+
+// Create assigns an id when the user has none and fails with ErrConflict
+// when a live user already has the email.
+func (r *SQLiteUserRepository) Create(ctx context.Context, u *models.User) error {
+	if u.Id == "" {
+		u.Id = uuid.New().String()
+	}
+	return translateDuplicate(r.DB.WithContext(ctx).Create(u).Error, er.ErrConflict)
+}
+
+// Update is a compare-and-swap on the user's version, which it bumps.
+func (r *SQLiteUserRepository) Update(ctx context.Context, id string, u *models.UserUpdateRequest, version int64) error {
+	updates := map[string]interface{}{}
+	if u.Name != nil {
+		updates["name"] = *u.Name
+	}
+	if u.Email != nil {
+		updates["email"] = *u.Email
+	}
+
+	updates["updated_at"] = time.Now()
+	updates["version"] = gorm.Expr("version + 1")
+
+	db := r.DB.WithContext(ctx)
+	result := atVersion(db.Model(&models.User{}), id, version).Updates(updates)
+
+	if result.Error != nil {
+		return translateDuplicate(result.Error, er.ErrConflict)
+	}
+	if result.RowsAffected == 0 {
+		return versionMissed(db, &models.User{}, id, er.ErrUserNotFound)
+	}
+	return nil
+}
+
+func (r *SQLiteUserRepository) SetRole(ctx context.Context, id string, role string) error {
+	result := r.DB.WithContext(ctx).Model(&models.User{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"role":       role,
+		"updated_at": time.Now(),
+		"version":    gorm.Expr("version + 1"),
+	})
+
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return er.ErrUserNotFound
+	}
+	return nil
+}
+
+func (r *SQLiteUserRepository) Delete(ctx context.Context, id string, version int64) error {
+	db := r.DB.WithContext(ctx)
+	result := atVersion(db, id, version).Delete(&models.User{})
+
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return versionMissed(db, &models.User{}, id, er.ErrUserNotFound)
+	}
+	return nil
+}
+
+// DeleteAll soft-deletes every user and returns their ids.
+func (r *SQLiteUserRepository) DeleteAll(ctx context.Context) ([]string, error) {
+	var ids []string
+	err := r.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.User{}).Pluck("id", &ids).Error; err != nil {
+			return err
+		}
+		return tx.Where("1 = 1").Delete(&models.User{}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+// ListDeleted returns soft-deleted users, most recently deleted first.
+func (r *SQLiteUserRepository) ListDeleted(ctx context.Context, limit, offset int) ([]models.User, int64, error) {
+	db := r.DB.WithContext(ctx).Unscoped().Model(&models.User{}).Where("deleted_at IS NOT NULL")
+
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var users []models.User
+	err := db.
+		Order("deleted_at DESC, id").
+		Limit(limit).
+		Offset(offset).
+		Find(&users).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	return users, total, nil
+}
+
+// Restore fails with ErrConflict when another account has taken the email
+// since the user was deleted; emails are only unique among live users.
+func (r *SQLiteUserRepository) Restore(ctx context.Context, id string) error {
+	return r.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var user models.User
+		err := tx.Unscoped().
+			Where("id = ? AND deleted_at IS NOT NULL", id).
+			First(&user).Error
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return er.ErrUserNotFound
+			}
+			return err
+		}
+
+		var taken int64
+		if err := tx.Model(&models.User{}).Where("lower(email) = lower(?)", user.Email).Count(&taken).Error; err != nil {
+			return err
+		}
+		if taken > 0 {
+			return er.ErrConflict
+		}
+
+		err = tx.Unscoped().Model(&models.User{}).
+			Where("id = ?", id).
+			Updates(map[string]interface{}{"deleted_at": nil, "updated_at": time.Now(), "version": gorm.Expr("version + 1")}).Error
+		return translateDuplicate(err, er.ErrConflict)
+	})
+}
+
+// Purge permanently removes users soft-deleted before the cutoff and
+// returns their ids. Their sessions, keys, visits and reviews go with them
+// through the foreign keys.
+func (r *SQLiteUserRepository) Purge(ctx context.Context, before time.Time) ([]string, error) {
+	var ids []string
+	err := r.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Model(&models.User{}).Where("deleted_at < ?", before).Pluck("id", &ids).Error; err != nil {
+			return err
+		}
+		return tx.Unscoped().Where("deleted_at < ?", before).Delete(&models.User{}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
@@ -0,0 +1,18 @@
+package repository
+
+import (
+	"context"
+
+	"deu/internal/models"
+)
+
+// TagRepository stores the tags places can be classified with. Deleting a
+// tag detaches it from every place.
+type TagRepository interface {
+	List(ctx context.Context) ([]models.Tag, error)
+	GetByID(ctx context.Context, id string) (*models.Tag, error)
+	GetBySlugs(ctx context.Context, slugs []string) ([]models.Tag, error)
+	Create(ctx context.Context, t *models.Tag) error
+	Update(ctx context.Context, t *models.Tag) error
+	Delete(ctx context.Context, id string) error
+}
@@ -0,0 +1,22 @@
+package repository
+
+import "context"
+
+// Repos are the repositories a unit of work hands to its function. They all
+// act within the same transaction.
+type Repos struct {
+	Users      UserRepository
+	Places     PlaceRepository
+	UserPlaces UserPlaceRepository
+	Reviews    ReviewRepository
+}
+
+// UnitOfWork runs several repository calls as one atomic step.
+type UnitOfWork interface {
+	// WithTx calls fn with repositories bound to a new transaction, which
+	// commits if fn returns nil and rolls back if it fails or panics. Users
+	// and places read through tx stay as read until the transaction ends,
+	// so a check made in fn cannot be undone by a concurrent delete. Calls
+	// to WithTx must not be nested.
+	WithTx(ctx context.Context, fn func(tx Repos) error) error
+}
@@ -1,11 +1,15 @@
-package repository
-
-import (
-	"context"
-)
-
-type UserPlaceRepository interface {
-    AddVisitedPlace(ctx context.Context, userID, placeID string) error
-    HasVisitedPlace(ctx context.Context, userID, placeID string) (bool, error)
-    RemoveVisitedPlace(ctx context.Context, userID, placeID string) error
+package repository
+
+import (
+	"context"
+	"deu/internal/models"
+)
+
+type UserPlaceRepository interface {
+    AddVisitedPlace(ctx context.Context, visit *models.UserPlace) error
+    ListVisits(ctx context.Context, userID, placeID string) ([]models.UserPlace, error)
+    RemoveVisitedPlace(ctx context.Context, userID, placeID string) error
+    RemoveVisit(ctx context.Context, userID, visitID string) error
+    ListVisitedPlaces(ctx context.Context, userID string, limit, offset int) ([]models.Place, int64, error)
+    ListVisitors(ctx context.Context, placeID string, limit, offset int) ([]models.User, int64, error)
 }
\ No newline at end of file
@@ -1,15 +1,24 @@
-package repository
-
-import (
-	"deu/internal/models"
-	"context"
-)
-
-type UserRepository interface {
-    GetAll(ctx context.Context) ([]models.User, error)
-    GetByID(ctx context.Context, id string) (*models.User, error)
-    Create(ctx context.Context, u *models.User) error
-    Update(ctx context.Context, id string, u *models.UserUpdateRequest) error
-    Delete(ctx context.Context, id string) error
-    DeleteAll(ctx context.Context) error
+package repository
+
+import (
+	"deu/internal/models"
+	"context"
+	"time"
+)
+
+type UserRepository interface {
+    List(ctx context.Context, q models.UserQuery) ([]models.User, error)
+    Count(ctx context.Context, q models.UserQuery) (int64, error)
+    GetByID(ctx context.Context, id string) (*models.User, error)
+    GetByEmail(ctx context.Context, email string) (*models.User, error)
+    Create(ctx context.Context, u *models.User) error
+    // Update and Delete only apply while the user is still at version; a
+    // version of 0 skips the check.
+    Update(ctx context.Context, id string, u *models.UserUpdateRequest, version int64) error
+    SetRole(ctx context.Context, id string, role string) error
+    Delete(ctx context.Context, id string, version int64) error
+    DeleteAll(ctx context.Context) ([]string, error)
+    ListDeleted(ctx context.Context, limit, offset int) ([]models.User, int64, error)
+    Restore(ctx context.Context, id string) error
+    Purge(ctx context.Context, before time.Time) ([]string, error)
 }
\ No newline at end of file
@@ -1,303 +1,381 @@
-package users
-
-import (
-	"encoding/json"
-	"fmt"
-	"net/http"
-	"strings"
-
-	er "deu/internal/errors"
-	"deu/internal/models"
-
-	"github.com/go-playground/validator/v10"
-)
-
-var validate *validator.Validate
-
-func init() {
-	validate = validator.New()
-}
-
-func writeJSON(w http.ResponseWriter, status int, data interface{}) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(status)
-	if data != nil {
-		json.NewEncoder(w).Encode(data)
-	}
-}
-
-func validateRequest(s interface{}) map[string]string {
-	err := validate.Struct(s)
-	if err == nil {
-		return nil
-	}
-
-	errorsMap := make(map[string]string)
-	for _, err := range err.(validator.ValidationErrors) {
-		errorsMap[err.Field()] = fmt.Sprintf("Field '%s' failed validation: tag '%s'.",
-			err.Field(),
-			err.Tag(),
-			err.Param(),
-		)
-	}
-
-	return errorsMap
-}
-
-type Handler struct {
-	Service *UserService
-}
-
-// validateAndGetID checks if the ID string is a valid UUID and extracts it.
-func validateAndGetID(w http.ResponseWriter, parts []string) (string, bool) {
-	if len(parts) < 3 || parts[2] == "" {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "User ID missing in path"})
-		return "", false
-	}
-	id := parts[2]
-	
-	if err := validate.Var(id, "required,uuid"); err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "User ID must be a valid UUID"})
-		return "", false
-	}
-	return id, true
-}
-
-
-// GET /users
-func (h *Handler) GetAll(w http.ResponseWriter, r *http.Request) {
-	resp, err := h.Service.GetAll(r.Context())
-	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
-		return
-	}
-
-	writeJSON(w, http.StatusOK, resp)
-}
-
-// GET /users/{id}
-func (h *Handler) GetById(w http.ResponseWriter, r *http.Request) {
-	parts := strings.Split(r.URL.Path, "/")
-	
-	id, ok := validateAndGetID(w, parts)
-	if !ok {
-		return
-	}
-
-	u, err := h.Service.GetById(r.Context(), id)
-	if err != nil {
-		if err == er.ErrUserNotFound {
-			writeJSON(w, http.StatusNotFound, map[string]string{"error": "User not found"})
-			return
-		}
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
-		return
-	}
-
-	writeJSON(w, http.StatusOK, u)
-}
-
-// POST /users
-func (h *Handler) Create(w http.ResponseWriter, r *http.Request) {
-	var u models.UserCreateRequest
-	
-	if err := json.NewDecoder(r.Body).Decode(&u); err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid JSON format"})
-		return
-	}
-
-	if errorsMap := validateRequest(u); errorsMap != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"validation_errors": errorsMap})
-		return
-	}
-	
-	user, err := h.Service.Create(r.Context(), &u)
-	if err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
-		return
-	}
-
-	writeJSON(w, http.StatusCreated, *user)
-}
-
-// PATCH /users/{id}
-func (h *Handler) Update(w http.ResponseWriter, r *http.Request) {
-	parts := strings.Split(r.URL.Path, "/")
-	
-	id, ok := validateAndGetID(w, parts)
-	if !ok {
-		return
-	}
-
-	var u models.UserUpdateRequest
-	
-	if err := json.NewDecoder(r.Body).Decode(&u); err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid JSON format"})
-		return
-	}
-
-	if errorsMap := validateRequest(u); errorsMap != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"validation_errors": errorsMap})
-		return
-	}
-
-	err := h.Service.Update(r.Context(), id, &u)
-	if err != nil {
-		if err == er.ErrUserNotFound {
-			writeJSON(w, http.StatusNotFound, map[string]string{"error": "User not found"})
-			return
-		}
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
-		return
-	}
-
-	writeJSON(w, http.StatusOK, map[string]string{"status": "updated"})
-}
-
-// DELETE /users/{id}
-func (h *Handler) DeleteById(w http.ResponseWriter, r *http.Request) {
-	parts := strings.Split(r.URL.Path, "/")
-	
-	id, ok := validateAndGetID(w, parts)
-	if !ok {
-		return
-	}
-
-	err := h.Service.DeleteById(r.Context(), id)
-	if err != nil {
-		if err == er.ErrUserNotFound {
-			writeJSON(w, http.StatusNotFound, map[string]string{"error": "User not found"})
-			return
-		}
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
-		return
-	}
-
-	writeJSON(w, http.StatusOK, map[string]string{"status": "deleted"})
-}
-
-// POST /users/{id}/places/{place_id}
-func (h *Handler) AddVisitedPlace(w http.ResponseWriter, r *http.Request) {
-	parts := strings.Split(r.URL.Path, "/")
-	
-	if len(parts) < 5 || parts[2] == "" || parts[4] == "" {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Missing user ID or place ID"})
-		return
-	}
-	userID := parts[2]
-	placeID := parts[4]
-	
-	if err := validate.Var(userID, "required,uuid"); err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "User ID must be a valid UUID"})
-		return
-	}
-
-	if err := validate.Var(placeID, "required,uuid"); err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Place ID must be a valid UUID"})
-		return
-	}
-
-	err := h.Service.AddVisitedPlace(r.Context(), userID, placeID)
-
-	if err != nil {
-		switch err {
-		case er.ErrUserNotFound:
-			writeJSON(w, http.StatusNotFound, map[string]string{"error": "User not found"})
-		case er.ErrPlaceNotFound:
-			writeJSON(w, http.StatusNotFound, map[string]string{"error": "Place not found"})
-		default:
-			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
-		}
-		return
-	}
-
-	writeJSON(w, http.StatusCreated, map[string]string{"status": "Place added to user's visited list"})
-}
-
-// GET /users/{id}/places/{place_id}
-func (h *Handler) CheckIfVisited(w http.ResponseWriter, r *http.Request) {
-	parts := strings.Split(r.URL.Path, "/")
-
-	if len(parts) < 5 || parts[2] == "" || parts[4] == "" {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Missing user ID or place ID"})
-		return
-	}
-	userID := parts[2]
-	placeID := parts[4]
-
-	if err := validate.Var(userID, "required,uuid"); err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "User ID must be a valid UUID"})
-		return
-	}
-
-	if err := validate.Var(placeID, "required,uuid"); err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Place ID must be a valid UUID"})
-		return
-	}
-
-	visited, err := h.Service.HasVisitedPlace(r.Context(), userID, placeID)
-
-	if err != nil {
-		if err == er.ErrUserNotFound || err == er.ErrPlaceNotFound {
-			writeJSON(w, http.StatusNotFound, map[string]string{"error": err.Error()})
-			return
-		}
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
-		return
-	}
-
-	if visited {
-		writeJSON(w, http.StatusOK, map[string]interface{}{"visited": true, "message": "User has visited this place"})
-	} else {
-		writeJSON(w, http.StatusOK, map[string]interface{}{"visited": false, "message": "User has NOT visited this place"})
-	}
-}
-
-// DELETE /users/{id}/places/{place_id}
-func (h *Handler) RemoveVisitedPlace(w http.ResponseWriter, r *http.Request) {
-	parts := strings.Split(r.URL.Path, "/")
-	
-	if len(parts) < 5 || parts[2] == "" || parts[4] == "" {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Missing user ID or place ID"})
-		return
-	}
-	userID := parts[2]
-	placeID := parts[4]
-	
-	if err := validate.Var(userID, "required,uuid"); err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "User ID must be a valid UUID"})
-		return
-	}
-
-	if err := validate.Var(placeID, "required,uuid"); err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Place ID must be a valid UUID"})
-		return
-	}
-
-	err := h.Service.RemoveVisitedPlace(r.Context(), userID, placeID)
-
-	if err != nil {
-		switch err {
-		case er.ErrUserNotFound:
-			writeJSON(w, http.StatusNotFound, map[string]string{"error": "User not found"})
-		case er.ErrPlaceNotFound:
-			writeJSON(w, http.StatusNotFound, map[string]string{"error": "Place not found"})
-		default:
-			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
-		}
-		return
-	}
-
-	writeJSON(w, http.StatusOK, map[string]string{"status": "Place removed from user's visited list"})
-}
-
-// DELETE /users
-func (h *Handler) DeleteAll(w http.ResponseWriter, r *http.Request) {
-	err := h.Service.DeleteAll(r.Context())
-	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
-		return
-	}
-
-	writeJSON(w, http.StatusOK, map[string]string{"status": "all users deleted"})
-}
\ No newline at end of file
+package users
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	er "deu/internal/errors"
+	"deu/internal/models"
+	"deu/pkg/httpx"
+)
+
+type Handler struct {
+	Service *UserService
+	// TrashRetention is how long deleted users stay restorable before
+	// DELETE /trash/users may purge them.
+	TrashRetention time.Duration
+}
+
+// parseUserQuery reads paging, sorting and filter parameters for GET /users.
+func parseUserQuery(w http.ResponseWriter, r *http.Request) (models.UserQuery, bool) {
+	limit, offset, ok := httpx.Pagination(w, r)
+	if !ok {
+		return models.UserQuery{}, false
+	}
+
+	query := r.URL.Query()
+	q := models.UserQuery{
+		Limit:  limit,
+		Offset: offset,
+		Sort:   models.UserSortName,
+		Name:   query.Get("name"),
+		Email:  query.Get("email"),
+	}
+
+	if v := query.Get("sort"); v != "" {
+		switch v {
+		case models.UserSortName, models.UserSortEmail, models.UserSortCreatedAt:
+			q.Sort = v
+		default:
+			er.Write(w, r, er.WithDetail(er.ErrInvalidQuery, "sort must be one of username, email, createdAt"))
+			return q, false
+		}
+	}
+	switch query.Get("order") {
+	case "", "asc":
+	case "desc":
+		q.Desc = true
+	default:
+		er.Write(w, r, er.WithDetail(er.ErrInvalidQuery, "order must be asc or desc"))
+		return q, false
+	}
+
+	if v := query.Get("cursor"); v != "" {
+		cursor, err := models.DecodeCursor(v)
+		if err != nil {
+			er.Write(w, r, er.WithDetail(er.ErrInvalidQuery, "Invalid cursor"))
+			return q, false
+		}
+		q.Cursor = cursor
+	}
+
+	return q, true
+}
+
+// GET /users
+func (h *Handler) GetAll(w http.ResponseWriter, r *http.Request) {
+	q, ok := parseUserQuery(w, r)
+	if !ok {
+		return
+	}
+
+	resp, err := h.Service.List(r.Context(), q)
+	if err != nil {
+		if errors.Is(err, er.ErrInvalidQuery) {
+			er.Write(w, r, er.WithDetail(er.ErrInvalidQuery, "Invalid cursor for this sort, or cursor combined with offset"))
+			return
+		}
+		er.Write(w, r, err)
+		return
+	}
+
+	httpx.JSON(w, http.StatusOK, resp)
+}
+
+// GET /users/{id}
+func (h *Handler) GetById(w http.ResponseWriter, r *http.Request) {
+	id, ok := httpx.PathUUID(w, r, "id")
+	if !ok {
+		return
+	}
+
+	u, err := h.Service.GetById(r.Context(), id)
+	if err != nil {
+		er.Write(w, r, err)
+		return
+	}
+
+	etag := httpx.VersionETag(u.Version)
+	w.Header().Set("ETag", etag)
+	if httpx.ETagMatches(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	httpx.JSON(w, http.StatusOK, u)
+}
+
+// POST /users
+func (h *Handler) Create(w http.ResponseWriter, r *http.Request) {
+	var u models.UserCreateRequest
+	if !httpx.Decode(w, r, &u) {
+		return
+	}
+	
+	user, err := h.Service.Create(r.Context(), &u)
+	if err != nil {
+		er.Write(w, r, err)
+		return
+	}
+
+	httpx.JSON(w, http.StatusCreated, *user)
+}
+
+// PATCH /users/{id}
+func (h *Handler) Update(w http.ResponseWriter, r *http.Request) {
+	id, ok := httpx.PathUUID(w, r, "id")
+	if !ok {
+		return
+	}
+
+	var u models.UserUpdateRequest
+	if !httpx.Decode(w, r, &u) {
+		return
+	}
+
+	version, ok := httpx.IfMatchVersion(w, r)
+	if !ok {
+		return
+	}
+
+	user, err := h.Service.Update(r.Context(), id, &u, version)
+	if err != nil {
+		er.Write(w, r, err)
+		return
+	}
+
+	w.Header().Set("ETag", httpx.VersionETag(user.Version))
+	httpx.Status(w, http.StatusOK, "updated")
+}
+
+// PUT /users/{id}/role
+func (h *Handler) SetRole(w http.ResponseWriter, r *http.Request) {
+	id, ok := httpx.PathUUID(w, r, "id")
+	if !ok {
+		return
+	}
+
+	var req models.UserRoleRequest
+	if !httpx.Decode(w, r, &req) {
+		return
+	}
+
+	err := h.Service.SetRole(r.Context(), id, req.Role)
+	if err != nil {
+		er.Write(w, r, err)
+		return
+	}
+
+	httpx.Status(w, http.StatusOK, "role updated")
+}
+
+// DELETE /users/{id}
+func (h *Handler) DeleteById(w http.ResponseWriter, r *http.Request) {
+	id, ok := httpx.PathUUID(w, r, "id")
+	if !ok {
+		return
+	}
+
+	version, ok := httpx.IfMatchVersion(w, r)
+	if !ok {
+		return
+	}
+
+	err := h.Service.DeleteById(r.Context(), id, version)
+	if err != nil {
+		er.Write(w, r, err)
+		return
+	}
+
+	httpx.Status(w, http.StatusOK, "deleted")
+}
+
+// POST /users/{id}/places/{place_id}
+func (h *Handler) AddVisitedPlace(w http.ResponseWriter, r *http.Request) {
+	userID, ok := httpx.PathUUID(w, r, "id")
+	if !ok {
+		return
+	}
+	placeID, ok := httpx.PathUUID(w, r, "place_id")
+	if !ok {
+		return
+	}
+
+	// The body is optional; an empty one records a visit happening now.
+	var v models.VisitCreateRequest
+	if !httpx.DecodeOptional(w, r, &v) {
+		return
+	}
+
+	visit, err := h.Service.AddVisitedPlace(r.Context(), userID, placeID, &v)
+
+	if err != nil {
+		er.Write(w, r, err)
+		return
+	}
+
+	httpx.JSON(w, http.StatusCreated, visit)
+}
+
+// GET /users/{id}/places
+func (h *Handler) ListVisitedPlaces(w http.ResponseWriter, r *http.Request) {
+	id, ok := httpx.PathUUID(w, r, "id")
+	if !ok {
+		return
+	}
+
+	limit, offset, ok := httpx.Pagination(w, r)
+	if !ok {
+		return
+	}
+
+	page, err := h.Service.ListVisitedPlaces(r.Context(), id, limit, offset)
+	if err != nil {
+		er.Write(w, r, err)
+		return
+	}
+
+	httpx.JSON(w, http.StatusOK, page)
+}
+
+// GET /users/{id}/places/{place_id}
+func (h *Handler) CheckIfVisited(w http.ResponseWriter, r *http.Request) {
+	userID, ok := httpx.PathUUID(w, r, "id")
+	if !ok {
+		return
+	}
+	placeID, ok := httpx.PathUUID(w, r, "place_id")
+	if !ok {
+		return
+	}
+
+	history, err := h.Service.GetVisitHistory(r.Context(), userID, placeID)
+
+	if err != nil {
+		er.Write(w, r, err)
+		return
+	}
+
+	httpx.JSON(w, http.StatusOK, history)
+}
+
+// DELETE /users/{id}/places/{place_id}
+func (h *Handler) RemoveVisitedPlace(w http.ResponseWriter, r *http.Request) {
+	userID, ok := httpx.PathUUID(w, r, "id")
+	if !ok {
+		return
+	}
+	placeID, ok := httpx.PathUUID(w, r, "place_id")
+	if !ok {
+		return
+	}
+
+	err := h.Service.RemoveVisitedPlace(r.Context(), userID, placeID)
+
+	if err != nil {
+		er.Write(w, r, err)
+		return
+	}
+
+	httpx.Status(w, http.StatusOK, "Place removed from user's visited list")
+}
+
+// DELETE /users/{id}/visits/{visit_id}
+func (h *Handler) RemoveVisit(w http.ResponseWriter, r *http.Request) {
+	userID, ok := httpx.PathUUID(w, r, "id")
+	if !ok {
+		return
+	}
+	visitID, ok := httpx.PathUUID(w, r, "visit_id")
+	if !ok {
+		return
+	}
+
+	err := h.Service.RemoveVisit(r.Context(), userID, visitID)
+
+	if err != nil {
+		er.Write(w, r, err)
+		return
+	}
+
+	httpx.Status(w, http.StatusOK, "Visit removed")
+}
+
+// DELETE /users
+func (h *Handler) DeleteAll(w http.ResponseWriter, r *http.Request) {
+	err := h.Service.DeleteAll(r.Context())
+	if err != nil {
+		er.Write(w, r, err)
+		return
+	}
+
+	httpx.Status(w, http.StatusOK, "all users deleted")
+}
+
+// GET /trash/users
+func (h *Handler) ListDeleted(w http.ResponseWriter, r *http.Request) {
+	limit, offset, ok := httpx.Pagination(w, r)
+	if !ok {
+		return
+	}
+
+	page, err := h.Service.ListDeleted(r.Context(), limit, offset)
+	if err != nil {
+		er.Write(w, r, err)
+		return
+	}
+
+	httpx.JSON(w, http.StatusOK, page)
+}
+
+// POST /users/{id}/restore
+func (h *Handler) Restore(w http.ResponseWriter, r *http.Request) {
+	id, ok := httpx.PathUUID(w, r, "id")
+	if !ok {
+		return
+	}
+
+	err := h.Service.Restore(r.Context(), id)
+	if err != nil {
+		er.Write(w, r, err)
+		return
+	}
+
+	httpx.Status(w, http.StatusOK, "restored")
+}
+
+// DELETE /trash/users
+//
+// Permanently removes users that have been in the trash for longer than
+// the retention period.
+func (h *Handler) Purge(w http.ResponseWriter, r *http.Request) {
+	purged, err := h.Service.Purge(r.Context(), time.Now().Add(-h.TrashRetention))
+	if err != nil {
+		er.Write(w, r, err)
+		return
+	}
+
+	httpx.JSON(w, http.StatusOK, map[string]int{"purged": purged})
+}
+
+// GET /users/{id}/history
+func (h *Handler) History(w http.ResponseWriter, r *http.Request) {
+	id, ok := httpx.PathUUID(w, r, "id")
+	if !ok {
+		return
+	}
+
+	limit, offset, ok := httpx.Pagination(w, r)
+	if !ok {
+		return
+	}
+
+	page, err := h.Service.History(r.Context(), id, limit, offset)
+	if err != nil {
+		er.Write(w, r, err)
+		return
+	}
+
+	httpx.JSON(w, http.StatusOK, page)
+}
@@ -1,129 +1,438 @@
-package users
-
-import (
-	"time"
-    "context"
-	"github.com/google/uuid"
-    
-	er "deu/internal/errors"
-    repo "deu/internal/repository"
-	"deu/internal/models"
-)
-
-type UserService struct {
-    repo repo.UserRepository
-    userPlaceRepo repo.UserPlaceRepository 
-    placeRepo repo.PlaceRepository
-}
-
-func NewUserService(userRepo repo.UserRepository, userPlaceRepo repo.UserPlaceRepository, placeRepo repo.PlaceRepository) *UserService {
-    return &UserService{
-        repo: userRepo, 
-        userPlaceRepo: userPlaceRepo, 
-        placeRepo: placeRepo,
-    }
-}
-
-func (s *UserService) GetAll(ctx context.Context) ([]models.User, error) {
-    return s.repo.GetAll(ctx)
-}
-
-func (s *UserService) GetById(ctx context.Context, id string) (*models.User, error) {
-    if id == "" {
-        return nil, er.ErrInvalidUserData
-    }
-    return s.repo.GetByID(ctx, id)
-}
-
-func (s *UserService) Create(ctx context.Context, u *models.UserCreateRequest) (*models.User, error) {
-
-    if u.Name == "" || u.Email == "" {
-        return nil, er.ErrInvalidUserData
-    }
-
-	id := uuid.New()
-
-	user := models.User{
-		Id: 		id.String(),
-		Name: 		u.Name,
-		Email: 		u.Email,
-		CreatedAt:	time.Now(),
-	}
-
-
-    return &user, s.repo.Create(ctx, &user)
-}
-
-func (s *UserService) Update(ctx context.Context, id string, u *models.UserUpdateRequest) error {
-
-    if id == "" {
-        return er.ErrInvalidUserData
-    }
-
-    return s.repo.Update(ctx, id, u)
-}
-
-func (s *UserService) DeleteById(ctx context.Context, id string) error {
-    if id == "" {
-        return er.ErrInvalidUserData
-    }
-    return s.repo.Delete(ctx, id)
-}
-
-func (s *UserService) AddVisitedPlace(ctx context.Context, userID, placeID string) error {
-    if userID == "" || placeID == "" {
-        return er.ErrInvalidUserData
-    }
-
-    _, userErr := s.repo.GetByID(ctx, userID)
-    if userErr != nil {
-        return userErr
-    }
-    
-    _, placeErr := s.placeRepo.GetByID(ctx, placeID)
-    if placeErr != nil {
-        return placeErr
-    }
-
-    return s.userPlaceRepo.AddVisitedPlace(ctx, userID, placeID)
-}
-
-func (s *UserService) HasVisitedPlace(ctx context.Context, userID, placeID string) (bool, error) {
-    if userID == "" || placeID == "" {
-        return false, er.ErrInvalidUserData
-    }
-
-    _, userErr := s.repo.GetByID(ctx, userID)
-    if userErr != nil {
-        return false, userErr
-    }
-    
-    _, placeErr := s.placeRepo.GetByID(ctx, placeID)
-    if placeErr != nil {
-        return false, placeErr
-    }
-
-    return s.userPlaceRepo.HasVisitedPlace(ctx, userID, placeID)
-}
-
-func (s *UserService) RemoveVisitedPlace(ctx context.Context, userID, placeID string) error {
-    if userID == "" || placeID == "" {
-        return er.ErrInvalidUserData
-    }
-
-    _, userErr := s.repo.GetByID(ctx, userID)
-    if userErr != nil {
-        return userErr
-    }
-    
-    _, placeErr := s.placeRepo.GetByID(ctx, placeID)
-    if placeErr != nil {
-        return placeErr
-    }
-
-    return s.userPlaceRepo.RemoveVisitedPlace(ctx, userID, placeID)
-}
-
-func (s *UserService) DeleteAll(ctx context.Context) error {
-    return s.repo.DeleteAll(ctx)
+package users
+
+import (
+	"time"
+    "context"
+	"github.com/google/uuid"
+    
+	"deu/internal/audit"
+	"deu/internal/auth"
+	er "deu/internal/errors"
+    repo "deu/internal/repository"
+	"deu/internal/models"
+)
+
+type UserService struct {
+    repo repo.UserRepository
+    userPlaceRepo repo.UserPlaceRepository 
+    placeRepo repo.PlaceRepository
+    // uow runs the operations that read and write several rows in one
+    // transaction.
+    uow repo.UnitOfWork
+    audit *audit.Trail
+}
+
+func NewUserService(userRepo repo.UserRepository, userPlaceRepo repo.UserPlaceRepository, placeRepo repo.PlaceRepository, uow repo.UnitOfWork, trail *audit.Trail) *UserService {
+    return &UserService{
+        repo: userRepo, 
+        userPlaceRepo: userPlaceRepo, 
+        placeRepo: placeRepo,
+        uow: uow,
+        audit: trail,
+    }
+}
+
+// userState is the part of a user that is audited. The password hash is
+// deliberately left out.
+func userState(u *models.User) map[string]any {
+    return map[string]any{
+        "username": u.Name,
+        "email":    u.Email,
+        "role":     u.Role,
+    }
+}
+
+// record adds a change to a user's history. after is the user's state once
+// the change is done, or their last state when they were deleted.
+func (s *UserService) record(ctx context.Context, action, id string, before, after *models.User) {
+    change := audit.Change{EntityType: models.AuditEntityUser, EntityID: id, Action: action}
+    if before != nil {
+        change.Before = userState(before)
+    }
+    if after != nil {
+        change.After = userState(after)
+    }
+    s.audit.Record(ctx, change)
+}
+
+// recordUpdate reloads a user after a change, records how they differ from
+// before and returns the reloaded user.
+func (s *UserService) recordUpdate(ctx context.Context, before *models.User) (*models.User, error) {
+    after, err := s.repo.GetByID(ctx, before.Id)
+    if err != nil {
+        return nil, err
+    }
+    s.record(ctx, models.AuditUpdate, before.Id, before, after)
+    return after, nil
+}
+
+// authorizeSelf makes sure the caller is acting on their own account rather
+// than on whatever id was put into the request path. Admins may act on any
+// account.
+func authorizeSelf(ctx context.Context, id string) error {
+    caller, ok := auth.PrincipalFromContext(ctx)
+    if !ok {
+        return er.ErrUnauthorized
+    }
+    if caller.UserID != id && !caller.IsAdmin() {
+        return er.ErrForbidden
+    }
+    return nil
+}
+
+// List returns one page of users. One extra row is fetched to tell whether
+// a next cursor is needed; the total is only counted for offset paging.
+func (s *UserService) List(ctx context.Context, q models.UserQuery) (*models.Page[models.User], error) {
+    if q.Cursor != nil && (q.Offset != 0 || q.Cursor.Sort != q.Sort || q.Cursor.Desc != q.Desc) {
+        return nil, er.ErrInvalidQuery
+    }
+
+    limit := q.Limit
+    q.Limit = limit + 1
+    users, err := s.repo.List(ctx, q)
+    if err != nil {
+        return nil, err
+    }
+
+    page := &models.Page[models.User]{Limit: limit, Offset: q.Offset}
+    if len(users) > limit {
+        users = users[:limit]
+        last := users[limit-1]
+        page.NextCursor = models.Cursor{Sort: q.Sort, Desc: q.Desc, Value: last.SortValue(q.Sort), Id: last.Id}.Encode()
+    }
+    page.Items = users
+
+    if q.Cursor == nil {
+        total, err := s.repo.Count(ctx, q)
+        if err != nil {
+            return nil, err
+        }
+        page.Total = &total
+    }
+
+    return page, nil
+}
+
+// GetById returns the caller's own account, or any account to an admin.
+// Other users only see each other through models.UserSummary.
+func (s *UserService) GetById(ctx context.Context, id string) (*models.User, error) {
+    if id == "" {
+        return nil, er.ErrInvalidUserData
+    }
+    if err := authorizeSelf(ctx, id); err != nil {
+        return nil, err
+    }
+    return s.repo.GetByID(ctx, id)
+}
+
+func (s *UserService) Create(ctx context.Context, u *models.UserCreateRequest) (*models.User, error) {
+
+    if u.Name == "" || u.Email == "" {
+        return nil, er.ErrInvalidUserData
+    }
+
+	id := uuid.New()
+
+	user := models.User{
+		Id: 		id.String(),
+		Name: 		u.Name,
+		Email: 		models.NormalizeEmail(u.Email),
+		Role: 		models.RoleMember,
+		Version:	1,
+		CreatedAt:	time.Now(),
+	}
+
+
+    if err := s.repo.Create(ctx, &user); err != nil {
+        return nil, err
+    }
+    s.record(ctx, models.AuditCreate, user.Id, nil, &user)
+
+    return &user, nil
+}
+
+// Update applies u if the user is still at version, which is 0 to skip the
+// check, and returns the updated user.
+func (s *UserService) Update(ctx context.Context, id string, u *models.UserUpdateRequest, version int64) (*models.User, error) {
+
+    if id == "" {
+        return nil, er.ErrInvalidUserData
+    }
+
+    if err := authorizeSelf(ctx, id); err != nil {
+        return nil, err
+    }
+
+    before, err := s.repo.GetByID(ctx, id)
+    if err != nil {
+        return nil, err
+    }
+    if version != 0 && before.Version != version {
+        return nil, er.ErrVersionMismatch
+    }
+    if u.Email != nil {
+        email := models.NormalizeEmail(*u.Email)
+        u.Email = &email
+    }
+    if err := s.repo.Update(ctx, id, u, version); err != nil {
+        return nil, err
+    }
+
+    return s.recordUpdate(ctx, before)
+}
+
+func (s *UserService) SetRole(ctx context.Context, id string, role string) error {
+    if id == "" || role == "" {
+        return er.ErrInvalidUserData
+    }
+
+    caller, ok := auth.PrincipalFromContext(ctx)
+    if !ok {
+        return er.ErrUnauthorized
+    }
+    if !caller.IsAdmin() {
+        return er.ErrForbidden
+    }
+
+    before, err := s.repo.GetByID(ctx, id)
+    if err != nil {
+        return err
+    }
+    if err := s.repo.SetRole(ctx, id, role); err != nil {
+        return err
+    }
+    _, err = s.recordUpdate(ctx, before)
+
+    return err
+}
+
+// DeleteById moves the user to the trash if they are still at version,
+// which is 0 to skip the check.
+func (s *UserService) DeleteById(ctx context.Context, id string, version int64) error {
+    if id == "" {
+        return er.ErrInvalidUserData
+    }
+
+    if err := authorizeSelf(ctx, id); err != nil {
+        return err
+    }
+
+    var user *models.User
+    err := s.uow.WithTx(ctx, func(tx repo.Repos) error {
+        var err error
+        user, err = tx.Users.GetByID(ctx, id)
+        if err != nil {
+            return err
+        }
+        if version != 0 && user.Version != version {
+            return er.ErrVersionMismatch
+        }
+        return tx.Users.Delete(ctx, id, version)
+    })
+    if err != nil {
+        return err
+    }
+    s.record(ctx, models.AuditDelete, id, nil, user)
+
+    return nil
+}
+
+// AddVisitedPlace records a new visit. Earlier visits to the same place are
+// kept; the visit date defaults to now and may not lie in the future. The
+// user and place are checked in the same transaction as the insert, so
+// neither can be deleted in between.
+func (s *UserService) AddVisitedPlace(ctx context.Context, userID, placeID string, v *models.VisitCreateRequest) (*models.UserPlace, error) {
+    if userID == "" || placeID == "" {
+        return nil, er.ErrInvalidUserData
+    }
+
+    if err := authorizeSelf(ctx, userID); err != nil {
+        return nil, err
+    }
+
+    now := time.Now().UTC()
+    visitedAt := now
+    if v.VisitedAt != nil {
+        if v.VisitedAt.After(now.Add(time.Minute)) {
+            return nil, er.ErrInvalidVisitData
+        }
+        visitedAt = v.VisitedAt.UTC()
+    }
+
+    visit := models.UserPlace{
+        Id:        uuid.New().String(),
+        UserID:    userID,
+        PlaceID:   placeID,
+        VisitedAt: visitedAt,
+        Note:      v.Note,
+        Rating:    v.Rating,
+        PhotoRef:  v.PhotoRef,
+        CreatedAt: now,
+    }
+
+    err := s.uow.WithTx(ctx, func(tx repo.Repos) error {
+        if _, err := tx.Users.GetByID(ctx, userID); err != nil {
+            return err
+        }
+        if _, err := tx.Places.GetByID(ctx, placeID); err != nil {
+            return err
+        }
+        return tx.UserPlaces.AddVisitedPlace(ctx, &visit)
+    })
+    if err != nil {
+        return nil, err
+    }
+
+    return &visit, nil
+}
+
+func (s *UserService) ListVisitedPlaces(ctx context.Context, userID string, limit, offset int) (*models.Page[models.Place], error) {
+    if userID == "" {
+        return nil, er.ErrInvalidUserData
+    }
+
+    if err := authorizeSelf(ctx, userID); err != nil {
+        return nil, err
+    }
+
+    _, userErr := s.repo.GetByID(ctx, userID)
+    if userErr != nil {
+        return nil, userErr
+    }
+
+    places, total, err := s.userPlaceRepo.ListVisitedPlaces(ctx, userID, limit, offset)
+    if err != nil {
+        return nil, err
+    }
+
+    return &models.Page[models.Place]{Items: places, Total: &total, Limit: limit, Offset: offset}, nil
+}
+
+func (s *UserService) GetVisitHistory(ctx context.Context, userID, placeID string) (*models.VisitHistory, error) {
+    if userID == "" || placeID == "" {
+        return nil, er.ErrInvalidUserData
+    }
+
+    if err := authorizeSelf(ctx, userID); err != nil {
+        return nil, err
+    }
+
+    _, userErr := s.repo.GetByID(ctx, userID)
+    if userErr != nil {
+        return nil, userErr
+    }
+    
+    _, placeErr := s.placeRepo.GetByID(ctx, placeID)
+    if placeErr != nil {
+        return nil, placeErr
+    }
+
+    visits, err := s.userPlaceRepo.ListVisits(ctx, userID, placeID)
+    if err != nil {
+        return nil, err
+    }
+
+    return &models.VisitHistory{Visited: len(visits) > 0, Visits: visits}, nil
+}
+
+func (s *UserService) RemoveVisitedPlace(ctx context.Context, userID, placeID string) error {
+    if userID == "" || placeID == "" {
+        return er.ErrInvalidUserData
+    }
+
+    if err := authorizeSelf(ctx, userID); err != nil {
+        return err
+    }
+
+    return s.uow.WithTx(ctx, func(tx repo.Repos) error {
+        if _, err := tx.Users.GetByID(ctx, userID); err != nil {
+            return err
+        }
+        if _, err := tx.Places.GetByID(ctx, placeID); err != nil {
+            return err
+        }
+        return tx.UserPlaces.RemoveVisitedPlace(ctx, userID, placeID)
+    })
+}
+
+func (s *UserService) RemoveVisit(ctx context.Context, userID, visitID string) error {
+    if userID == "" || visitID == "" {
+        return er.ErrInvalidUserData
+    }
+
+    if err := authorizeSelf(ctx, userID); err != nil {
+        return err
+    }
+
+    return s.userPlaceRepo.RemoveVisit(ctx, userID, visitID)
+}
+
+func (s *UserService) DeleteAll(ctx context.Context) error {
+    var ids []string
+    err := s.uow.WithTx(ctx, func(tx repo.Repos) error {
+        var err error
+        ids, err = tx.Users.DeleteAll(ctx)
+        return err
+    })
+    if err != nil {
+        return err
+    }
+    for _, id := range ids {
+        s.record(ctx, models.AuditDelete, id, nil, nil)
+    }
+    return nil
+}
+
+// ListDeleted returns one page of the users in the trash, most recently
+// deleted first.
+func (s *UserService) ListDeleted(ctx context.Context, limit, offset int) (*models.Page[models.User], error) {
+    users, total, err := s.repo.ListDeleted(ctx, limit, offset)
+    if err != nil {
+        return nil, err
+    }
+
+    return &models.Page[models.User]{Items: users, Total: &total, Limit: limit, Offset: offset}, nil
+}
+
+// Restore takes a user out of the trash. Existing sessions and API keys
+// start working again.
+func (s *UserService) Restore(ctx context.Context, id string) error {
+    if id == "" {
+        return er.ErrInvalidUserData
+    }
+
+    if err := s.repo.Restore(ctx, id); err != nil {
+        return err
+    }
+    if user, err := s.repo.GetByID(ctx, id); err == nil {
+        s.record(ctx, models.AuditRestore, id, nil, user)
+    }
+    return nil
+}
+
+// Purge permanently removes the users deleted before the cutoff and
+// reports how many were removed.
+func (s *UserService) Purge(ctx context.Context, before time.Time) (int, error) {
+    ids, err := s.repo.Purge(ctx, before)
+    if err != nil {
+        return 0, err
+    }
+    for _, id := range ids {
+        s.record(ctx, models.AuditPurge, id, nil, nil)
+    }
+    return len(ids), nil
+}
+
+// History returns a page of a user's changes, newest first. Users may read
+// their own history; admins may read anyone's.
+func (s *UserService) History(ctx context.Context, id string, limit, offset int) (*models.Page[models.AuditEntry], error) {
+    if id == "" {
+        return nil, er.ErrInvalidUserData
+    }
+
+    if err := authorizeSelf(ctx, id); err != nil {
+        return nil, err
+    }
+
+    return s.audit.History(ctx, models.AuditEntityUser, id, limit, offset)
 }
\ No newline at end of file
@@ -0,0 +1,25 @@
+// Package blob stores opaque binary objects such as uploaded photos under
+// slash-separated keys.
+package blob
+
+import (
+	"context"
+	"errors"
+	"io"
+)
+
+var ErrNotFound = errors.New("blob not found")
+
+// Store is implemented by every blob backend. Keys use forward slashes
+// whatever the backend, e.g. "places/{id}/{photo_id}".
+type Store interface {
+	// Put writes the object under key, replacing any existing one.
+	Put(ctx context.Context, key string, r io.Reader) error
+	// Get opens the object for reading. It returns ErrNotFound when the
+	// key does not exist. The caller must close the reader.
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete removes the object. Deleting a missing key is not an error.
+	Delete(ctx context.Context, key string) error
+	// DeletePrefix removes every object whose key starts with prefix.
+	DeletePrefix(ctx context.Context, prefix string) error
+}
@@ -0,0 +1,69 @@
+package cache
+
+import (
+	"context"
+	"sync"
+)
+
+// Invalidation tells the caches of every instance to drop a key, or all of
+// their keys when All is set.
+type Invalidation struct {
+	Key string `json:"key,omitempty"`
+	All bool   `json:"all,omitempty"`
+}
+
+// Bus carries invalidations between the instances sharing a database, so a
+// write on one of them does not leave the others serving stale values.
+type Bus interface {
+	// Publish sends inv to every subscriber, including those of the
+	// publishing instance.
+	Publish(ctx context.Context, inv Invalidation) error
+	// Subscribe registers fn to be called with every invalidation received.
+	Subscribe(fn func(Invalidation))
+}
+
+// Evictor returns a subscriber that applies invalidations to c.
+func Evictor[V any](c Cache[V]) func(Invalidation) {
+	return func(inv Invalidation) {
+		if inv.All {
+			c.Clear()
+		} else {
+			c.Delete(inv.Key)
+		}
+	}
+}
+
+// subscribers is the subscriber list shared by the Bus implementations.
+type subscribers struct {
+	mu  sync.RWMutex
+	fns []func(Invalidation)
+}
+
+func (s *subscribers) Subscribe(fn func(Invalidation)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.fns = append(s.fns, fn)
+}
+
+func (s *subscribers) deliver(inv Invalidation) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, fn := range s.fns {
+		fn(inv)
+	}
+}
+
+// LocalBus delivers invalidations within the process, synchronously. It
+// suits a single instance, and tests that run several services side by side.
+type LocalBus struct {
+	subscribers
+}
+
+func NewLocalBus() *LocalBus {
+	return &LocalBus{}
+}
+
+func (b *LocalBus) Publish(_ context.Context, inv Invalidation) error {
+	b.deliver(inv)
+	return nil
+}
@@ -0,0 +1,60 @@
+// Package cache keeps recently read values in memory: bounded in size,
+// expiring after a TTL, copied on the way in and out so callers never
+// share a cached value, and loading each missing key only once however many
+// callers ask for it at the same time. A Bus carries invalidations between
+// the instances of the server, so one instance's writes evict the values
+// the others cached.
+package cache
+
+import "context"
+
+// Cache is implemented by every cache backend.
+type Cache[V any] interface {
+	// Get returns a copy of the value cached under key.
+	Get(key string) (V, bool)
+	// GetOrLoad returns the value cached under key, or calls load to fetch
+	// it and caches the result. Concurrent calls for the same missing key
+	// share a single load. Errors are returned but not cached.
+	GetOrLoad(ctx context.Context, key string, load func(ctx context.Context) (V, error)) (V, error)
+	// Set caches a copy of v under key.
+	Set(key string, v V)
+	// Delete drops key, including any load for it still in flight.
+	Delete(key string)
+	// Clear drops every key.
+	Clear()
+	// Stats returns the counters since the cache was created.
+	Stats() Stats
+}
+
+// Stats counts how a cache has been used.
+type Stats struct {
+	Hits   uint64 `json:"hits"`
+	Misses uint64 `json:"misses"`
+	// Evictions counts entries dropped to make room for newer ones.
+	Evictions uint64 `json:"evictions"`
+	// Expirations counts entries dropped because they outlived the TTL.
+	Expirations uint64 `json:"expirations"`
+	Entries     int    `json:"entries"`
+	MaxEntries  int    `json:"maxEntries"`
+}
+
+// Nop caches nothing; every lookup is a miss. It stands in when caching is
+// disabled.
+type Nop[V any] struct{}
+
+func (Nop[V]) Get(string) (V, bool) {
+	var zero V
+	return zero, false
+}
+
+func (Nop[V]) GetOrLoad(ctx context.Context, _ string, load func(ctx context.Context) (V, error)) (V, error) {
+	return load(ctx)
+}
+
+func (Nop[V]) Set(string, V) {}
+
+func (Nop[V]) Delete(string) {}
+
+func (Nop[V]) Clear() {}
+
+func (Nop[V]) Stats() Stats { return Stats{} }
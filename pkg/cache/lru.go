@@ -0,0 +1,191 @@
+package cache
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// Options configure an LRU cache.
+type Options[V any] struct {
+	// MaxEntries bounds the number of cached values; the least recently
+	// used one is evicted to make room. Zero means 10000.
+	MaxEntries int
+	// TTL is how long a value stays fresh after it was cached. Zero means
+	// five minutes.
+	TTL time.Duration
+	// Clone copies a value. Values are cloned when cached and when handed
+	// out, so a caller mutating its copy cannot change the cache. Leave it
+	// nil for values without pointers, slices or maps.
+	Clone func(V) V
+}
+
+type entry[V any] struct {
+	key     string
+	value   V
+	expires time.Time
+}
+
+// LRU is a Cache with least-recently-used eviction and a TTL.
+type LRU[V any] struct {
+	maxEntries int
+	ttl        time.Duration
+	clone      func(V) V
+
+	mu    sync.Mutex
+	order *list.List // front is the most recently used
+	items map[string]*list.Element
+	// generation is bumped by Delete and Clear. A load that started before
+	// an invalidation must not cache what it read.
+	generation uint64
+	stats      Stats
+
+	loads singleflight.Group
+	now   func() time.Time
+}
+
+// NewLRU returns an empty LRU cache.
+func NewLRU[V any](opts Options[V]) *LRU[V] {
+	if opts.MaxEntries <= 0 {
+		opts.MaxEntries = 10000
+	}
+	if opts.TTL <= 0 {
+		opts.TTL = 5 * time.Minute
+	}
+	if opts.Clone == nil {
+		opts.Clone = func(v V) V { return v }
+	}
+	return &LRU[V]{
+		maxEntries: opts.MaxEntries,
+		ttl:        opts.TTL,
+		clone:      opts.Clone,
+		order:      list.New(),
+		items:      make(map[string]*list.Element),
+		now:        time.Now,
+	}
+}
+
+func (c *LRU[V]) Get(key string) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if v, ok := c.lookup(key); ok {
+		c.stats.Hits++
+		return c.clone(v), true
+	}
+	c.stats.Misses++
+	var zero V
+	return zero, false
+}
+
+func (c *LRU[V]) GetOrLoad(ctx context.Context, key string, load func(ctx context.Context) (V, error)) (V, error) {
+	if v, ok := c.Get(key); ok {
+		return v, nil
+	}
+
+	c.mu.Lock()
+	generation := c.generation
+	c.mu.Unlock()
+
+	// The load is shared, so it must not fail because the caller that
+	// happened to start it went away. The value is cloned for every caller.
+	v, err, _ := c.loads.Do(key, func() (any, error) {
+		v, err := load(context.WithoutCancel(ctx))
+		if err != nil {
+			return v, err
+		}
+		c.mu.Lock()
+		if c.generation == generation {
+			c.store(key, v)
+		}
+		c.mu.Unlock()
+		return v, nil
+	})
+	if err != nil {
+		var zero V
+		return zero, err
+	}
+	return c.clone(v.(V)), nil
+}
+
+func (c *LRU[V]) Set(key string, v V) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.store(key, v)
+}
+
+func (c *LRU[V]) Delete(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.generation++
+	c.loads.Forget(key)
+	if el, ok := c.items[key]; ok {
+		c.remove(el)
+	}
+}
+
+func (c *LRU[V]) Clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.generation++
+	for key := range c.items {
+		c.loads.Forget(key)
+	}
+	c.order.Init()
+	c.items = make(map[string]*list.Element)
+}
+
+func (c *LRU[V]) Stats() Stats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stats := c.stats
+	stats.Entries = len(c.items)
+	stats.MaxEntries = c.maxEntries
+	return stats
+}
+
+// lookup returns the fresh value under key and marks it as used. c.mu must
+// be held.
+func (c *LRU[V]) lookup(key string) (V, bool) {
+	var zero V
+	el, ok := c.items[key]
+	if !ok {
+		return zero, false
+	}
+	e := el.Value.(*entry[V])
+	if !c.now().Before(e.expires) {
+		c.remove(el)
+		c.stats.Expirations++
+		return zero, false
+	}
+	c.order.MoveToFront(el)
+	return e.value, true
+}
+
+// store caches a copy of v, evicting the least recently used entry when
+// the cache is full. c.mu must be held.
+func (c *LRU[V]) store(key string, v V) {
+	e := &entry[V]{key: key, value: c.clone(v), expires: c.now().Add(c.ttl)}
+	if el, ok := c.items[key]; ok {
+		el.Value = e
+		c.order.MoveToFront(el)
+		return
+	}
+
+	c.items[key] = c.order.PushFront(e)
+	for len(c.items) > c.maxEntries {
+		c.remove(c.order.Back())
+		c.stats.Evictions++
+	}
+}
+
+func (c *LRU[V]) remove(el *list.Element) {
+	c.order.Remove(el)
+	delete(c.items, el.Value.(*entry[V]).key)
+}
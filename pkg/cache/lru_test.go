@@ -0,0 +1,166 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestLRUEvictsLeastRecentlyUsed(t *testing.T) {
+	c := NewLRU(Options[int]{MaxEntries: 2})
+	c.Set("a", 1)
+	c.Set("b", 2)
+	c.Get("a")
+	c.Set("c", 3)
+
+	if _, ok := c.Get("b"); ok {
+		t.Error("b was kept although it was the least recently used")
+	}
+	for _, key := range []string{"a", "c"} {
+		if _, ok := c.Get(key); !ok {
+			t.Errorf("%s was evicted", key)
+		}
+	}
+	if got := c.Stats().Evictions; got != 1 {
+		t.Errorf("Evictions = %d, want 1", got)
+	}
+}
+
+func TestLRUExpiresAfterTTL(t *testing.T) {
+	now := time.Now()
+	c := NewLRU(Options[int]{TTL: time.Minute})
+	c.now = func() time.Time { return now }
+
+	c.Set("a", 1)
+	now = now.Add(time.Minute - time.Nanosecond)
+	if _, ok := c.Get("a"); !ok {
+		t.Fatal("a expired before its TTL")
+	}
+	now = now.Add(time.Nanosecond)
+	if _, ok := c.Get("a"); ok {
+		t.Fatal("a was served after its TTL")
+	}
+
+	stats := c.Stats()
+	if stats.Expirations != 1 || stats.Entries != 0 {
+		t.Errorf("Expirations = %d, Entries = %d, want 1 and 0", stats.Expirations, stats.Entries)
+	}
+}
+
+func TestLRUClonesValues(t *testing.T) {
+	c := NewLRU(Options[[]int]{Clone: func(v []int) []int { return append([]int(nil), v...) }})
+
+	v := []int{1}
+	c.Set("a", v)
+	v[0] = 2
+	got, _ := c.Get("a")
+	got[0] = 3
+
+	if again, _ := c.Get("a"); again[0] != 1 {
+		t.Errorf("cached value changed to %d through a caller's copy", again[0])
+	}
+}
+
+func TestLRUGetOrLoadSharesOneLoad(t *testing.T) {
+	c := NewLRU(Options[int]{})
+	release := make(chan struct{})
+	var calls atomic.Int32
+
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			v, err := c.GetOrLoad(context.Background(), "a", func(context.Context) (int, error) {
+				calls.Add(1)
+				<-release
+				return 1, nil
+			})
+			if err != nil || v != 1 {
+				t.Errorf("GetOrLoad = %d, %v, want 1", v, err)
+			}
+		}()
+	}
+	// Give the goroutines time to pile up behind the first load.
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if n := calls.Load(); n != 1 {
+		t.Errorf("load ran %d times, want 1", n)
+	}
+}
+
+func TestLRUGetOrLoadDoesNotCacheErrors(t *testing.T) {
+	c := NewLRU(Options[int]{})
+	failure := errors.New("failed")
+
+	_, err := c.GetOrLoad(context.Background(), "a", func(context.Context) (int, error) { return 0, failure })
+	if !errors.Is(err, failure) {
+		t.Fatalf("GetOrLoad returned %v, want the load's error", err)
+	}
+	if _, ok := c.Get("a"); ok {
+		t.Error("a failed load was cached")
+	}
+}
+
+// TestLRUInvalidationBeatsStaleLoad covers a load that read the old value,
+// then lost the race to an invalidation: it must not put the old value
+// back.
+func TestLRUInvalidationBeatsStaleLoad(t *testing.T) {
+	tests := []struct {
+		name       string
+		invalidate func(c *LRU[int])
+		cached     bool
+	}{
+		{"no invalidation", func(c *LRU[int]) {}, true},
+		{"delete of the key", func(c *LRU[int]) { c.Delete("a") }, false},
+		{"delete of another key", func(c *LRU[int]) { c.Delete("b") }, false},
+		{"clear", func(c *LRU[int]) { c.Clear() }, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewLRU(Options[int]{})
+			loading := make(chan struct{})
+			release := make(chan struct{})
+
+			done := make(chan int)
+			go func() {
+				v, _ := c.GetOrLoad(context.Background(), "a", func(context.Context) (int, error) {
+					close(loading)
+					<-release
+					return 1, nil
+				})
+				done <- v
+			}()
+
+			<-loading
+			tt.invalidate(c)
+			close(release)
+
+			if v := <-done; v != 1 {
+				t.Errorf("GetOrLoad = %d, want the loaded value 1", v)
+			}
+			if _, ok := c.Get("a"); ok != tt.cached {
+				t.Errorf("value cached = %v, want %v", ok, tt.cached)
+			}
+		})
+	}
+}
+
+func TestLRULoadAfterInvalidationIsCached(t *testing.T) {
+	c := NewLRU(Options[int]{})
+	c.Set("a", 1)
+	c.Delete("a")
+
+	v, err := c.GetOrLoad(context.Background(), "a", func(context.Context) (int, error) { return 2, nil })
+	if err != nil || v != 2 {
+		t.Fatalf("GetOrLoad = %d, %v, want 2", v, err)
+	}
+	if got, ok := c.Get("a"); !ok || got != 2 {
+		t.Errorf("Get = %d, %v, want 2 from the cache", got, ok)
+	}
+}
@@ -0,0 +1,94 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"gorm.io/gorm"
+)
+
+const (
+	minListenRetry = time.Second
+	maxListenRetry = 30 * time.Second
+)
+
+// PostgresBus carries invalidations over LISTEN/NOTIFY on the application
+// database. It publishes through the shared connection pool and receives on
+// a dedicated connection that Listen keeps open.
+type PostgresBus struct {
+	subscribers
+	db      *gorm.DB
+	dsn     string
+	channel string
+}
+
+// NewPostgresBus returns a bus on channel. dsn is used to open the listening
+// connection and should name the database db is connected to.
+func NewPostgresBus(db *gorm.DB, dsn, channel string) *PostgresBus {
+	return &PostgresBus{db: db, dsn: dsn, channel: channel}
+}
+
+func (b *PostgresBus) Publish(ctx context.Context, inv Invalidation) error {
+	payload, err := json.Marshal(inv)
+	if err != nil {
+		return err
+	}
+	return b.db.WithContext(ctx).Exec("SELECT pg_notify(?, ?)", b.channel, string(payload)).Error
+}
+
+// Listen delivers invalidations to the subscribers until ctx is cancelled,
+// reconnecting whenever the connection drops. Notifications sent while no
+// connection was listening are lost, so every (re)connect is followed by an
+// invalidation of all keys.
+func (b *PostgresBus) Listen(ctx context.Context) {
+	retry := minListenRetry
+	for {
+		listened, err := b.listen(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		if listened {
+			retry = minListenRetry
+		}
+		slog.Warn("Cache invalidation listener disconnected", "channel", b.channel, "retry_in", retry, "error", err)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(retry):
+		}
+		retry = min(retry*2, maxListenRetry)
+	}
+}
+
+// listen runs one listening connection until it fails. It reports whether
+// LISTEN succeeded.
+func (b *PostgresBus) listen(ctx context.Context) (bool, error) {
+	conn, err := pgx.Connect(ctx, b.dsn)
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close(context.WithoutCancel(ctx))
+
+	if _, err := conn.Exec(ctx, "LISTEN "+pgx.Identifier{b.channel}.Sanitize()); err != nil {
+		return false, err
+	}
+	slog.Info("Listening for cache invalidations", "channel", b.channel)
+	b.deliver(Invalidation{All: true})
+
+	for {
+		n, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return true, err
+		}
+		var inv Invalidation
+		if err := json.Unmarshal([]byte(n.Payload), &inv); err != nil {
+			slog.Warn("Ignoring malformed cache invalidation", "channel", b.channel, "payload", n.Payload, "error", err)
+			continue
+		}
+		b.deliver(inv)
+	}
+}
@@ -1,29 +1,36 @@
-package db
-
-import (
-	"log"
-
-	//"deu/internal/models"
-
-	"gorm.io/driver/postgres"
-	"gorm.io/gorm"
-)
-
-func InitDB(dsn string) *gorm.DB {
-	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
-	if err != nil {
-		log.Fatalf("Failed to connect to database: %v", err)
-	}
-
-	//err = db.AutoMigrate(
-	//	&models.User{},
-	//	&models.Place{},
-	//	&models.UserPlace{},
-	//)
-	//if err != nil {
-	//	log.Fatalf("Failed to auto-migrate database: %v", err)
-	//}
-
-	log.Println("Database connection established and migrations complete.")
-	return db
-}
\ No newline at end of file
+package db
+
+import (
+	"log"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// InitDB connects to Postgres. The schema is managed by the migrations in
+// this package; see Migrator.
+func InitDB(dsn string) *gorm.DB {
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+
+	log.Println("Database connection established.")
+	return db
+}
+
+// InitSQLite opens the SQLite database at path, creating it if needed. The
+// connections enforce foreign keys, wait for the write lock instead of
+// failing, and take it when a transaction begins, so a transaction never
+// has to be retried for having read before another one wrote.
+func InitSQLite(path string) *gorm.DB {
+	dsn := "file:" + path + "?_foreign_keys=on&_busy_timeout=5000&_journal_mode=WAL&_txlock=immediate"
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{TranslateError: true})
+	if err != nil {
+		log.Fatalf("Failed to open database: %v", err)
+	}
+
+	log.Println("Database opened:", path)
+	return db
+}
@@ -0,0 +1,291 @@
+package db
+
+import (
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+//go:embed migrations/postgres/*.sql
+var postgresMigrations embed.FS
+
+// PostgresMigrations returns the migrations of the Postgres schema.
+func PostgresMigrations() fs.FS {
+	sub, err := fs.Sub(postgresMigrations, "migrations/postgres")
+	if err != nil {
+		panic(err)
+	}
+	return sub
+}
+
+//go:embed migrations/sqlite/*.sql
+var sqliteMigrations embed.FS
+
+// SQLiteMigrations returns the migrations of the SQLite schema.
+func SQLiteMigrations() fs.FS {
+	sub, err := fs.Sub(sqliteMigrations, "migrations/sqlite")
+	if err != nil {
+		panic(err)
+	}
+	return sub
+}
+
+// ErrSchemaBehind means the database lacks migrations the code relies on.
+var ErrSchemaBehind = errors.New("database schema is behind the code")
+
+// migrationLockKey serialises migrators across processes through a Postgres
+// advisory lock, so replicas starting together do not race.
+const migrationLockKey = 7402318856194
+
+// Migration is one numbered schema change. Scripts are named
+// NNNN_name.up.sql and NNNN_name.down.sql; the down script is optional but
+// without it the migration cannot be rolled back.
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+// schemaMigration is a row of schema_migrations, one per applied migration.
+type schemaMigration struct {
+	Version   int64 `gorm:"primaryKey;autoIncrement:false"`
+	Name      string
+	AppliedAt time.Time
+}
+
+func (schemaMigration) TableName() string {
+	return "schema_migrations"
+}
+
+// MigrationStatus compares the database with the migrations of the code.
+type MigrationStatus struct {
+	// Current is the highest applied version, 0 for an empty database.
+	Current int64
+	// Latest is the highest version the code knows.
+	Latest int64
+	// Pending lists the known migrations that are not applied, in order.
+	Pending []Migration
+	// Unknown lists applied versions the code does not know, left by a
+	// newer release.
+	Unknown []int64
+}
+
+// Migrator applies and rolls back versioned migrations, recording them in
+// the schema_migrations table. Each run happens in a single transaction, so
+// a failing script leaves the schema as it was.
+type Migrator struct {
+	db         *gorm.DB
+	migrations []Migration
+}
+
+// NewMigrator reads the migration scripts in dir.
+func NewMigrator(db *gorm.DB, dir fs.FS) (*Migrator, error) {
+	entries, err := fs.ReadDir(dir, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int64]*Migration)
+	for _, e := range entries {
+		if e.IsDir() || path.Ext(e.Name()) != ".sql" {
+			continue
+		}
+		version, name, direction, err := parseMigrationName(e.Name())
+		if err != nil {
+			return nil, err
+		}
+		script, err := fs.ReadFile(dir, e.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: name}
+			byVersion[version] = m
+		} else if m.Name != name {
+			return nil, fmt.Errorf("migration %d is named both %q and %q", version, m.Name, name)
+		}
+		if direction == "up" {
+			m.Up = string(script)
+		} else {
+			m.Down = string(script)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, fmt.Errorf("migration %04d_%s has no up script", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+
+	return &Migrator{db: db, migrations: migrations}, nil
+}
+
+// parseMigrationName splits "0002_add_tags.up.sql" into 2, "add_tags" and
+// "up".
+func parseMigrationName(file string) (int64, string, string, error) {
+	base := strings.TrimSuffix(file, ".sql")
+	direction := path.Ext(base)
+	base = strings.TrimSuffix(base, direction)
+	direction = strings.TrimPrefix(direction, ".")
+
+	number, name, _ := strings.Cut(base, "_")
+	version, err := strconv.ParseInt(number, 10, 64)
+	if err != nil || version <= 0 || (direction != "up" && direction != "down") {
+		return 0, "", "", fmt.Errorf("migration file %q is not named NNNN_name.up.sql or NNNN_name.down.sql", file)
+	}
+	return version, name, direction, nil
+}
+
+// Up applies every pending migration and returns them.
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	var applied []Migration
+	err := m.locked(ctx, func(tx *gorm.DB) error {
+		status, err := m.status(tx)
+		if err != nil {
+			return err
+		}
+		for _, mig := range status.Pending {
+			if err := tx.Exec(mig.Up).Error; err != nil {
+				return fmt.Errorf("migration %04d_%s: %w", mig.Version, mig.Name, err)
+			}
+			row := schemaMigration{Version: mig.Version, Name: mig.Name, AppliedAt: time.Now()}
+			if err := tx.Create(&row).Error; err != nil {
+				return err
+			}
+			applied = append(applied, mig)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return applied, nil
+}
+
+// Down rolls back the last steps applied migrations, newest first, and
+// returns them.
+func (m *Migrator) Down(ctx context.Context, steps int) ([]Migration, error) {
+	known := make(map[int64]Migration, len(m.migrations))
+	for _, mig := range m.migrations {
+		known[mig.Version] = mig
+	}
+
+	var reverted []Migration
+	err := m.locked(ctx, func(tx *gorm.DB) error {
+		var rows []schemaMigration
+		if err := tx.Select("version", "name").Order("version DESC").Limit(steps).Find(&rows).Error; err != nil {
+			return err
+		}
+		for _, row := range rows {
+			mig, ok := known[row.Version]
+			if !ok {
+				return fmt.Errorf("migration %04d_%s is not known to this build and cannot be rolled back", row.Version, row.Name)
+			}
+			if mig.Down == "" {
+				return fmt.Errorf("migration %04d_%s has no down script", mig.Version, mig.Name)
+			}
+			if err := tx.Exec(mig.Down).Error; err != nil {
+				return fmt.Errorf("rolling back migration %04d_%s: %w", mig.Version, mig.Name, err)
+			}
+			if err := tx.Delete(&schemaMigration{}, row.Version).Error; err != nil {
+				return err
+			}
+			reverted = append(reverted, mig)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return reverted, nil
+}
+
+// Status reports which migrations the database has.
+func (m *Migrator) Status(ctx context.Context) (MigrationStatus, error) {
+	db := m.db.WithContext(ctx)
+	if !db.Migrator().HasTable(&schemaMigration{}) {
+		return m.compare(nil), nil
+	}
+	return m.status(db)
+}
+
+// Check fails with ErrSchemaBehind when migrations are pending. A schema
+// that is ahead of the code is accepted; see MigrationStatus.Unknown.
+func (m *Migrator) Check(ctx context.Context) error {
+	status, err := m.Status(ctx)
+	if err != nil {
+		return err
+	}
+	if len(status.Pending) > 0 {
+		return fmt.Errorf("%w: at version %d, %d migration(s) pending up to %d", ErrSchemaBehind, status.Current, len(status.Pending), status.Latest)
+	}
+	return nil
+}
+
+// locked runs fn in a transaction that holds the migration lock and in
+// which schema_migrations exists.
+func (m *Migrator) locked(ctx context.Context, fn func(tx *gorm.DB) error) error {
+	return m.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if tx.Dialector.Name() == "postgres" {
+			if err := tx.Exec("SELECT pg_advisory_xact_lock(?)", migrationLockKey).Error; err != nil {
+				return err
+			}
+		}
+		err := tx.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+			version BIGINT PRIMARY KEY,
+			name VARCHAR(255) NOT NULL,
+			applied_at TIMESTAMP WITH TIME ZONE NOT NULL
+		)`).Error
+		if err != nil {
+			return err
+		}
+		return fn(tx)
+	})
+}
+
+func (m *Migrator) status(db *gorm.DB) (MigrationStatus, error) {
+	var rows []schemaMigration
+	if err := db.Select("version", "name").Order("version").Find(&rows).Error; err != nil {
+		return MigrationStatus{}, err
+	}
+	return m.compare(rows), nil
+}
+
+func (m *Migrator) compare(rows []schemaMigration) MigrationStatus {
+	var status MigrationStatus
+	applied := make(map[int64]bool, len(rows))
+	for _, row := range rows {
+		applied[row.Version] = true
+		status.Current = max(status.Current, row.Version)
+	}
+
+	known := make(map[int64]bool, len(m.migrations))
+	for _, mig := range m.migrations {
+		known[mig.Version] = true
+		status.Latest = max(status.Latest, mig.Version)
+		if !applied[mig.Version] {
+			status.Pending = append(status.Pending, mig)
+		}
+	}
+	for _, row := range rows {
+		if !known[row.Version] {
+			status.Unknown = append(status.Unknown, row.Version)
+		}
+	}
+	return status
+}
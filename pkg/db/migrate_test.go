@@ -0,0 +1,308 @@
+package db
+
+import (
+	"context"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"gorm.io/gorm"
+)
+
+func script(sql string) *fstest.MapFile {
+	return &fstest.MapFile{Data: []byte(sql)}
+}
+
+func TestNewMigratorPairsScripts(t *testing.T) {
+	dir := fstest.MapFS{
+		"0010_add_tags.up.sql":    script("CREATE TABLE tags ();"),
+		"0002_add_users.up.sql":   script("CREATE TABLE users ();"),
+		"0002_add_users.down.sql": script("DROP TABLE users;"),
+		"0010_add_tags.down.sql":  script("DROP TABLE tags;"),
+		"0011_backfill.up.sql":    script("UPDATE tags SET name = name;"),
+		"README.md":               script("not a migration"),
+	}
+	m, err := NewMigrator(nil, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []Migration{
+		{Version: 2, Name: "add_users", Up: "CREATE TABLE users ();", Down: "DROP TABLE users;"},
+		{Version: 10, Name: "add_tags", Up: "CREATE TABLE tags ();", Down: "DROP TABLE tags;"},
+		{Version: 11, Name: "backfill", Up: "UPDATE tags SET name = name;"},
+	}
+	if len(m.migrations) != len(want) {
+		t.Fatalf("got %d migrations, want %d", len(m.migrations), len(want))
+	}
+	for i := range want {
+		if m.migrations[i] != want[i] {
+			t.Errorf("migration %d = %+v, want %+v", i, m.migrations[i], want[i])
+		}
+	}
+}
+
+func TestNewMigratorRejectsBadScripts(t *testing.T) {
+	tests := []struct {
+		name string
+		dir  fstest.MapFS
+	}{
+		{"no version", fstest.MapFS{"add_users.up.sql": script("")}},
+		{"version zero", fstest.MapFS{"0000_add_users.up.sql": script("")}},
+		{"no direction", fstest.MapFS{"0001_add_users.sql": script("")}},
+		{"only a down script", fstest.MapFS{"0001_add_users.down.sql": script("DROP TABLE users;")}},
+		{"two names for a version", fstest.MapFS{
+			"0001_add_users.up.sql":      script("CREATE TABLE users ();"),
+			"0001_create_users.down.sql": script("DROP TABLE users;"),
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewMigrator(nil, tt.dir); err == nil {
+				t.Error("NewMigrator succeeded, want an error")
+			}
+		})
+	}
+}
+
+func TestMigrationsCanBeRolledBack(t *testing.T) {
+	for name, dir := range map[string]fs.FS{"postgres": PostgresMigrations(), "sqlite": SQLiteMigrations()} {
+		m, err := NewMigrator(nil, dir)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		for _, mig := range m.migrations {
+			if mig.Down == "" {
+				t.Errorf("%s migration %04d_%s has no down script", name, mig.Version, mig.Name)
+			}
+		}
+	}
+}
+
+// testSchema is where the Postgres tests migrate, so that they leave the
+// schema other tests of the same database use alone.
+const testSchema = "migrate_test"
+
+// openPostgres connects to TEST_DATABASE_URL with an empty testSchema first
+// on the search path, and skips the test when the variable is not set.
+func openPostgres(t *testing.T) *gorm.DB {
+	t.Helper()
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+
+	admin := InitDB(dsn)
+	t.Cleanup(func() { closeDB(t, admin) })
+	// An extension exists once per database, in the schema it was created
+	// in; create them where the other tests look for them.
+	for _, stmt := range []string{
+		"DROP SCHEMA IF EXISTS " + testSchema + " CASCADE",
+		"CREATE SCHEMA " + testSchema,
+		"CREATE EXTENSION IF NOT EXISTS cube SCHEMA public",
+		"CREATE EXTENSION IF NOT EXISTS earthdistance SCHEMA public",
+		"CREATE EXTENSION IF NOT EXISTS pg_trgm SCHEMA public",
+	} {
+		if err := admin.Exec(stmt).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Cleanup(func() {
+		if err := admin.Exec("DROP SCHEMA " + testSchema + " CASCADE").Error; err != nil {
+			t.Error(err)
+		}
+	})
+
+	gormDB := InitDB(withSearchPath(dsn, testSchema+",public"))
+	t.Cleanup(func() { closeDB(t, gormDB) })
+	return gormDB
+}
+
+// withSearchPath adds a search_path run-time parameter to a URL or
+// keyword/value connection string.
+func withSearchPath(dsn, searchPath string) string {
+	if !strings.Contains(dsn, "://") {
+		return dsn + " search_path=" + searchPath
+	}
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return dsn
+	}
+	q := u.Query()
+	q.Set("search_path", searchPath)
+	u.RawQuery = q.Encode()
+	return u.String()
+}
+
+func closeDB(t *testing.T, gormDB *gorm.DB) {
+	sqlDB, err := gormDB.DB()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if err := sqlDB.Close(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestPostgresMigrationsRoundTrip(t *testing.T) {
+	checkRoundTrip(t, openPostgres(t), PostgresMigrations())
+}
+
+func TestSQLiteMigrationsRoundTrip(t *testing.T) {
+	gormDB := InitSQLite(filepath.Join(t.TempDir(), "migrate.db"))
+	t.Cleanup(func() { closeDB(t, gormDB) })
+	checkRoundTrip(t, gormDB, SQLiteMigrations())
+}
+
+// checkRoundTrip migrates an empty database all the way up, down and up
+// again.
+func checkRoundTrip(t *testing.T, gormDB *gorm.DB, dir fs.FS) {
+	t.Helper()
+	ctx := context.Background()
+	m, err := NewMigrator(gormDB, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	applied, err := m.Up(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(applied) != len(m.migrations) {
+		t.Errorf("applied %d migrations, want %d", len(applied), len(m.migrations))
+	}
+	if err := m.Check(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	reverted, err := m.Down(ctx, len(m.migrations))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reverted) != len(m.migrations) {
+		t.Errorf("reverted %d migrations, want %d", len(reverted), len(m.migrations))
+	}
+	status, err := m.Status(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.Current != 0 || len(status.Pending) != len(m.migrations) {
+		t.Errorf("after rolling back: at version %d with %d pending, want 0 and %d", status.Current, len(status.Pending), len(m.migrations))
+	}
+	for _, table := range []string{"users", "places", "user_places"} {
+		if gormDB.Migrator().HasTable(table) {
+			t.Errorf("table %s is left after rolling back", table)
+		}
+	}
+
+	// The down scripts must leave nothing behind that stops a second run.
+	if _, err := m.Up(ctx); err != nil {
+		t.Fatalf("migrating up again: %v", err)
+	}
+}
+
+// upTo returns the migrations in dir up to and including version.
+func upTo(t *testing.T, dir fs.FS, version int64) fs.FS {
+	t.Helper()
+	entries, err := fs.ReadDir(dir, ".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sub := fstest.MapFS{}
+	for _, e := range entries {
+		v, _, _, err := parseMigrationName(e.Name())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if v > version {
+			continue
+		}
+		data, err := fs.ReadFile(dir, e.Name())
+		if err != nil {
+			t.Fatal(err)
+		}
+		sub[e.Name()] = script(string(data))
+	}
+	return sub
+}
+
+func TestPostgresCaseInsensitiveEmailsMigration(t *testing.T) {
+	gormDB := openPostgres(t)
+	ctx := context.Background()
+	exec := func(sql string, args ...any) error {
+		return gormDB.Exec(sql, args...).Error
+	}
+
+	before, err := NewMigrator(gormDB, upTo(t, PostgresMigrations(), 12))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := before.Up(ctx); err != nil {
+		t.Fatal(err)
+	}
+	// Until 0013, emails that differ in case or spacing are different.
+	users := []struct{ name, email string }{
+		{"ada", " Ada@Example.com"},
+		{"ada again", "ada@example.com"},
+		{"bob", "BOB@example.com"},
+		{"bob's old account", "bob@example.com"},
+	}
+	for _, u := range users {
+		if err := exec("INSERT INTO users (name, email) VALUES (?, ?)", u.name, u.email); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := exec("UPDATE users SET deleted_at = now() WHERE name = ?", "bob's old account"); err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := NewMigrator(gormDB, PostgresMigrations())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Up(ctx); err == nil {
+		t.Fatal("0013 succeeded with two live accounts for one email")
+	}
+	if status, err := m.Status(ctx); err != nil || status.Current != 12 {
+		t.Fatalf("after the failed run: at version %d (%v), want 12", status.Current, err)
+	}
+
+	// A deleted account may share its email with a live one.
+	if err := exec("UPDATE users SET deleted_at = now() WHERE name = ?", "ada again"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Up(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	var emails []string
+	if err := gormDB.Raw("SELECT email FROM users ORDER BY email, name").Scan(&emails).Error; err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"ada@example.com", "ada@example.com", "bob@example.com", "bob@example.com"}
+	if strings.Join(emails, " ") != strings.Join(want, " ") {
+		t.Errorf("emails after 0013: %q, want %q", emails, want)
+	}
+	if err := exec("INSERT INTO users (name, email) VALUES (?, ?)", "ada's twin", "ADA@example.com"); err == nil {
+		t.Error("inserted a live account for a taken email in another case")
+	}
+
+	// Rolling back needs the emails to be unique again, deleted or not.
+	if _, err := m.Down(ctx, 1); err == nil {
+		t.Fatal("rolled back 0013 with two accounts for one email")
+	}
+	if err := exec("DELETE FROM users WHERE deleted_at IS NOT NULL"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Down(ctx, 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := exec("INSERT INTO users (name, email) VALUES (?, ?)", "ada's twin", "ADA@example.com"); err != nil {
+		t.Errorf("after rolling back 0013, emails in another case should be distinct: %v", err)
+	}
+}
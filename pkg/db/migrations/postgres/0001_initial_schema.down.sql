@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS user_places;
+DROP TABLE IF EXISTS places;
+DROP TABLE IF EXISTS users;
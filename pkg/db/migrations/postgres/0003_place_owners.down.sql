@@ -0,0 +1 @@
+ALTER TABLE places DROP COLUMN IF EXISTS created_by;
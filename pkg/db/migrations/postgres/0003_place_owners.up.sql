@@ -0,0 +1,11 @@
+-- Places remember who created them. Existing places have no known creator,
+-- so only the roles allowed to change any place can change them.
+
+ALTER TABLE places
+    ADD COLUMN created_by UUID,
+    ADD CONSTRAINT fk_places_created_by
+        FOREIGN KEY (created_by)
+        REFERENCES users (id)
+        ON DELETE SET NULL;
+
+CREATE INDEX idx_places_created_by ON places (created_by);
@@ -0,0 +1,23 @@
+-- Only the first visit of each user to each place survives, since the
+-- pair becomes the primary key again.
+
+DELETE FROM user_places later
+USING user_places earlier
+WHERE later.user_id = earlier.user_id
+  AND later.place_id = earlier.place_id
+  AND (later.visited_at, later.id) > (earlier.visited_at, earlier.id);
+
+DROP INDEX IF EXISTS idx_user_places_place_id;
+DROP INDEX IF EXISTS idx_user_places_user_place;
+
+ALTER TABLE user_places DROP CONSTRAINT user_places_pkey;
+
+ALTER TABLE user_places
+    DROP COLUMN created_at,
+    DROP COLUMN photo_ref,
+    DROP COLUMN rating,
+    DROP COLUMN note,
+    DROP COLUMN visited_at,
+    DROP COLUMN id;
+
+ALTER TABLE user_places ADD PRIMARY KEY (user_id, place_id);
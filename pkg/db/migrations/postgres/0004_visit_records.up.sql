@@ -0,0 +1,20 @@
+-- A visit becomes a record of its own, so a user can visit a place more
+-- than once. Visits stored before this migration carry no date; they are
+-- dated to the time it runs.
+
+ALTER TABLE user_places DROP CONSTRAINT user_places_pkey;
+
+ALTER TABLE user_places
+    ADD COLUMN id UUID NOT NULL DEFAULT gen_random_uuid(),
+    ADD COLUMN visited_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    ADD COLUMN note TEXT,
+    ADD COLUMN rating SMALLINT CHECK (rating BETWEEN 1 AND 5),
+    ADD COLUMN photo_ref VARCHAR(512),
+    ADD COLUMN created_at TIMESTAMP WITH TIME ZONE;
+
+ALTER TABLE user_places ADD PRIMARY KEY (id);
+
+UPDATE user_places SET created_at = visited_at;
+
+CREATE INDEX idx_user_places_user_place ON user_places (user_id, place_id, visited_at DESC);
+CREATE INDEX idx_user_places_place_id ON user_places (place_id);
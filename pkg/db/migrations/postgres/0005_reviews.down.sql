@@ -0,0 +1,12 @@
+-- Places keep their average, rounded, as the single rating they had
+-- before reviews.
+
+ALTER TABLE places ADD COLUMN rating INTEGER;
+
+UPDATE places SET rating = round(average_rating) WHERE review_count > 0;
+
+ALTER TABLE places
+    DROP COLUMN review_count,
+    DROP COLUMN average_rating;
+
+DROP TABLE IF EXISTS reviews;
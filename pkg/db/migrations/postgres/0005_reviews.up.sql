@@ -0,0 +1,40 @@
+-- A place's rating is computed from its reviews instead of being set by
+-- whoever edits the place. The old rating cannot be attributed to any
+-- user, so it is dropped and every place starts without reviews.
+
+CREATE TABLE reviews (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    place_id UUID NOT NULL,
+    user_id UUID NOT NULL,
+    rating SMALLINT NOT NULL CHECK (rating BETWEEN 1 AND 5),
+    comment TEXT,
+    hidden BOOLEAN NOT NULL DEFAULT FALSE,
+    hidden_by UUID,
+    hidden_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE,
+    updated_at TIMESTAMP WITH TIME ZONE,
+
+    CONSTRAINT uq_reviews_place_user UNIQUE (place_id, user_id),
+
+    CONSTRAINT fk_reviews_place
+        FOREIGN KEY (place_id)
+        REFERENCES places (id)
+        ON DELETE CASCADE,
+
+    CONSTRAINT fk_reviews_user
+        FOREIGN KEY (user_id)
+        REFERENCES users (id)
+        ON DELETE CASCADE,
+
+    CONSTRAINT fk_reviews_hidden_by
+        FOREIGN KEY (hidden_by)
+        REFERENCES users (id)
+        ON DELETE SET NULL
+);
+
+CREATE INDEX idx_reviews_place_updated ON reviews (place_id, updated_at DESC);
+
+ALTER TABLE places
+    DROP COLUMN rating,
+    ADD COLUMN average_rating NUMERIC(3, 2) NOT NULL DEFAULT 0,
+    ADD COLUMN review_count INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_places_average_rating_id;
+DROP INDEX IF EXISTS idx_places_created_at_id;
+DROP INDEX IF EXISTS idx_places_name_id;
+DROP INDEX IF EXISTS idx_users_created_at_id;
+DROP INDEX IF EXISTS idx_users_name_id;
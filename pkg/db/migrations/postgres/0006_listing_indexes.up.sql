@@ -0,0 +1,7 @@
+-- Keyset paging orders by the sort column and then the id.
+
+CREATE INDEX idx_users_name_id ON users (name, id);
+CREATE INDEX idx_users_created_at_id ON users (created_at, id);
+CREATE INDEX idx_places_name_id ON places (name, id);
+CREATE INDEX idx_places_created_at_id ON places (created_at, id);
+CREATE INDEX idx_places_average_rating_id ON places (average_rating, id);
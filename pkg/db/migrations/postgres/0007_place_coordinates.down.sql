@@ -0,0 +1,12 @@
+-- The extensions are left in place: other schemas may use them.
+
+ALTER TABLE places ADD COLUMN location JSONB;
+
+UPDATE places SET location = jsonb_build_object('latitude', latitude, 'longitude', longitude);
+
+DROP INDEX IF EXISTS idx_places_lat_lng;
+DROP INDEX IF EXISTS idx_places_earth;
+
+ALTER TABLE places
+    DROP COLUMN longitude,
+    DROP COLUMN latitude;
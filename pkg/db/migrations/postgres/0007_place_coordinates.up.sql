@@ -0,0 +1,23 @@
+-- Coordinates move out of the location JSON into columns that can be
+-- indexed for distance queries. The API has always required a location,
+-- so a place without one would be a bug; it is put at 0, 0 rather than
+-- blocking the migration.
+
+CREATE EXTENSION IF NOT EXISTS cube;
+CREATE EXTENSION IF NOT EXISTS earthdistance;
+
+ALTER TABLE places
+    ADD COLUMN latitude DOUBLE PRECISION,
+    ADD COLUMN longitude DOUBLE PRECISION;
+
+UPDATE places SET
+    latitude = coalesce((location->>'latitude')::DOUBLE PRECISION, 0),
+    longitude = coalesce((location->>'longitude')::DOUBLE PRECISION, 0);
+
+ALTER TABLE places
+    ALTER COLUMN latitude SET NOT NULL,
+    ALTER COLUMN longitude SET NOT NULL,
+    DROP COLUMN location;
+
+CREATE INDEX idx_places_earth ON places USING gist (ll_to_earth(latitude, longitude));
+CREATE INDEX idx_places_lat_lng ON places (latitude, longitude);
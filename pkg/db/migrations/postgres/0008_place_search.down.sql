@@ -0,0 +1,6 @@
+-- The extension is left in place: other schemas may use it.
+
+DROP INDEX IF EXISTS idx_places_address_trgm;
+DROP INDEX IF EXISTS idx_places_name_trgm;
+
+ALTER TABLE places DROP COLUMN IF EXISTS search_vector;
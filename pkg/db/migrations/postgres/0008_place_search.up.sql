@@ -0,0 +1,14 @@
+-- Ranked full-text search over the name, address and description, and
+-- trigram indexes for prefix and typo-tolerant matching.
+
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+ALTER TABLE places ADD COLUMN search_vector TSVECTOR GENERATED ALWAYS AS (
+    setweight(to_tsvector('simple', coalesce(name, '')), 'A') ||
+    setweight(to_tsvector('simple', coalesce(address, '')), 'B') ||
+    setweight(to_tsvector('simple', coalesce(description, '')), 'C')
+) STORED;
+
+CREATE INDEX idx_places_search_vector ON places USING gin (search_vector);
+CREATE INDEX idx_places_name_trgm ON places USING gin (name gin_trgm_ops);
+CREATE INDEX idx_places_address_trgm ON places USING gin (address gin_trgm_ops);
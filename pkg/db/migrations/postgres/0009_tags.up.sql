@@ -0,0 +1,25 @@
+CREATE TABLE tags (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    name VARCHAR(50) NOT NULL,
+    slug VARCHAR(50) NOT NULL UNIQUE,
+    created_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE TABLE place_tags (
+    place_id UUID NOT NULL,
+    tag_id UUID NOT NULL,
+
+    PRIMARY KEY (place_id, tag_id),
+
+    CONSTRAINT fk_place_tags_place
+        FOREIGN KEY (place_id)
+        REFERENCES places (id)
+        ON DELETE CASCADE,
+
+    CONSTRAINT fk_place_tags_tag
+        FOREIGN KEY (tag_id)
+        REFERENCES tags (id)
+        ON DELETE CASCADE
+);
+
+CREATE INDEX idx_place_tags_tag_id ON place_tags (tag_id);
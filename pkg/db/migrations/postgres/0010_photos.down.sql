@@ -0,0 +1,3 @@
+-- The blobs of the dropped photos stay in the blob store.
+
+DROP TABLE IF EXISTS photos;
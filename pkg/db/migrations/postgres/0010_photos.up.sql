@@ -0,0 +1,26 @@
+-- The image data lives in the blob store; a row only describes it.
+
+CREATE TABLE photos (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    place_id UUID NOT NULL,
+    uploaded_by UUID,
+    content_type VARCHAR(50) NOT NULL,
+    thumbnail_type VARCHAR(50) NOT NULL,
+    size BIGINT NOT NULL,
+    width INTEGER NOT NULL,
+    height INTEGER NOT NULL,
+    checksum CHAR(64) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE,
+
+    CONSTRAINT fk_photos_place
+        FOREIGN KEY (place_id)
+        REFERENCES places (id)
+        ON DELETE CASCADE,
+
+    CONSTRAINT fk_photos_uploaded_by
+        FOREIGN KEY (uploaded_by)
+        REFERENCES users (id)
+        ON DELETE SET NULL
+);
+
+CREATE INDEX idx_photos_place_created ON photos (place_id, created_at DESC);
@@ -0,0 +1,23 @@
+CREATE TABLE audit_entries (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    entity_type VARCHAR(20) NOT NULL,
+    entity_id UUID NOT NULL,
+    action VARCHAR(20) NOT NULL,
+    actor_id UUID,
+    changes JSONB NOT NULL DEFAULT '[]',
+    snapshot JSONB,
+    revert_of UUID,
+    created_at TIMESTAMP WITH TIME ZONE,
+
+    CONSTRAINT fk_audit_entries_actor
+        FOREIGN KEY (actor_id)
+        REFERENCES users (id)
+        ON DELETE SET NULL,
+
+    CONSTRAINT fk_audit_entries_revert_of
+        FOREIGN KEY (revert_of)
+        REFERENCES audit_entries (id)
+        ON DELETE SET NULL
+);
+
+CREATE INDEX idx_audit_entries_entity ON audit_entries (entity_type, entity_id, created_at DESC);
@@ -0,0 +1,4 @@
+-- Every write bumps the version, which clients send back in If-Match.
+
+ALTER TABLE users ADD COLUMN version BIGINT NOT NULL DEFAULT 1;
+ALTER TABLE places ADD COLUMN version BIGINT NOT NULL DEFAULT 1;
@@ -0,0 +1,6 @@
+-- Fails when a live user has reused the email of a deleted one; purge the
+-- deleted user first. Emails stay lower-cased.
+
+DROP INDEX IF EXISTS idx_users_email;
+
+ALTER TABLE users ADD CONSTRAINT users_email_key UNIQUE (email);
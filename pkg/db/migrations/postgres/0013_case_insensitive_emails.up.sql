@@ -0,0 +1,10 @@
+-- Emails are stored normalized and unique regardless of case, and only
+-- among live users, so a deleted user's email can be registered again.
+-- Two live accounts whose emails differ only in case make this migration
+-- fail; merge or delete one of them and run it again.
+
+ALTER TABLE users DROP CONSTRAINT users_email_key;
+
+UPDATE users SET email = lower(trim(email));
+
+CREATE UNIQUE INDEX idx_users_email ON users (lower(email)) WHERE deleted_at IS NULL;
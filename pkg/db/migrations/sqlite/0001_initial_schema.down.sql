@@ -0,0 +1,11 @@
+DROP TABLE IF EXISTS audit_entries;
+DROP TABLE IF EXISTS reviews;
+DROP TABLE IF EXISTS user_places;
+DROP TABLE IF EXISTS photos;
+DROP TABLE IF EXISTS place_tags;
+DROP TABLE IF EXISTS tags;
+DROP TABLE IF EXISTS places;
+DROP TABLE IF EXISTS api_keys;
+DROP TABLE IF EXISTS refresh_tokens;
+DROP TABLE IF EXISTS sessions;
+DROP TABLE IF EXISTS users;
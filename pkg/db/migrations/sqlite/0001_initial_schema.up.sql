@@ -0,0 +1,155 @@
+-- The SQLite counterpart of the Postgres schema. Ids are generated by the
+-- application, timestamps are stored as DATETIME text and JSON documents as
+-- TEXT. Foreign keys are only enforced on connections opened with
+-- foreign_keys on, which InitSQLite does.
+
+CREATE TABLE IF NOT EXISTS users (
+    id TEXT PRIMARY KEY NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    email VARCHAR(255) NOT NULL,
+    password_hash VARCHAR(255),
+    role VARCHAR(20) NOT NULL DEFAULT 'member' CHECK (role IN ('admin', 'editor', 'member')),
+    version INTEGER NOT NULL DEFAULT 1,
+    created_at DATETIME,
+    updated_at DATETIME,
+    deleted_at DATETIME
+);
+
+CREATE INDEX IF NOT EXISTS idx_users_deleted_at ON users (deleted_at);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_email ON users (lower(email)) WHERE deleted_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_users_name_id ON users (name, id);
+CREATE INDEX IF NOT EXISTS idx_users_created_at_id ON users (created_at, id);
+
+CREATE TABLE IF NOT EXISTS sessions (
+    id VARCHAR(64) PRIMARY KEY NOT NULL,
+    user_id TEXT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    expires_at DATETIME NOT NULL,
+    created_at DATETIME
+);
+
+CREATE INDEX IF NOT EXISTS idx_sessions_user_id ON sessions (user_id);
+
+CREATE TABLE IF NOT EXISTS refresh_tokens (
+    id VARCHAR(64) PRIMARY KEY NOT NULL,
+    user_id TEXT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    family_id TEXT NOT NULL,
+    expires_at DATETIME NOT NULL,
+    revoked_at DATETIME,
+    created_at DATETIME
+);
+
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_user_id ON refresh_tokens (user_id);
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_family_id ON refresh_tokens (family_id);
+
+CREATE TABLE IF NOT EXISTS api_keys (
+    id TEXT PRIMARY KEY NOT NULL,
+    user_id TEXT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    prefix VARCHAR(16) NOT NULL,
+    key_hash VARCHAR(64) NOT NULL UNIQUE,
+    scope VARCHAR(20) NOT NULL CHECK (scope IN ('read-only', 'read-write')),
+    expires_at DATETIME,
+    last_used_at DATETIME,
+    created_at DATETIME
+);
+
+CREATE INDEX IF NOT EXISTS idx_api_keys_user_id ON api_keys (user_id);
+
+CREATE TABLE IF NOT EXISTS places (
+    id TEXT PRIMARY KEY NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    description TEXT,
+    latitude REAL NOT NULL,
+    longitude REAL NOT NULL,
+    address VARCHAR(255),
+    average_rating REAL NOT NULL DEFAULT 0,
+    review_count INTEGER NOT NULL DEFAULT 0,
+    created_by TEXT REFERENCES users (id) ON DELETE SET NULL,
+    version INTEGER NOT NULL DEFAULT 1,
+    created_at DATETIME,
+    updated_at DATETIME,
+    deleted_at DATETIME
+);
+
+CREATE INDEX IF NOT EXISTS idx_places_deleted_at ON places (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_places_created_by ON places (created_by);
+CREATE INDEX IF NOT EXISTS idx_places_name_id ON places (name, id);
+CREATE INDEX IF NOT EXISTS idx_places_created_at_id ON places (created_at, id);
+CREATE INDEX IF NOT EXISTS idx_places_average_rating_id ON places (average_rating, id);
+CREATE INDEX IF NOT EXISTS idx_places_lat_lng ON places (latitude, longitude);
+
+CREATE TABLE IF NOT EXISTS tags (
+    id TEXT PRIMARY KEY NOT NULL,
+    name VARCHAR(50) NOT NULL,
+    slug VARCHAR(50) NOT NULL UNIQUE,
+    created_at DATETIME
+);
+
+CREATE TABLE IF NOT EXISTS place_tags (
+    place_id TEXT NOT NULL REFERENCES places (id) ON DELETE CASCADE,
+    tag_id TEXT NOT NULL REFERENCES tags (id) ON DELETE CASCADE,
+
+    PRIMARY KEY (place_id, tag_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_place_tags_tag_id ON place_tags (tag_id);
+
+CREATE TABLE IF NOT EXISTS photos (
+    id TEXT PRIMARY KEY NOT NULL,
+    place_id TEXT NOT NULL REFERENCES places (id) ON DELETE CASCADE,
+    uploaded_by TEXT REFERENCES users (id) ON DELETE SET NULL,
+    content_type VARCHAR(50) NOT NULL,
+    thumbnail_type VARCHAR(50) NOT NULL,
+    size INTEGER NOT NULL,
+    width INTEGER NOT NULL,
+    height INTEGER NOT NULL,
+    checksum CHAR(64) NOT NULL,
+    created_at DATETIME
+);
+
+CREATE INDEX IF NOT EXISTS idx_photos_place_created ON photos (place_id, created_at DESC);
+
+CREATE TABLE IF NOT EXISTS user_places (
+    id TEXT PRIMARY KEY NOT NULL,
+    user_id TEXT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    place_id TEXT NOT NULL REFERENCES places (id) ON DELETE CASCADE,
+    visited_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    note TEXT,
+    rating INTEGER CHECK (rating BETWEEN 1 AND 5),
+    photo_ref VARCHAR(512),
+    created_at DATETIME
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_places_user_place ON user_places (user_id, place_id, visited_at DESC);
+CREATE INDEX IF NOT EXISTS idx_user_places_place_id ON user_places (place_id);
+
+CREATE TABLE IF NOT EXISTS reviews (
+    id TEXT PRIMARY KEY NOT NULL,
+    place_id TEXT NOT NULL REFERENCES places (id) ON DELETE CASCADE,
+    user_id TEXT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    rating INTEGER NOT NULL CHECK (rating BETWEEN 1 AND 5),
+    comment TEXT,
+    hidden BOOLEAN NOT NULL DEFAULT FALSE,
+    hidden_by TEXT REFERENCES users (id) ON DELETE SET NULL,
+    hidden_at DATETIME,
+    created_at DATETIME,
+    updated_at DATETIME,
+
+    CONSTRAINT uq_reviews_place_user UNIQUE (place_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_reviews_place_updated ON reviews (place_id, updated_at DESC);
+
+CREATE TABLE IF NOT EXISTS audit_entries (
+    id TEXT PRIMARY KEY NOT NULL,
+    entity_type VARCHAR(20) NOT NULL,
+    entity_id TEXT NOT NULL,
+    action VARCHAR(20) NOT NULL,
+    actor_id TEXT REFERENCES users (id) ON DELETE SET NULL,
+    changes TEXT NOT NULL DEFAULT '[]',
+    snapshot TEXT,
+    revert_of TEXT REFERENCES audit_entries (id) ON DELETE SET NULL,
+    created_at DATETIME
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_entries_entity ON audit_entries (entity_type, entity_id, created_at DESC);
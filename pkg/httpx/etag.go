@@ -0,0 +1,47 @@
+package httpx
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	er "deu/internal/errors"
+)
+
+// VersionETag is the strong entity tag for a version of a resource.
+func VersionETag(version int64) string {
+	return `"` + strconv.FormatInt(version, 10) + `"`
+}
+
+// IfMatchVersion reads the version a PATCH or DELETE is conditional on.
+// If-Match must be present; "*" matches any version and comes back as 0.
+// A tag that cannot be one of ours can never match, so it fails with 412.
+func IfMatchVersion(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	header := strings.TrimSpace(r.Header.Get("If-Match"))
+	if header == "" {
+		er.Write(w, r, er.ErrPreconditionRequired)
+		return 0, false
+	}
+	if header == "*" {
+		return 0, true
+	}
+
+	if len(header) > 2 && header[0] == '"' && header[len(header)-1] == '"' {
+		if version, err := strconv.ParseInt(header[1:len(header)-1], 10, 64); err == nil && version > 0 {
+			return version, true
+		}
+	}
+	er.Write(w, r, er.ErrVersionMismatch)
+	return 0, false
+}
+
+// ETagMatches reports whether an If-None-Match header lists etag.
+func ETagMatches(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
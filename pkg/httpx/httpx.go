@@ -0,0 +1,26 @@
+// Package httpx holds the request parsing and response writing shared by
+// the API handlers: typed path parameters, size-limited strict JSON bodies,
+// validation, paging parameters, entity tags and JSON responses. Failures
+// are written as problem details, so a handler only has to return when a
+// helper reports false.
+package httpx
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// JSON writes v as the JSON body of a response with the given status.
+func JSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if v != nil {
+		json.NewEncoder(w).Encode(v)
+	}
+}
+
+// Status writes a {"status": message} body, the reply of actions that have
+// nothing else to return.
+func Status(w http.ResponseWriter, status int, message string) {
+	JSON(w, status, map[string]string{"status": message})
+}
@@ -0,0 +1,175 @@
+package httpx
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+
+	er "deu/internal/errors"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+)
+
+// MaxBodyBytes bounds the JSON bodies read by Decode.
+const MaxBodyBytes = 1 << 20
+
+const (
+	DefaultPageLimit = 20
+	MaxPageLimit     = 100
+)
+
+var validate = newValidator()
+
+func newValidator() *validator.Validate {
+	v := validator.New()
+	v.RegisterTagNameFunc(er.JSONFieldName)
+	return v
+}
+
+// Validate checks v against its validate tags. It returns an ErrValidation
+// listing every failing field by its JSON path.
+func Validate(v any) error {
+	return er.FromValidation(validate.Struct(v))
+}
+
+// PathUUID returns the named path parameter in canonical form, or writes
+// ErrInvalidID when it is not a UUID.
+func PathUUID(w http.ResponseWriter, r *http.Request, name string) (string, bool) {
+	value := r.PathValue(name)
+	id, err := uuid.Parse(value)
+	if err != nil || len(value) != 36 {
+		er.Write(w, r, er.WithDetail(er.ErrInvalidID, "Path parameter %q must be a valid UUID.", name))
+		return "", false
+	}
+	return id.String(), true
+}
+
+// Decode reads a JSON body into dst and validates it. The body may be at
+// most MaxBodyBytes, must hold exactly one value and may not have fields
+// that dst does not declare, so that misspelt fields are not silently
+// ignored.
+func Decode(w http.ResponseWriter, r *http.Request, dst any) bool {
+	return decode(w, r, dst, false)
+}
+
+// DecodeOptional is Decode for requests whose body may be left out, in
+// which case dst keeps its zero value and is validated as such.
+func DecodeOptional(w http.ResponseWriter, r *http.Request, dst any) bool {
+	return decode(w, r, dst, true)
+}
+
+func decode(w http.ResponseWriter, r *http.Request, dst any, optional bool) bool {
+	r.Body = http.MaxBytesReader(w, r.Body, MaxBodyBytes)
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+
+	err := dec.Decode(dst)
+	switch {
+	case errors.Is(err, io.EOF) && optional:
+		err = nil
+	case err == nil:
+		if dec.Decode(&json.RawMessage{}) != io.EOF {
+			err = errTrailingData
+		}
+	}
+	if err != nil {
+		er.Write(w, r, decodeError(err))
+		return false
+	}
+
+	if err := Validate(dst); err != nil {
+		er.Write(w, r, err)
+		return false
+	}
+	return true
+}
+
+var errTrailingData = errors.New("trailing data")
+
+// decodeError explains why a body could not be decoded without echoing the
+// decoder's own wording.
+func decodeError(err error) error {
+	var (
+		tooLarge  *http.MaxBytesError
+		syntaxErr *json.SyntaxError
+		typeErr   *json.UnmarshalTypeError
+	)
+	switch {
+	case errors.As(err, &tooLarge):
+		return er.WithDetail(er.ErrBodyTooLarge, "The request body must be at most %d bytes.", tooLarge.Limit)
+	case errors.Is(err, io.EOF):
+		return er.WithDetail(er.ErrInvalidJSON, "The request body is empty.")
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		return er.WithDetail(er.ErrInvalidJSON, "The request body ends in the middle of a JSON value.")
+	case errors.As(err, &syntaxErr):
+		return er.WithDetail(er.ErrInvalidJSON, "The request body has a JSON syntax error at byte %d.", syntaxErr.Offset)
+	case errors.As(err, &typeErr) && typeErr.Field != "":
+		return er.Validation([]er.FieldError{{
+			Field:   typeErr.Field,
+			Code:    "type",
+			Message: "must be " + jsonType(typeErr.Type),
+		}})
+	case errors.Is(err, errTrailingData):
+		return er.WithDetail(er.ErrInvalidJSON, "The request body must hold a single JSON value.")
+	}
+
+	// encoding/json has no error type for unknown fields.
+	if field, ok := strings.CutPrefix(err.Error(), "json: unknown field "); ok {
+		return er.Validation([]er.FieldError{{
+			Field:   strings.Trim(field, `"`),
+			Code:    "unknown",
+			Message: "is not a known field",
+		}})
+	}
+	return er.ErrInvalidJSON
+}
+
+func jsonType(t reflect.Type) string {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return "a string"
+	case reflect.Bool:
+		return "true or false"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "an integer"
+	case reflect.Float32, reflect.Float64:
+		return "a number"
+	case reflect.Slice, reflect.Array:
+		return "an array"
+	}
+	return "an object"
+}
+
+// Pagination reads the optional limit and offset query parameters.
+func Pagination(w http.ResponseWriter, r *http.Request) (limit, offset int, ok bool) {
+	limit = DefaultPageLimit
+	query := r.URL.Query()
+
+	if v := query.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > MaxPageLimit {
+			er.Write(w, r, er.WithDetail(er.ErrInvalidQuery, "limit must be between 1 and %d", MaxPageLimit))
+			return 0, 0, false
+		}
+		limit = n
+	}
+	if v := query.Get("offset"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			er.Write(w, r, er.WithDetail(er.ErrInvalidQuery, "offset must be a non-negative integer"))
+			return 0, 0, false
+		}
+		offset = n
+	}
+
+	return limit, offset, true
+}
@@ -0,0 +1,116 @@
+package httpx
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	er "deu/internal/errors"
+)
+
+type testBody struct {
+	Name  string `json:"name" validate:"required"`
+	Count *int   `json:"count,omitempty"`
+}
+
+// decodeBody runs Decode or DecodeOptional on body and returns the problem
+// it wrote, if any.
+func decodeBody(t *testing.T, body string, optional bool) (testBody, *er.ErrorResponse) {
+	t.Helper()
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/things", strings.NewReader(body))
+
+	var dst testBody
+	var ok bool
+	if optional {
+		ok = DecodeOptional(w, r, &dst)
+	} else {
+		ok = Decode(w, r, &dst)
+	}
+	if ok {
+		return dst, nil
+	}
+
+	var problem er.ErrorResponse
+	if err := json.NewDecoder(w.Body).Decode(&problem); err != nil {
+		t.Fatal(err)
+	}
+	return dst, &problem
+}
+
+func TestDecode(t *testing.T) {
+	tests := []struct {
+		name      string
+		body      string
+		wantCode  string
+		wantField string
+	}{
+		{"valid", `{"name":"Louvre","count":2}`, "", ""},
+		{"empty", ``, "invalid_json", ""},
+		{"truncated", `{"name":"Lou`, "invalid_json", ""},
+		{"syntax error", `{"name":}`, "invalid_json", ""},
+		{"trailing data", `{"name":"Louvre"} {}`, "invalid_json", ""},
+		{"unknown field", `{"name":"Louvre","nmae":"Louvre"}`, "validation_failed", "nmae"},
+		{"wrong type", `{"name":"Louvre","count":"two"}`, "validation_failed", "count"},
+		{"missing required field", `{"count":2}`, "validation_failed", "name"},
+		{"too large", `{"name":"` + strings.Repeat("x", MaxBodyBytes) + `"}`, "body_too_large", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, problem := decodeBody(t, tt.body, false)
+			if tt.wantCode == "" {
+				if problem != nil {
+					t.Fatalf("got problem %+v, want none", problem)
+				}
+				return
+			}
+			if problem == nil {
+				t.Fatalf("got no problem, want %q", tt.wantCode)
+			}
+			if problem.Code != tt.wantCode {
+				t.Errorf("code %q, want %q", problem.Code, tt.wantCode)
+			}
+			if tt.wantField != "" && (len(problem.Errors) != 1 || problem.Errors[0].Field != tt.wantField) {
+				t.Errorf("errors %+v, want one for %q", problem.Errors, tt.wantField)
+			}
+		})
+	}
+}
+
+func TestDecodeOptionalAcceptsNoBody(t *testing.T) {
+	if _, problem := decodeBody(t, ``, true); problem == nil || problem.Code != "validation_failed" {
+		t.Errorf("empty body: got %+v, want the zero value to fail validation", problem)
+	}
+	if _, problem := decodeBody(t, `{"name":"Louvre"} {}`, true); problem == nil || problem.Code != "invalid_json" {
+		t.Errorf("trailing data: got %+v, want invalid_json", problem)
+	}
+}
+
+func TestPathUUID(t *testing.T) {
+	tests := []struct {
+		value  string
+		want   string
+		wantOK bool
+	}{
+		{"5b7c1ff2-0cbe-4b2a-9a4b-1c3c7f6e2d10", "5b7c1ff2-0cbe-4b2a-9a4b-1c3c7f6e2d10", true},
+		{"5B7C1FF2-0CBE-4B2A-9A4B-1C3C7F6E2D10", "5b7c1ff2-0cbe-4b2a-9a4b-1c3c7f6e2d10", true},
+		{"{5b7c1ff2-0cbe-4b2a-9a4b-1c3c7f6e2d10}", "", false},
+		{"5b7c1ff20cbe4b2a9a4b1c3c7f6e2d10", "", false},
+		{"louvre", "", false},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/places/x", nil)
+		r.SetPathValue("id", tt.value)
+
+		got, ok := PathUUID(w, r, "id")
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("PathUUID(%q) = %q, %v, want %q, %v", tt.value, got, ok, tt.want, tt.wantOK)
+		}
+		if !ok && w.Code != http.StatusBadRequest {
+			t.Errorf("PathUUID(%q) wrote status %d, want 400", tt.value, w.Code)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package router
+
+import (
+	"net/http"
+	"strings"
+
+	"deu/internal/auth"
+	er "deu/internal/errors"
+
+	"github.com/google/uuid"
+)
+
+// RequestIDHeader carries the id of a request in both directions.
+const RequestIDHeader = "X-Request-ID"
+
+// RequestID gives every request an id, echoed in the X-Request-ID response
+// header, stored in the context and included in error bodies. An id sent by
+// the client or a proxy is kept if it is short and plain enough to log.
+func RequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(RequestIDHeader)
+		if !validRequestID(id) {
+			id = uuid.New().String()
+		}
+
+		w.Header().Set(RequestIDHeader, id)
+		next.ServeHTTP(w, r.WithContext(er.WithRequestID(r.Context(), id)))
+	})
+}
+
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 64 {
+		return false
+	}
+	for _, c := range id {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9', c == '-', c == '_', c == '.':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// AuthMiddleware identifies the caller from an "Authorization: Bearer" access
+// token, an "Authorization: ApiKey" personal key or the session cookie, and
+// stores the principal in the request context. A request whose Authorization
+// header does not verify is rejected outright; requests with no credentials
+// continue anonymously and route policies decide whether that is enough.
+func AuthMiddleware(next http.Handler, authService *auth.AuthService) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if header := r.Header.Get("Authorization"); header != "" {
+			scheme, credentials, _ := strings.Cut(header, " ")
+			credentials = strings.TrimSpace(credentials)
+
+			var principal auth.Principal
+			var err error
+			switch {
+			case strings.EqualFold(scheme, "Bearer"):
+				principal, err = authService.AuthenticateBearer(r.Context(), credentials)
+			case strings.EqualFold(scheme, "ApiKey"):
+				principal, err = authService.AuthenticateAPIKey(r.Context(), credentials)
+			default:
+				err = er.ErrInvalidToken
+			}
+			if err != nil {
+				er.Write(w, r, er.ErrInvalidToken)
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(auth.WithPrincipal(r.Context(), principal)))
+			return
+		}
+
+		cookie, err := r.Cookie(auth.SessionCookieName)
+		if err != nil || cookie.Value == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		principal, err := authService.AuthenticateSession(r.Context(), cookie.Value)
+		if err != nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(auth.WithPrincipal(r.Context(), principal)))
+	})
+}
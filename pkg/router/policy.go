@@ -0,0 +1,75 @@
+package router
+
+import (
+	"net/http"
+
+	"deu/internal/auth"
+	er "deu/internal/errors"
+	"deu/internal/models"
+)
+
+// Policy decides whether the caller may reach a route. It returns nil to
+// allow the request, er.ErrUnauthorized when the caller has to log in first
+// and er.ErrForbidden when they are logged in but lack the required role.
+type Policy func(r *http.Request) error
+
+// Public lets every request through, authenticated or not.
+func Public(r *http.Request) error {
+	return nil
+}
+
+// Authenticated allows any logged-in caller.
+func Authenticated(r *http.Request) error {
+	if _, ok := auth.PrincipalFromContext(r.Context()); !ok {
+		return er.ErrUnauthorized
+	}
+	return nil
+}
+
+// RequireRole allows logged-in callers holding one of the given roles.
+func RequireRole(roles ...string) Policy {
+	return func(r *http.Request) error {
+		principal, ok := auth.PrincipalFromContext(r.Context())
+		if !ok {
+			return er.ErrUnauthorized
+		}
+		if !principal.HasRole(roles...) {
+			return er.ErrForbidden
+		}
+		return nil
+	}
+}
+
+var adminOnly = RequireRole(models.RoleAdmin)
+
+var moderatorOnly = RequireRole(models.RoleAdmin, models.RoleEditor)
+
+// readOnlyScope rejects unsafe methods from read-only API keys, whatever
+// the route policy says.
+func readOnlyScope(r *http.Request) error {
+	principal, ok := auth.PrincipalFromContext(r.Context())
+	if !ok || !principal.ReadOnly() {
+		return nil
+	}
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return nil
+	}
+	return er.ErrForbidden
+}
+
+// guard wraps h so that it only runs when policy allows the request.
+// Denied requests get the same problem details body from every route.
+func guard(policy Policy, h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		err := readOnlyScope(r)
+		if err == nil {
+			err = policy(r)
+		}
+		if err != nil {
+			er.Write(w, r, err)
+			return
+		}
+		h(w, r)
+	}
+}
@@ -1,43 +1,95 @@
-// deu/pkg/router/router.go (Updated)
-package router
-
-import (
-	"net/http"
-
-	"deu/internal/users"
-	"deu/internal/places"
-)
-
-type Config struct {
-	UserHandler *users.Handler
-	PlaceHandler *places.Handler
-}
-
-func NewRouter(cfg Config) http.Handler {
-	mux := http.NewServeMux()
-
-	fs := http.FileServer(http.Dir("./static"))
-	mux.Handle("GET /", fs)
-
-	mux.HandleFunc("GET /users", cfg.UserHandler.GetAll)
-	mux.HandleFunc("POST /users", cfg.UserHandler.Create)
-	mux.HandleFunc("DELETE /users", cfg.UserHandler.DeleteAll)
-
-	mux.HandleFunc("GET /users/{id}", cfg.UserHandler.GetById)
-	mux.HandleFunc("PATCH /users/{id}", cfg.UserHandler.Update)
-	mux.HandleFunc("DELETE /users/{id}", cfg.UserHandler.DeleteById)
-
-	mux.HandleFunc("POST /users/{id}/places/{place_id}", cfg.UserHandler.AddVisitedPlace)
-	mux.HandleFunc("GET /users/{id}/places/{place_id}", cfg.UserHandler.CheckIfVisited)
-	mux.HandleFunc("DELETE /users/{id}/places/{place_id}", cfg.UserHandler.RemoveVisitedPlace)
-
-	mux.HandleFunc("GET /places", cfg.PlaceHandler.GetAll)
-	mux.HandleFunc("POST /places", cfg.PlaceHandler.Create)
-	mux.HandleFunc("DELETE /places", cfg.PlaceHandler.DeleteAll)
-
-	mux.HandleFunc("GET /places/{id}", cfg.PlaceHandler.GetById)
-	mux.HandleFunc("PATCH /places/{id}", cfg.PlaceHandler.Update)
-	mux.HandleFunc("DELETE /places/{id}", cfg.PlaceHandler.DeleteById)
-
-	return mux
+// deu/pkg/router/router.go (Updated)
+package router
+
+import (
+	"net/http"
+
+	"deu/internal/auth"
+	"deu/internal/users"
+	"deu/internal/places"
+)
+
+type Config struct {
+	AuthHandler *auth.Handler
+	UserHandler *users.Handler
+	PlaceHandler *places.Handler
+}
+
+func NewRouter(cfg Config) http.Handler {
+	mux := http.NewServeMux()
+
+	// Every API route declares the policy it is checked against.
+	handle := func(pattern string, policy Policy, h http.HandlerFunc) {
+		mux.HandleFunc(pattern, guard(policy, h))
+	}
+
+	fs := http.FileServer(http.Dir("./static"))
+	mux.Handle("GET /", fs)
+
+	handle("POST /auth/register", Public, cfg.AuthHandler.Register)
+	handle("POST /auth/login", Public, cfg.AuthHandler.Login)
+	handle("POST /auth/logout", Public, cfg.AuthHandler.Logout)
+	handle("POST /auth/token", Public, cfg.AuthHandler.Token)
+	handle("POST /auth/refresh", Public, cfg.AuthHandler.Refresh)
+	handle("POST /auth/revoke", Public, cfg.AuthHandler.Revoke)
+	handle("GET /auth/me", Authenticated, cfg.AuthHandler.Me)
+
+	handle("GET /users", adminOnly, cfg.UserHandler.GetAll)
+	handle("POST /users", adminOnly, cfg.UserHandler.Create)
+	handle("DELETE /users", adminOnly, cfg.UserHandler.DeleteAll)
+
+	handle("GET /users/{id}", Authenticated, cfg.UserHandler.GetById)
+	handle("PATCH /users/{id}", Authenticated, cfg.UserHandler.Update)
+	handle("DELETE /users/{id}", Authenticated, cfg.UserHandler.DeleteById)
+	handle("PUT /users/{id}/role", adminOnly, cfg.UserHandler.SetRole)
+	handle("GET /users/{id}/history", Authenticated, cfg.UserHandler.History)
+
+	handle("POST /users/{id}/api-keys", Authenticated, cfg.AuthHandler.CreateAPIKey)
+	handle("GET /users/{id}/api-keys", Authenticated, cfg.AuthHandler.ListAPIKeys)
+	handle("DELETE /users/{id}/api-keys/{key_id}", Authenticated, cfg.AuthHandler.RevokeAPIKey)
+
+	handle("GET /users/{id}/places", Authenticated, cfg.UserHandler.ListVisitedPlaces)
+	handle("POST /users/{id}/places/{place_id}", Authenticated, cfg.UserHandler.AddVisitedPlace)
+	handle("GET /users/{id}/places/{place_id}", Authenticated, cfg.UserHandler.CheckIfVisited)
+	handle("DELETE /users/{id}/places/{place_id}", Authenticated, cfg.UserHandler.RemoveVisitedPlace)
+	handle("DELETE /users/{id}/visits/{visit_id}", Authenticated, cfg.UserHandler.RemoveVisit)
+
+	handle("GET /places", Public, cfg.PlaceHandler.GetAll)
+	handle("POST /places", Authenticated, cfg.PlaceHandler.Create)
+	handle("DELETE /places", adminOnly, cfg.PlaceHandler.DeleteAll)
+
+	handle("GET /places/nearby", Public, cfg.PlaceHandler.Nearby)
+	handle("GET /places/search", Public, cfg.PlaceHandler.Search)
+	handle("GET /places/{id}", Public, cfg.PlaceHandler.GetById)
+	handle("GET /places/{id}/visitors", Authenticated, cfg.PlaceHandler.ListVisitors)
+	handle("GET /places/{id}/history", Authenticated, cfg.PlaceHandler.History)
+	handle("POST /places/{id}/history/{entry_id}/revert", adminOnly, cfg.PlaceHandler.Revert)
+	handle("POST /places/{id}/reviews", Authenticated, cfg.PlaceHandler.SaveReview)
+	handle("GET /places/{id}/reviews", Public, cfg.PlaceHandler.ListReviews)
+	handle("PUT /places/{id}/reviews/{review_id}/visibility", moderatorOnly, cfg.PlaceHandler.SetReviewVisibility)
+	handle("DELETE /places/{id}/reviews/{review_id}", Authenticated, cfg.PlaceHandler.DeleteReview)
+	handle("POST /places/{id}/photos", Authenticated, cfg.PlaceHandler.UploadPhoto)
+	handle("GET /places/{id}/photos", Public, cfg.PlaceHandler.ListPhotos)
+	handle("GET /places/{id}/photos/{photo_id}", Public, cfg.PlaceHandler.GetPhoto)
+	handle("GET /places/{id}/photos/{photo_id}/thumbnail", Public, cfg.PlaceHandler.GetPhotoThumbnail)
+	handle("DELETE /places/{id}/photos/{photo_id}", Authenticated, cfg.PlaceHandler.DeletePhoto)
+	// Members may change their own places; PlaceService checks ownership.
+	handle("PATCH /places/{id}", Authenticated, cfg.PlaceHandler.Update)
+	handle("DELETE /places/{id}", Authenticated, cfg.PlaceHandler.DeleteById)
+
+	handle("GET /admin/cache", adminOnly, cfg.PlaceHandler.CacheStats)
+
+	handle("GET /trash/places", adminOnly, cfg.PlaceHandler.ListDeleted)
+	handle("DELETE /trash/places", adminOnly, cfg.PlaceHandler.Purge)
+	handle("POST /places/{id}/restore", adminOnly, cfg.PlaceHandler.Restore)
+	handle("GET /trash/users", adminOnly, cfg.UserHandler.ListDeleted)
+	handle("DELETE /trash/users", adminOnly, cfg.UserHandler.Purge)
+	handle("POST /users/{id}/restore", adminOnly, cfg.UserHandler.Restore)
+
+	handle("GET /tags", Public, cfg.PlaceHandler.ListTags)
+	handle("POST /tags", moderatorOnly, cfg.PlaceHandler.CreateTag)
+	handle("PATCH /tags/{id}", moderatorOnly, cfg.PlaceHandler.UpdateTag)
+	handle("DELETE /tags/{id}", moderatorOnly, cfg.PlaceHandler.DeleteTag)
+
+	return mux
 }
\ No newline at end of file
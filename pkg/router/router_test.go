@@ -0,0 +1,433 @@
+package router
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"deu/internal/audit"
+	"deu/internal/auth"
+	er "deu/internal/errors"
+	"deu/internal/models"
+	"deu/internal/places"
+	"deu/internal/repository"
+	"deu/internal/users"
+	"deu/pkg/cache"
+)
+
+var testSecret = []byte("test-secret")
+
+// newTestServer serves the whole API over the in-memory backend. The
+// emails in admins are granted the admin role when they register.
+func newTestServer(t *testing.T, admins ...string) *httptest.Server {
+	t.Helper()
+	b := repository.NewMemoryBackend()
+	trail := audit.NewTrail(b.Audit)
+
+	authService := auth.NewAuthService(b.Users, b.Sessions, b.RefreshTokens, b.APIKeys, auth.Settings{
+		AdminEmails:     admins,
+		SessionTTL:      time.Hour,
+		JWTSecret:       testSecret,
+		AccessTokenTTL:  time.Minute,
+		RefreshTokenTTL: time.Hour,
+	})
+	userService := users.NewUserService(b.Users, b.UserPlaces, b.Places, b.UnitOfWork, trail)
+	placeService := places.NewPlaceService(b.Places, b.UserPlaces, b.Reviews, b.Tags, b.Photos, b.UnitOfWork, nil, trail, cache.Nop[*models.Place]{}, cache.NewLocalBus())
+
+	r := NewRouter(Config{
+		AuthHandler:  &auth.Handler{Service: authService},
+		UserHandler:  &users.Handler{Service: userService},
+		PlaceHandler: &places.Handler{Service: placeService},
+	})
+	srv := httptest.NewServer(RequestID(AuthMiddleware(r, authService)))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// call sends a JSON request with the given Authorization header, if any,
+// and decodes the JSON response into out, if given.
+func call(t *testing.T, srv *httptest.Server, method, path, authorization string, body, out any) int {
+	t.Helper()
+	status, _ := callWithHeaders(t, srv, method, path, authorization, nil, body, out)
+	return status
+}
+
+// callWithHeaders is call with extra request headers; it also returns the
+// response headers.
+func callWithHeaders(t *testing.T, srv *httptest.Server, method, path, authorization string, header map[string]string, body, out any) (int, http.Header) {
+	t.Helper()
+	resp := send(t, srv, method, path, authorization, header, body)
+	defer resp.Body.Close()
+	if out != nil && resp.StatusCode < 300 {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			t.Fatalf("%s %s: decoding response: %v", method, path, err)
+		}
+	}
+	return resp.StatusCode, resp.Header
+}
+
+// send sends a JSON request and returns the response for the caller to
+// read and close.
+func send(t *testing.T, srv *httptest.Server, method, path, authorization string, header map[string]string, body any) *http.Response {
+	t.Helper()
+	var payload bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&payload).Encode(body); err != nil {
+			t.Fatal(err)
+		}
+	}
+	req, err := http.NewRequest(method, srv.URL+path, &payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+	for name, value := range header {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := srv.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp
+}
+
+func expectStatus(t *testing.T, got, want int, what string) {
+	t.Helper()
+	if got != want {
+		t.Errorf("%s: status %d, want %d", what, got, want)
+	}
+}
+
+// signUp registers a user and returns them with a bearer Authorization
+// header for them.
+func signUp(t *testing.T, srv *httptest.Server, email string) (models.User, string) {
+	t.Helper()
+	var user models.User
+	status := call(t, srv, http.MethodPost, "/auth/register", "", models.RegisterRequest{Name: "tester", Email: email, Password: "correct horse"}, &user)
+	expectStatus(t, status, http.StatusCreated, "register")
+
+	var tokens models.TokenPair
+	status = call(t, srv, http.MethodPost, "/auth/token", "", models.LoginRequest{Email: email, Password: "correct horse"}, &tokens)
+	expectStatus(t, status, http.StatusOK, "token")
+	return user, "Bearer " + tokens.AccessToken
+}
+
+func TestWrongPasswordIsUnauthorized(t *testing.T) {
+	srv := newTestServer(t)
+	signUp(t, srv, "ada@example.com")
+
+	for _, path := range []string{"/auth/login", "/auth/token"} {
+		status := call(t, srv, http.MethodPost, path, "", models.LoginRequest{Email: "ada@example.com", Password: "wrong horse"}, nil)
+		expectStatus(t, status, http.StatusUnauthorized, path+" with a wrong password")
+
+		status = call(t, srv, http.MethodPost, path, "", models.LoginRequest{Email: "bob@example.com", Password: "correct horse"}, nil)
+		expectStatus(t, status, http.StatusUnauthorized, path+" with an unknown email")
+	}
+}
+
+func TestDuplicateEmailsConflict(t *testing.T) {
+	srv := newTestServer(t)
+	signUp(t, srv, "ada@example.com")
+	bob, bobBearer := signUp(t, srv, "bob@example.com")
+
+	status := call(t, srv, http.MethodPost, "/auth/register", "", models.RegisterRequest{Name: "tester", Email: "ADA@example.com", Password: "correct horse"}, nil)
+	expectStatus(t, status, http.StatusConflict, "register a taken email in another case")
+
+	email := "Ada@Example.com"
+	status, _ = callWithHeaders(t, srv, http.MethodPatch, "/users/"+bob.Id, bobBearer, map[string]string{"If-Match": "*"}, models.UserUpdateRequest{Email: &email}, nil)
+	expectStatus(t, status, http.StatusConflict, "change email to a taken one")
+}
+
+func TestRefreshTokenReuseRevokesFamily(t *testing.T) {
+	srv := newTestServer(t)
+	signUp(t, srv, "ada@example.com")
+
+	var first, second models.TokenPair
+	status := call(t, srv, http.MethodPost, "/auth/token", "", models.LoginRequest{Email: "ada@example.com", Password: "correct horse"}, &first)
+	expectStatus(t, status, http.StatusOK, "token")
+	status = call(t, srv, http.MethodPost, "/auth/refresh", "", models.RefreshRequest{RefreshToken: first.RefreshToken}, &second)
+	expectStatus(t, status, http.StatusOK, "first refresh")
+
+	status = call(t, srv, http.MethodPost, "/auth/refresh", "", models.RefreshRequest{RefreshToken: first.RefreshToken}, nil)
+	expectStatus(t, status, http.StatusUnauthorized, "refresh with the rotated token")
+	status = call(t, srv, http.MethodPost, "/auth/refresh", "", models.RefreshRequest{RefreshToken: second.RefreshToken}, nil)
+	expectStatus(t, status, http.StatusUnauthorized, "refresh with the token the family ended on")
+}
+
+func TestForgedBearerTokensAreRejected(t *testing.T) {
+	srv := newTestServer(t)
+	user, bearer := signUp(t, srv, "ada@example.com")
+
+	now := time.Now()
+	claims := jwt.RegisteredClaims{
+		Issuer:    "traveler-track",
+		Subject:   user.Id,
+		ExpiresAt: jwt.NewNumericDate(now.Add(time.Minute)),
+	}
+	sign := func(method jwt.SigningMethod, key any, claims jwt.RegisteredClaims) string {
+		token, err := jwt.NewWithClaims(method, claims).SignedString(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return "Bearer " + token
+	}
+	wrongIssuer := claims
+	wrongIssuer.Issuer = "someone-else"
+
+	tests := []struct {
+		name          string
+		authorization string
+		want          int
+	}{
+		{"issued token", bearer, http.StatusOK},
+		{"alg none", sign(jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType, claims), http.StatusUnauthorized},
+		{"wrong issuer", sign(jwt.SigningMethodHS256, testSecret, wrongIssuer), http.StatusUnauthorized},
+		{"wrong secret", sign(jwt.SigningMethodHS256, []byte("other-secret"), claims), http.StatusUnauthorized},
+		{"unknown scheme", "Basic YWRhOmhvcnNl", http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status := call(t, srv, http.MethodGet, "/auth/me", tt.authorization, nil, nil)
+			expectStatus(t, status, tt.want, "GET /auth/me")
+		})
+	}
+}
+
+func TestRoutesCheckRoles(t *testing.T) {
+	srv := newTestServer(t, "admin@example.com")
+	ada, adaBearer := signUp(t, srv, "ada@example.com")
+	_, adminBearer := signUp(t, srv, "admin@example.com")
+
+	tests := []struct {
+		name          string
+		method, path  string
+		body          any
+		authorization string
+		want          int
+	}{
+		{"anonymous lists users", http.MethodGet, "/users", nil, "", http.StatusUnauthorized},
+		{"member lists users", http.MethodGet, "/users", nil, adaBearer, http.StatusForbidden},
+		{"admin lists users", http.MethodGet, "/users", nil, adminBearer, http.StatusOK},
+		{"member promotes themselves", http.MethodPut, "/users/" + ada.Id + "/role", models.UserRoleRequest{Role: models.RoleAdmin}, adaBearer, http.StatusForbidden},
+		{"admin makes a member an editor", http.MethodPut, "/users/" + ada.Id + "/role", models.UserRoleRequest{Role: models.RoleEditor}, adminBearer, http.StatusOK},
+		{"member deletes every place", http.MethodDelete, "/places", nil, adaBearer, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status := call(t, srv, tt.method, tt.path, tt.authorization, tt.body, nil)
+			expectStatus(t, status, tt.want, tt.method+" "+tt.path)
+		})
+	}
+}
+
+// createKey creates an API key for user and returns an Authorization
+// header using it.
+func createKey(t *testing.T, srv *httptest.Server, user models.User, bearer, scope string) string {
+	t.Helper()
+	var key models.APIKeyCreated
+	status := call(t, srv, http.MethodPost, "/users/"+user.Id+"/api-keys", bearer, models.APIKeyCreateRequest{Name: scope, Scope: scope}, &key)
+	expectStatus(t, status, http.StatusCreated, "create "+scope+" key")
+	return "ApiKey " + key.Key
+}
+
+func TestReadOnlyAPIKeyCannotWrite(t *testing.T) {
+	srv := newTestServer(t)
+	user, bearer := signUp(t, srv, "ada@example.com")
+	readOnly := createKey(t, srv, user, bearer, models.APIKeyScopeReadOnly)
+	readWrite := createKey(t, srv, user, bearer, models.APIKeyScopeReadWrite)
+
+	place := models.PlaceCreateRequest{
+		Name:        "Louvre Museum",
+		Description: "Home of the Mona Lisa",
+		Location:    models.Location{Latitude: 48.86, Longitude: 2.34},
+		Address:     "Rue de Rivoli, Paris",
+	}
+	name := "renamed"
+
+	status := call(t, srv, http.MethodGet, "/auth/me", readOnly, nil, nil)
+	expectStatus(t, status, http.StatusOK, "read with a read-only key")
+	status = call(t, srv, http.MethodGet, "/places", readOnly, nil, nil)
+	expectStatus(t, status, http.StatusOK, "list places with a read-only key")
+
+	status = call(t, srv, http.MethodPost, "/places", readOnly, place, nil)
+	expectStatus(t, status, http.StatusForbidden, "create a place with a read-only key")
+	status = call(t, srv, http.MethodPatch, "/users/"+user.Id, readOnly, models.UserUpdateRequest{Name: &name}, nil)
+	expectStatus(t, status, http.StatusForbidden, "update own account with a read-only key")
+	status = call(t, srv, http.MethodDelete, "/users/"+user.Id, readOnly, nil, nil)
+	expectStatus(t, status, http.StatusForbidden, "delete own account with a read-only key")
+
+	status = call(t, srv, http.MethodPost, "/places", readWrite, place, nil)
+	expectStatus(t, status, http.StatusCreated, "create a place with a read-write key")
+}
+
+func TestAPIKeyCannotCreateKeys(t *testing.T) {
+	srv := newTestServer(t)
+	user, bearer := signUp(t, srv, "ada@example.com")
+	readWrite := createKey(t, srv, user, bearer, models.APIKeyScopeReadWrite)
+
+	status := call(t, srv, http.MethodPost, "/users/"+user.Id+"/api-keys", readWrite, models.APIKeyCreateRequest{Name: "minted"}, nil)
+	expectStatus(t, status, http.StatusForbidden, "create a key with an API key")
+	status = call(t, srv, http.MethodGet, "/users/"+user.Id+"/api-keys", readWrite, nil, nil)
+	expectStatus(t, status, http.StatusForbidden, "list keys with an API key")
+}
+
+// checkConditionalRequests walks a resource that is at version 1 through
+// conditional reads and PATCHes. Each step builds on the one before.
+func checkConditionalRequests(t *testing.T, srv *httptest.Server, path, authorization string, update any) {
+	t.Helper()
+	steps := []struct {
+		name     string
+		method   string
+		header   string
+		value    string
+		want     int
+		wantETag string
+	}{
+		{"read", http.MethodGet, "", "", http.StatusOK, `"1"`},
+		{"read the version the client has", http.MethodGet, "If-None-Match", `"1"`, http.StatusNotModified, `"1"`},
+		{"read with a weak tag", http.MethodGet, "If-None-Match", `W/"1"`, http.StatusNotModified, `"1"`},
+		{"read with another tag", http.MethodGet, "If-None-Match", `"7"`, http.StatusOK, `"1"`},
+		{"write without If-Match", http.MethodPatch, "", "", http.StatusPreconditionRequired, ""},
+		{"write a version that does not exist yet", http.MethodPatch, "If-Match", `"2"`, http.StatusPreconditionFailed, ""},
+		{"write an unquoted tag", http.MethodPatch, "If-Match", "1", http.StatusPreconditionFailed, ""},
+		{"write the current version", http.MethodPatch, "If-Match", `"1"`, http.StatusOK, `"2"`},
+		{"write a stale version", http.MethodPatch, "If-Match", `"1"`, http.StatusPreconditionFailed, ""},
+		{"write any version", http.MethodPatch, "If-Match", "*", http.StatusOK, `"3"`},
+		{"read after the writes", http.MethodGet, "If-None-Match", `"2"`, http.StatusOK, `"3"`},
+	}
+	for _, step := range steps {
+		var header map[string]string
+		if step.header != "" {
+			header = map[string]string{step.header: step.value}
+		}
+		var body any
+		if step.method == http.MethodPatch {
+			body = update
+		}
+		status, got := callWithHeaders(t, srv, step.method, path, authorization, header, body, nil)
+		expectStatus(t, status, step.want, step.name)
+		if step.wantETag != "" && got.Get("ETag") != step.wantETag {
+			t.Errorf("%s: ETag %q, want %q", step.name, got.Get("ETag"), step.wantETag)
+		}
+	}
+}
+
+func TestPlaceWritesAreConditional(t *testing.T) {
+	srv := newTestServer(t)
+	_, bearer := signUp(t, srv, "ada@example.com")
+
+	var place models.Place
+	status := call(t, srv, http.MethodPost, "/places", bearer, models.PlaceCreateRequest{
+		Name:        "Louvre Museum",
+		Description: "Home of the Mona Lisa",
+		Location:    models.Location{Latitude: 48.86, Longitude: 2.34},
+		Address:     "Rue de Rivoli, Paris",
+	}, &place)
+	expectStatus(t, status, http.StatusCreated, "create a place")
+
+	name := "Musée du Louvre"
+	checkConditionalRequests(t, srv, "/places/"+place.Id, bearer, models.PlaceUpdateRequest{Name: &name})
+}
+
+func TestTaggingAPlaceIsOneWrite(t *testing.T) {
+	srv := newTestServer(t, "admin@example.com")
+	_, bearer := signUp(t, srv, "admin@example.com")
+
+	status := call(t, srv, http.MethodPost, "/tags", bearer, models.TagRequest{Name: "museum"}, nil)
+	expectStatus(t, status, http.StatusCreated, "create a tag")
+	var place models.Place
+	status = call(t, srv, http.MethodPost, "/places", bearer, models.PlaceCreateRequest{
+		Name:        "Louvre Museum",
+		Description: "Home of the Mona Lisa",
+		Location:    models.Location{Latitude: 48.86, Longitude: 2.34},
+		Address:     "Rue de Rivoli, Paris",
+	}, &place)
+	expectStatus(t, status, http.StatusCreated, "create a place")
+
+	name := "Musée du Louvre"
+	tags := []string{"museum"}
+	path := "/places/" + place.Id
+	status, got := callWithHeaders(t, srv, http.MethodPatch, path, bearer, map[string]string{"If-Match": `"1"`}, models.PlaceUpdateRequest{Name: &name, Tags: &tags}, nil)
+	expectStatus(t, status, http.StatusOK, "rename and tag the place")
+	if got.Get("ETag") != `"2"` {
+		t.Errorf("ETag %q after one write, want %q", got.Get("ETag"), `"2"`)
+	}
+	status, _ = callWithHeaders(t, srv, http.MethodPatch, path, bearer, map[string]string{"If-Match": `"2"`}, models.PlaceUpdateRequest{Name: &name}, nil)
+	expectStatus(t, status, http.StatusOK, "write the version the client was given")
+}
+
+func TestUserWritesAreConditional(t *testing.T) {
+	srv := newTestServer(t)
+	user, bearer := signUp(t, srv, "ada@example.com")
+
+	name := "Ada Lovelace"
+	checkConditionalRequests(t, srv, "/users/"+user.Id, bearer, models.UserUpdateRequest{Name: &name})
+
+	path := "/users/" + user.Id
+	status, _ := callWithHeaders(t, srv, http.MethodDelete, path, bearer, nil, nil, nil)
+	expectStatus(t, status, http.StatusPreconditionRequired, "delete without If-Match")
+	status, _ = callWithHeaders(t, srv, http.MethodDelete, path, bearer, map[string]string{"If-Match": `"1"`}, nil, nil)
+	expectStatus(t, status, http.StatusPreconditionFailed, "delete a stale version")
+	status, _ = callWithHeaders(t, srv, http.MethodDelete, path, bearer, map[string]string{"If-Match": `"3"`}, nil, nil)
+	expectStatus(t, status, http.StatusOK, "delete the current version")
+}
+
+func TestErrorsAreProblemDetails(t *testing.T) {
+	srv := newTestServer(t)
+	_, bearer := signUp(t, srv, "ada@example.com")
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		header     map[string]string
+		body       any
+		wantStatus int
+		wantCode   string
+		wantField  string
+	}{
+		{"invalid place", http.MethodPost, "/places", nil, models.PlaceCreateRequest{Description: "No name"}, http.StatusBadRequest, "validation_failed", "name"},
+		{"malformed id", http.MethodGet, "/places/not-a-uuid", nil, nil, http.StatusBadRequest, "invalid_id", ""},
+		{"unknown place", http.MethodGet, "/places/00000000-0000-4000-8000-000000000000", nil, nil, http.StatusNotFound, "place_not_found", ""},
+		{"missing If-Match", http.MethodPatch, "/places/00000000-0000-4000-8000-000000000000", nil, models.PlaceUpdateRequest{}, http.StatusPreconditionRequired, "precondition_required", ""},
+		{"taken email", http.MethodPost, "/auth/register", nil, models.RegisterRequest{Name: "tester", Email: "ada@example.com", Password: "correct horse"}, http.StatusConflict, "email_taken", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := map[string]string{RequestIDHeader: "req-" + tt.wantCode}
+			resp := send(t, srv, tt.method, tt.path, bearer, header, tt.body)
+			defer resp.Body.Close()
+
+			expectStatus(t, resp.StatusCode, tt.wantStatus, tt.method+" "+tt.path)
+			if got := resp.Header.Get("Content-Type"); got != er.ProblemContentType {
+				t.Errorf("Content-Type %q, want %q", got, er.ProblemContentType)
+			}
+			if got := resp.Header.Get(RequestIDHeader); got != header[RequestIDHeader] {
+				t.Errorf("%s %q, want the one sent", RequestIDHeader, got)
+			}
+
+			var problem er.ErrorResponse
+			if err := json.NewDecoder(resp.Body).Decode(&problem); err != nil {
+				t.Fatal(err)
+			}
+			if problem.Status != tt.wantStatus || problem.Code != tt.wantCode {
+				t.Errorf("problem status %d code %q, want %d %q", problem.Status, problem.Code, tt.wantStatus, tt.wantCode)
+			}
+			if problem.RequestID != header[RequestIDHeader] {
+				t.Errorf("problem request id %q, want %q", problem.RequestID, header[RequestIDHeader])
+			}
+			if tt.wantField != "" && (len(problem.Errors) == 0 || problem.Errors[0].Field != tt.wantField) {
+				t.Errorf("problem errors %+v, want one for %q", problem.Errors, tt.wantField)
+			}
+		})
+	}
+}